@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is an output file that is written to a temporary file in the
+// same directory and renamed over the target when complete. A failed write
+// never leaves a truncated file behind, so files can be rewritten in place.
+type atomicFile struct {
+	*os.File
+	target string
+	done   bool
+}
+
+// createAtomic creates a temporary file for writing filename.
+// If filename is a symlink, the file it points to is replaced.
+func createAtomic(filename string) (*atomicFile, error) {
+	target := filename
+	if resolved, err := filepath.EvalSymlinks(filename); err == nil {
+		target = resolved
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: file, target: target}, nil
+}
+
+// commit moves the temporary file into place, keeping the permissions of the
+// file it replaces (or using the usual permissions for a new file)
+func (f *atomicFile) commit() error {
+	if f.done {
+		return errors.New("file already closed")
+	}
+	f.done = true
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(f.target); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	err := f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.target)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// abort removes the temporary file, unless it has been committed
+func (f *atomicFile) abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// dirEntries returns the names of the files in a directory
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestAtomicFileCommit(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "bookmarks.org")
+	if err := os.WriteFile(filename, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := createAtomic(filename)
+	if err != nil {
+		t.Fatalf("createAtomic failed: %v", err)
+	}
+	if _, err := file.WriteString("new"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing changes until the file is committed
+	if data, _ := os.ReadFile(filename); string(data) != "old" {
+		t.Errorf("Expected the original file before commit, got %q", data)
+	}
+	if err := file.commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	file.abort() // A no-op after commit
+
+	if data, _ := os.ReadFile(filename); string(data) != "new" {
+		t.Errorf("Expected the new content, got %q", data)
+	}
+	if info, err := os.Stat(filename); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the permissions to be kept, got %v", info.Mode())
+	}
+	if names := dirEntries(t, dir); len(names) != 1 {
+		t.Errorf("Expected only the target file to be left, got %v", names)
+	}
+	if err := file.commit(); err == nil {
+		t.Error("Expected an error when committing twice")
+	}
+}
+
+func TestAtomicFileAbort(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "bookmarks.html")
+	if err := os.WriteFile(filename, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := createAtomic(filename)
+	if err != nil {
+		t.Fatalf("createAtomic failed: %v", err)
+	}
+	file.WriteString("half-written")
+	file.abort()
+
+	if data, _ := os.ReadFile(filename); string(data) != "old" {
+		t.Errorf("Expected the original file to be intact, got %q", data)
+	}
+	if names := dirEntries(t, dir); len(names) != 1 {
+		t.Errorf("Expected the temporary file to be removed, got %v", names)
+	}
+}
+
+func TestAtomicFileCommitFails(t *testing.T) {
+	// A non-empty directory can't be replaced by a file
+	dir := t.TempDir()
+	target := filepath.Join(dir, "bookmarks")
+	if err := os.MkdirAll(filepath.Join(target, "keep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := createAtomic(target)
+	if err != nil {
+		t.Fatalf("createAtomic failed: %v", err)
+	}
+	file.WriteString("new")
+	if err := file.commit(); err == nil {
+		t.Fatal("Expected commit to fail")
+	}
+
+	if info, err := os.Stat(filepath.Join(target, "keep")); err != nil || !info.IsDir() {
+		t.Errorf("Expected the original to be intact, got %v", err)
+	}
+	if names := dirEntries(t, dir); len(names) != 1 {
+		t.Errorf("Expected the temporary file to be removed, got %v", names)
+	}
+}
+
+func TestAtomicFileFollowsSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.org")
+	link := filepath.Join(dir, "link.org")
+	if err := os.WriteFile(target, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	file, err := createAtomic(link)
+	if err != nil {
+		t.Fatalf("createAtomic failed: %v", err)
+	}
+	file.WriteString("new")
+	if err := file.commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(target); string(data) != "new" {
+		t.Errorf("Expected the link target to be replaced, got %q", data)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the symlink to be kept")
+	}
+}
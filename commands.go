@@ -0,0 +1,879 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// command is an orgmarks subcommand
+type command struct {
+	name        string
+	args        string // Arguments shown in the usage line
+	summary     string // One line for the command list
+	description string // Shown in the command's help
+	run         func(fs *flag.FlagSet, args []string) error
+}
+
+// commands lists the subcommands in the order they are shown in the usage
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name:    "convert",
+			args:    "[options] -i <input-file> -o <output-file>",
+			summary: "Convert a bookmark file to another format",
+			description: "Converts a bookmark file to another format. The formats are chosen by\n" +
+				"file extension; the input file can also be given as an argument. If both\n" +
+				"formats are the same, the file is rewritten in canonical form.",
+			run: runConvert,
+		},
+		{
+			name:    "normalize",
+			args:    "[options] <file>...",
+			summary: "Rewrite bookmark files in place in canonical form",
+			description: "Parses each file, applies the requested clean-ups and writes it back in\n" +
+				"the same format. The file is replaced only once the new version has been\n" +
+				"written completely.",
+			run: runNormalize,
+		},
+		{
+			name:    "merge",
+			args:    "[options] -i <input-file> -i <input-file2> ... -o <output-file>",
+			summary: "Merge several bookmark files into one",
+			description: "Merges bookmark files of any format into one file of any output format.\n" +
+				"Folders with the same title are combined, and bookmarks from earlier files\n" +
+				"come first. --into puts the later files into a folder of the first,\n" +
+				"--alias and --browser-aliases combine folders with different titles, and\n" +
+				"--inbox collects files that have no folders in one folder.\n\n" +
+				"With --base, two files (ours, then theirs) are merged three-way: changes\n" +
+				"made to either since the base, including deletions, moves and edits, are\n" +
+				"applied. Conflicting changes keep ours, are listed, and make merge exit\n" +
+				"with status 1.",
+			run: runMerge,
+		},
+		{
+			name:    "dedupe",
+			args:    "[options] -i <input-file> -o <output-file>",
+			summary: "Remove duplicate bookmarks",
+			description: "Removes bookmarks whose URL appears earlier in the tree and writes the\n" +
+				"result. The output may have the same format as the input. With --url-match,\n" +
+				"URLs are compared in canonical form; --strategy chooses which copy is kept.",
+			run: runDedupe,
+		},
+		{
+			name:    "similar",
+			args:    "[options] <file>...",
+			summary: "List bookmarks that are probably the same page",
+			description: "Finds near-duplicates: the same page under AMP, mobile (m.) or print\n" +
+				"URLs, or URLs with the same words. Pairs are scored by URL and title\n" +
+				"similarity and listed in clusters; nothing is removed. Exact duplicates\n" +
+				"are left to dedupe. Several files are compared as if merged.",
+			run: runSimilar,
+		},
+		{
+			name:    "diff",
+			args:    "[options] <old-file> <new-file>",
+			summary: "Show the changes between two bookmark files",
+			description: "Lists the bookmarks and folders added (+), removed (-), moved (>), renamed\n" +
+				"(~) or otherwise changed (*) between two files. Bookmarks are matched by\n" +
+				"URL, ignoring trailing slashes and the scheme. The diff is written as\n" +
+				"text, JSON, or an Org file of the new tree with a DIFF property on each\n" +
+				"changed node. Exits with status 1 if the files differ.",
+			run: runDiff,
+		},
+		{
+			name:        "stats",
+			args:        "<file>...",
+			summary:     "Show statistics about bookmark files",
+			description: "Counts the bookmarks, folders, tags and duplicates in each file.",
+			run:         runStats,
+		},
+		{
+			name:    "check",
+			args:    "<file>...",
+			summary: "Check bookmark files for problems",
+			description: "Reports files that can't be parsed, bookmarks with invalid URLs or no\n" +
+				"title, duplicate URLs and empty folders. Exits with status 1 if any\n" +
+				"problems are found.",
+			run: runCheck,
+		},
+		{
+			name:    "search",
+			args:    "[options] <query> <file>...",
+			summary: "Search bookmarks by title, URL, description or tag",
+			description: "Lists the bookmarks containing all words of the query (ignoring case) in\n" +
+				"their title, URL, description or tags. Exits with status 1 if nothing matches.",
+			run: runSearch,
+		},
+		{
+			name:        "version",
+			args:        "",
+			summary:     "Show version information",
+			description: "Shows the version of orgmarks.",
+			run:         runVersion,
+		},
+		{
+			name:        "help",
+			args:        "[command]",
+			summary:     "Show help for a command",
+			description: "Shows the usage and options of a command.",
+			run:         runHelp,
+		},
+	}
+}
+
+// usageError is an error in the command line; the command's usage is shown with it
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// exitStatus ends a command with a non-zero status without an error message,
+// e.g. when diff finds differences
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// findCommand returns the subcommand with the given name, or nil
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// newFlagSet creates the flag set of a command, with a usage message
+// built from its description
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet("orgmarks "+cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: orgmarks %s %s\n\n%s\n", cmd.name, cmd.args, cmd.description)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(out, "\nOptions:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// runCommand runs a subcommand and returns the exit status
+func runCommand(cmd *command, args []string) int {
+	fs := newFlagSet(cmd)
+	err := cmd.run(fs, args)
+
+	var status exitStatus
+	var usage usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &status):
+		return int(status)
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.SetOutput(os.Stderr)
+		fs.Usage()
+		return 1
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+}
+
+// parseFlags parses a command's arguments and returns the positional ones.
+// Unlike fs.Parse, flags may also come after positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			// The flag package has already printed the error and usage
+			return nil, exitStatus(1)
+		}
+
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+
+		// Everything after "--" is positional
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// runConvert implements "orgmarks convert"
+func runConvert(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{}
+	p.addInputFlags(fs)
+	p.addTransformFlags(fs)
+	p.addOutputFlags(fs)
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	p.inputs = append(p.inputs, rest...)
+
+	if len(p.inputs) > 1 {
+		return usageError("convert takes a single input file; use merge to combine files")
+	}
+	return convert(p)
+}
+
+// convert converts a single input file to the output format
+func convert(p *pipeline) error {
+	if err := p.checkInputs(); err != nil {
+		return err
+	}
+	if p.output == "" {
+		return usageError("no output file given (use -o)")
+	}
+	inputFile := p.inputs[0]
+
+	// Check the formats are supported
+	inputFormat, err := resolveFormat(inputFile, true, p.inOpts.from)
+	if err != nil {
+		return formatError(err)
+	}
+	outputFormat, err := resolveFormat(p.output, false, p.outOpts.to)
+	if err != nil {
+		return formatError(err)
+	}
+
+	// Check if output file exists and prompt for confirmation
+	if ok, err := p.confirmOverwrite(); err != nil {
+		return err
+	} else if !ok {
+		fmt.Fprintln(os.Stderr, "Operation cancelled")
+		return nil
+	}
+
+	if err := p.run(); err != nil {
+		return err
+	}
+	if inputFormat == outputFormat {
+		p.reportf("Normalized %s → %s\n", displayName(inputFile, true), displayName(p.output, false))
+	} else {
+		p.reportf("Successfully converted %s → %s\n", displayName(inputFile, true), displayName(p.output, false))
+	}
+	return nil
+}
+
+// runNormalize implements "orgmarks normalize"
+func runNormalize(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{}
+	p.addReadFlags(fs)
+	p.addTransformFlags(fs)
+	p.addWriteFlags(fs)
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return usageError("no file given")
+	}
+
+	for _, file := range files {
+		if file == stdio {
+			return usageError("normalize rewrites files in place; use convert -i - -o - for standard input")
+		}
+		p.inputs = stringSlice{file}
+		if err := p.checkInputs(); err != nil {
+			return err
+		}
+
+		// The file is written back in the format it was read in
+		fileFormat, err := resolveFormat(file, true, p.inOpts.from)
+		if err != nil {
+			return formatError(err)
+		}
+		if inputOnlyFormats[fileFormat] {
+			return fmt.Errorf("%s: %s files can only be read, not rewritten", file, fileFormat)
+		}
+
+		p.output = file
+		p.outOpts.to = string(fileFormat)
+		if err := p.run(); err != nil {
+			return err
+		}
+		fmt.Printf("Normalized %s\n", file)
+	}
+	return nil
+}
+
+// formatError adds the list of supported formats to a format detection error
+func formatError(err error) error {
+	return fmt.Errorf("%w\nSupported formats: %s", err, strings.Join(supportedFormats, ", "))
+}
+
+// runMerge implements "orgmarks merge"
+func runMerge(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{}
+	p.addInputFlags(fs)
+	base := fs.String("base", "", "Three-way merge: the state both inputs were made from (e.g. the browser export of the last sync)")
+	p.addMergeFlags(fs)
+	p.addTransformFlags(fs)
+	p.addOutputFlags(fs)
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	p.inputs = append(p.inputs, rest...)
+
+	if *base != "" {
+		if p.mergeOpts.Target != nil || p.mergeOpts.Aliases != nil || p.mergeOpts.Inbox != "" {
+			return usageError("--into, --alias, --browser-aliases and --inbox can't be used with --base")
+		}
+		return mergeThreeWay(p, *base)
+	}
+	return merge(p)
+}
+
+// merge merges all input files into a single output file
+func merge(p *pipeline) error {
+	if err := p.checkInputs(); err != nil {
+		return err
+	}
+	if p.output == "" {
+		return usageError("no output file given (use -o)")
+	}
+	if _, err := resolveFormat(p.output, false, p.outOpts.to); err != nil {
+		return formatError(err)
+	}
+
+	if err := p.run(); err != nil {
+		return err
+	}
+	p.reportf("Successfully merged %d files → %s\n", len(p.inputs), displayName(p.output, false))
+	return nil
+}
+
+// mergeThreeWay merges the two input files, ours and theirs, with the changes
+// made to each since base. Conflicts are listed, and make the command exit
+// with status 1 after writing the result.
+func mergeThreeWay(p *pipeline, base string) error {
+	if len(p.inputs) != 2 {
+		return usageError("a three-way merge needs exactly two input files: ours and theirs")
+	}
+	if err := p.checkInputs(); err != nil {
+		return err
+	}
+	if base == stdio || slices.Contains(p.inputs, base) {
+		return usageError("the base must be a separate file")
+	}
+	if _, err := os.Stat(base); os.IsNotExist(err) {
+		return fmt.Errorf("base file '%s' does not exist", base)
+	}
+	if p.output == "" {
+		return usageError("no output file given (use -o)")
+	}
+	if _, err := resolveFormat(p.output, false, p.outOpts.to); err != nil {
+		return formatError(err)
+	}
+
+	trees := make([]*models.Folder, 3)
+	for i, file := range []string{base, p.inputs[0], p.inputs[1]} {
+		tree, err := parseFile(file, p.readOptions())
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", displayName(file, true), err)
+		}
+		trees[i] = tree
+	}
+
+	root, conflicts := models.ThreeWayMerge(trees[0], trees[1], trees[2])
+	if _, err := p.transform(root); err != nil {
+		return err
+	}
+	if err := p.write(root); err != nil {
+		return err
+	}
+
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "Conflict: %s\n", conflict)
+	}
+	p.reportf("Merged changes to %s and %s since %s → %s\n",
+		displayName(p.inputs[0], true), displayName(p.inputs[1], true), base, displayName(p.output, false))
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "%d conflicts; kept our version (%s)\n", len(conflicts), displayName(p.inputs[0], true))
+		return exitStatus(1)
+	}
+	return nil
+}
+
+// runDedupe implements "orgmarks dedupe"
+func runDedupe(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{deduplicate: true}
+	p.addInputFlags(fs)
+	p.addDedupeFlags(fs)
+	fs.BoolVar(&p.deleteEmpty, "delete-empty", false, "Remove folders left empty by deduplication")
+	p.addOutputFlags(fs)
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	p.inputs = append(p.inputs, rest...)
+
+	if err := p.checkInputs(); err != nil {
+		return err
+	}
+	if p.output == "" {
+		return usageError("no output file given (use -o)")
+	}
+	if _, err := resolveFormat(p.output, false, p.outOpts.to); err != nil {
+		return formatError(err)
+	}
+
+	root, err := p.read()
+	if err != nil {
+		return err
+	}
+	report, err := p.transform(root)
+	if err != nil {
+		return err
+	}
+
+	if ok, err := p.confirmOverwrite(); err != nil {
+		return err
+	} else if !ok {
+		fmt.Fprintln(os.Stderr, "Operation cancelled")
+		return nil
+	}
+
+	if err := p.write(root); err != nil {
+		return err
+	}
+	p.reportf("Removed %d duplicate bookmarks → %s\n", report.Removed(), displayName(p.output, false))
+	return nil
+}
+
+// runSimilar implements "orgmarks similar"
+func runSimilar(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{}
+	p.addInputFlags(fs)
+	threshold := fs.Float64("threshold", models.DefaultSimilarityThreshold, "Lowest score (0 to 1) for two bookmarks to count as near-duplicates")
+	var opts models.SimilarityOptions
+	fs.Func("url-match", "How strictly URLs are compared to tell exact duplicates, which are left to dedupe: exact (default), basic, standard or aggressive", func(value string) error {
+		level, err := models.ParseURLNormalization(value)
+		opts.URLs = level
+		return err
+	})
+	fs.BoolVar(&opts.IncludeDuplicates, "include-duplicates", false, "Also list exact duplicates")
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	p.inputs = append(p.inputs, rest...)
+	if err := p.checkInputs(); err != nil {
+		return err
+	}
+	if *threshold <= 0 || *threshold > 1 {
+		return usageError("the threshold must be between 0 and 1")
+	}
+
+	root, err := p.read()
+	if err != nil {
+		return err
+	}
+
+	opts.Threshold = *threshold
+	clusters := models.FindSimilar(root, opts)
+	if len(clusters) == 0 {
+		fmt.Println("No near-duplicates found")
+		return nil
+	}
+	for n, cluster := range clusters {
+		if n > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Cluster %d: %d bookmarks\n", n+1, len(cluster.Bookmarks))
+		for i, c := range cluster.Bookmarks {
+			fmt.Printf("  %d) %s\n", i+1, formatBookmark(c.Bookmark, c.Path))
+		}
+		for _, pair := range cluster.Pairs {
+			fmt.Printf("     %d ~ %d  score %.2f (URL %.2f, title %.2f)\n",
+				pair.A+1, pair.B+1, pair.Score, pair.URLScore, pair.TitleScore)
+		}
+	}
+	return nil
+}
+
+// runDiff implements "orgmarks diff"
+func runDiff(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{}
+	p.addReadFlags(fs)
+	fs.StringVar(&p.output, "o", stdio, "Output file for the diff")
+	format := fs.String("format", "", "Diff format: text, json or org (default: from the output file extension, or text)")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return usageError("diff needs exactly two files")
+	}
+	diffFormat, err := diffFormatFor(*format, p.output)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	trees := make([]*models.Folder, 2)
+	for i, file := range files {
+		p.inputs = stringSlice{file}
+		if err := p.checkInputs(); err != nil {
+			return err
+		}
+		if trees[i], err = p.read(); err != nil {
+			return err
+		}
+	}
+
+	changes := models.DiffTrees(trees[0], trees[1])
+	if err := writeDiff(trees[1], changes, diffFormat, p.output); err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return exitStatus(1)
+	}
+	return nil
+}
+
+// runStats implements "orgmarks stats"
+func runStats(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{}
+	p.addInputFlags(fs)
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	files := append(p.inputs, rest...)
+	if len(files) == 0 {
+		return usageError("no input file given")
+	}
+
+	for i, file := range files {
+		p.inputs = stringSlice{file}
+		if err := p.checkInputs(); err != nil {
+			return err
+		}
+		root, err := p.read()
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		if len(files) > 1 {
+			fmt.Printf("%s:\n", file)
+		}
+		printStats(os.Stdout, root)
+	}
+	return nil
+}
+
+// printStats writes statistics about a bookmark tree
+func printStats(w io.Writer, root *models.Folder) {
+	var bookmarks, folders, emptyFolders, maxDepth int
+	var tagged, described, shortcuts, duplicates int
+	var oldest, newest time.Time
+	tags := make(map[string]bool)
+	seen := make(map[string]bool)
+
+	walkTree(root, nil, func(node models.Node, path []string) {
+		maxDepth = max(maxDepth, len(path)+1)
+
+		if node.IsFolder() {
+			folders++
+			if len(node.(*models.Folder).Children) == 0 {
+				emptyFolders++
+			}
+			return
+		}
+
+		bookmark := node.(*models.Bookmark)
+		bookmarks++
+		if seen[bookmark.URL] {
+			duplicates++
+		}
+		seen[bookmark.URL] = true
+		if len(bookmark.Tags) > 0 {
+			tagged++
+		}
+		for _, tag := range bookmark.Tags {
+			tags[tag] = true
+		}
+		if bookmark.Description != "" {
+			described++
+		}
+		if bookmark.ShortcutURL != "" {
+			shortcuts++
+		}
+		if added := bookmark.AddDate; !added.IsZero() {
+			if oldest.IsZero() || added.Before(oldest) {
+				oldest = added
+			}
+			if added.After(newest) {
+				newest = added
+			}
+		}
+	})
+
+	fmt.Fprintf(w, "Bookmarks:          %d\n", bookmarks)
+	fmt.Fprintf(w, "Folders:            %d (%d empty)\n", folders, emptyFolders)
+	fmt.Fprintf(w, "Deepest nesting:    %d\n", maxDepth)
+	fmt.Fprintf(w, "Tags:               %d (on %d bookmarks)\n", len(tags), tagged)
+	fmt.Fprintf(w, "With descriptions:  %d\n", described)
+	fmt.Fprintf(w, "With shortcuts:     %d\n", shortcuts)
+	fmt.Fprintf(w, "Duplicate URLs:     %d\n", duplicates)
+	if !oldest.IsZero() {
+		fmt.Fprintf(w, "Added between:      %s and %s\n", oldest.Format("2006-01-02"), newest.Format("2006-01-02"))
+	}
+}
+
+// runCheck implements "orgmarks check"
+func runCheck(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{}
+	p.addInputFlags(fs)
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	files := append(p.inputs, rest...)
+	if len(files) == 0 {
+		return usageError("no input file given")
+	}
+
+	problems := 0
+	report := func(file string, path []string, message string) {
+		location := file
+		if len(path) > 0 {
+			location += ": " + strings.Join(path, "/")
+		}
+		fmt.Printf("%s: %s\n", location, message)
+		problems++
+	}
+
+	for _, file := range files {
+		p.inputs = stringSlice{file}
+		if err := p.checkInputs(); err != nil {
+			report(file, nil, err.Error())
+			continue
+		}
+		root, err := p.read()
+		if err != nil {
+			report(file, nil, err.Error())
+			continue
+		}
+
+		firstSeen := make(map[string]string)
+		walkTree(root, nil, func(node models.Node, path []string) {
+			nodePath := append(path[:len(path):len(path)], node.GetTitle())
+
+			if node.IsFolder() {
+				if len(node.(*models.Folder).Children) == 0 {
+					report(file, nodePath, "empty folder")
+				}
+				return
+			}
+
+			bookmark := node.(*models.Bookmark)
+			if parsed, err := url.Parse(bookmark.URL); err != nil || parsed.Scheme == "" {
+				report(file, nodePath, fmt.Sprintf("invalid URL %q", bookmark.URL))
+			}
+			if strings.TrimSpace(bookmark.Title) == "" {
+				report(file, append(path[:len(path):len(path)], bookmark.URL), "bookmark has no title")
+			}
+			if first, ok := firstSeen[bookmark.URL]; ok {
+				report(file, nodePath, "duplicate of "+first)
+			} else {
+				firstSeen[bookmark.URL] = strings.Join(nodePath, "/")
+			}
+		})
+	}
+
+	if problems > 0 {
+		fmt.Printf("%d problems found\n", problems)
+		return exitStatus(1)
+	}
+	return nil
+}
+
+// runSearch implements "orgmarks search"
+func runSearch(fs *flag.FlagSet, args []string) error {
+	p := &pipeline{}
+	p.addInputFlags(fs)
+	var tags stringSlice
+	fs.Var(&tags, "tag", "Only show bookmarks with this tag (can be specified multiple times)")
+	urlsOnly := fs.Bool("urls", false, "Print only the URLs of matching bookmarks")
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return usageError("no query given")
+	}
+	query := strings.Fields(strings.ToLower(rest[0]))
+	files := append(p.inputs, rest[1:]...)
+	if len(files) == 0 {
+		return usageError("no input file given")
+	}
+
+	matches := 0
+	for _, file := range files {
+		p.inputs = stringSlice{file}
+		if err := p.checkInputs(); err != nil {
+			return err
+		}
+		root, err := p.read()
+		if err != nil {
+			return err
+		}
+
+		walkTree(root, nil, func(node models.Node, path []string) {
+			bookmark, ok := node.(*models.Bookmark)
+			if !ok || !matchesSearch(bookmark, query, tags) {
+				return
+			}
+			matches++
+			if *urlsOnly {
+				fmt.Println(bookmark.URL)
+			} else {
+				fmt.Println(formatBookmark(bookmark, path))
+			}
+		})
+	}
+
+	if matches == 0 {
+		return exitStatus(1)
+	}
+	return nil
+}
+
+// matchesSearch reports whether a bookmark contains all query words and has all tags
+func matchesSearch(bookmark *models.Bookmark, query []string, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, bookmarkTag := range bookmark.Tags {
+			if strings.EqualFold(tag, bookmarkTag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	text := strings.ToLower(strings.Join([]string{
+		bookmark.Title, bookmark.URL, bookmark.Description, strings.Join(bookmark.Tags, " "),
+	}, "\n"))
+	for _, word := range query {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// runVersion implements "orgmarks version"
+func runVersion(fs *flag.FlagSet, args []string) error {
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	printVersion()
+	return nil
+}
+
+// runHelp implements "orgmarks help"
+func runHelp(fs *flag.FlagSet, args []string) error {
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		printUsage(os.Stdout)
+		return nil
+	}
+
+	cmd := findCommand(rest[0])
+	if cmd == nil {
+		return usageError(fmt.Sprintf("unknown command '%s'", rest[0]))
+	}
+
+	// Register the command's flags without running it
+	cmdFlags := newFlagSet(cmd)
+	cmdFlags.SetOutput(os.Stdout)
+	_ = cmd.run(cmdFlags, []string{"-h"})
+	return nil
+}
+
+// walkTree calls fn for every folder and bookmark below root, with the titles
+// of the folders containing it. Separators are skipped.
+func walkTree(folder *models.Folder, path []string, fn func(node models.Node, path []string)) {
+	for _, child := range folder.Children {
+		if models.IsSeparator(child) {
+			continue
+		}
+		fn(child, path)
+		if child.IsFolder() {
+			subfolder := child.(*models.Folder)
+			walkTree(subfolder, append(path[:len(path):len(path)], subfolder.Title), fn)
+		}
+	}
+}
+
+// countBookmarks returns the number of bookmarks in a tree
+func countBookmarks(root *models.Folder) int {
+	count := 0
+	walkTree(root, nil, func(node models.Node, _ []string) {
+		if !node.IsFolder() {
+			count++
+		}
+	})
+	return count
+}
+
+// formatBookmark formats a bookmark on one line, with its folder path
+func formatBookmark(bookmark *models.Bookmark, path []string) string {
+	line := fmt.Sprintf("%s <%s>", bookmark.Title, bookmark.URL)
+	if len(path) > 0 {
+		line += fmt.Sprintf(" [%s]", strings.Join(path, "/"))
+	}
+	if len(bookmark.Tags) > 0 {
+		line += " :" + strings.Join(bookmark.Tags, ":") + ":"
+	}
+	return line
+}
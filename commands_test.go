@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testFlagSet returns a flag set like the commands use, without output
+func testFlagSet() (*flag.FlagSet, *string, *bool) {
+	fs := flag.NewFlagSet("orgmarks test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	output := fs.String("o", "", "Output file")
+	verbose := fs.Bool("v", false, "Verbose")
+	return fs, output, verbose
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		output     string
+		verbose    bool
+	}{
+		{[]string{"a.org", "b.html"}, []string{"a.org", "b.html"}, "", false},
+		{[]string{"-o", "out.org", "a.org"}, []string{"a.org"}, "out.org", false},
+		{[]string{"a.org", "-o", "out.org", "b.html", "-v"}, []string{"a.org", "b.html"}, "out.org", true},
+		{[]string{"-v", "a.org", "--", "-o", "b.html"}, []string{"a.org", "-o", "b.html"}, "", true},
+		{[]string{"-", "-o", "-"}, []string{"-"}, "-", false},
+		{nil, nil, "", false},
+	}
+
+	for _, tt := range tests {
+		fs, output, verbose := testFlagSet()
+		positional, err := parseFlags(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseFlags(%q): unexpected error %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(positional, tt.positional) {
+			t.Errorf("parseFlags(%q): positional %q, want %q", tt.args, positional, tt.positional)
+		}
+		if *output != tt.output || *verbose != tt.verbose {
+			t.Errorf("parseFlags(%q): -o %q -v %v, want -o %q -v %v", tt.args, *output, *verbose, tt.output, tt.verbose)
+		}
+	}
+}
+
+func TestParseFlagsErrors(t *testing.T) {
+	fs, _, _ := testFlagSet()
+	if _, err := parseFlags(fs, []string{"a.org", "-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Expected flag.ErrHelp for -h, got %v", err)
+	}
+
+	// Unknown flags and missing values end the command with status 1; the
+	// flag package has already printed the problem
+	for _, args := range [][]string{{"--unknown"}, {"a.org", "-o"}} {
+		fs, _, _ := testFlagSet()
+		_, err := parseFlags(fs, args)
+		var status exitStatus
+		if !errors.As(err, &status) || status != 1 {
+			t.Errorf("parseFlags(%q): expected exit status 1, got %v", args, err)
+		}
+	}
+}
+
+func TestNormalizeKeepsIcons(t *testing.T) {
+	org := `* Example
+#+ICON_URI: https://example.com/favicon.ico
+#+ICON: data:image/png;base64,iVBORw0KGgo=
+[[https://example.com/]]
+`
+	filename := filepath.Join(t.TempDir(), "icons.org")
+	if err := os.WriteFile(filename, []byte(org), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("orgmarks normalize", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := runNormalize(fs, []string{"--no-ids", filename}); err != nil {
+		t.Fatalf("normalize failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"#+ICON_URI: https://example.com/favicon.ico", "#+ICON: data:image/png;base64,iVBORw0KGgo="} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q to be kept, got:\n%s", expected, data)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// reportJSON is the JSON form of a deduplication report
+type reportJSON struct {
+	Removed int               `json:"removed"`
+	Groups  []reportGroupJSON `json:"groups"`
+}
+
+// reportGroupJSON is a duplicated URL in a JSON deduplication report
+type reportGroupJSON struct {
+	URL    string           `json:"url"`
+	Copies []reportCopyJSON `json:"copies"`
+}
+
+// reportCopyJSON is one copy of a duplicated URL in a JSON deduplication report
+type reportCopyJSON struct {
+	Title        string   `json:"title"`
+	URL          string   `json:"url"`
+	Path         []string `json:"path"`
+	Tags         []string `json:"tags,omitempty"`
+	AddDate      string   `json:"add_date,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Kept         bool     `json:"kept"`
+}
+
+// writeReport writes a deduplication report to the file given with --report:
+// JSON if its name ends in .json, text otherwise. With "-" the text report is
+// printed with the status messages.
+func (p *pipeline) writeReport(report *models.DeduplicateReport) error {
+	var buf bytes.Buffer
+	if strings.HasSuffix(strings.ToLower(p.reportFile), ".json") {
+		if err := writeReportJSON(&buf, report); err != nil {
+			return err
+		}
+	} else {
+		writeReportText(&buf, report)
+	}
+
+	if p.reportFile == stdio {
+		_, err := p.statusOutput().Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(p.reportFile, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// writeReportText writes a deduplication report as text, one block per
+// duplicated URL
+func writeReportText(w io.Writer, report *models.DeduplicateReport) {
+	fmt.Fprintf(w, "%d duplicated URLs, %d bookmarks removed\n", len(report.Groups), report.Removed())
+	for _, group := range report.Groups {
+		fmt.Fprintf(w, "\n%s (%d copies)\n", group.URL, len(group.Copies))
+		for i, c := range group.Copies {
+			status := "removed"
+			if group.Kept == models.KeepAll || i == group.Kept {
+				status = "kept"
+			}
+			fmt.Fprintf(w, "  %-8s %s\n", status+":", formatBookmark(c.Bookmark, c.Path))
+		}
+	}
+}
+
+// writeReportJSON writes a deduplication report as JSON
+func writeReportJSON(w io.Writer, report *models.DeduplicateReport) error {
+	out := reportJSON{
+		Removed: report.Removed(),
+		Groups:  make([]reportGroupJSON, 0, len(report.Groups)),
+	}
+	for _, group := range report.Groups {
+		g := reportGroupJSON{URL: group.URL}
+		for i, c := range group.Copies {
+			path := c.Path
+			if path == nil {
+				path = []string{}
+			}
+			g.Copies = append(g.Copies, reportCopyJSON{
+				Title:        c.Bookmark.Title,
+				URL:          c.Bookmark.URL,
+				Path:         path,
+				Tags:         c.Bookmark.Tags,
+				AddDate:      formatReportTime(c.Bookmark.AddDate),
+				LastModified: formatReportTime(c.Bookmark.LastModified),
+				Kept:         group.Kept == models.KeepAll || i == group.Kept,
+			})
+		}
+		out.Groups = append(out.Groups, g)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// formatReportTime formats a timestamp for a report, or returns "" if it isn't set
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// interactiveResolver returns a function that asks on the terminal which copy
+// of each duplicate to keep. The copy chosen by the strategy is the default,
+// and with the merge strategy the other copies are merged into the chosen one.
+func interactiveResolver(strategy models.DuplicateStrategy) func(copies []models.Duplicate) (int, error) {
+	count := 0
+	return func(copies []models.Duplicate) (int, error) {
+		count++
+		suggested := strategy.Choose(copies)
+
+		fmt.Fprintf(os.Stderr, "\nDuplicate %d: %s\n", count, copies[0].Bookmark.URL)
+		for i, c := range copies {
+			line := formatBookmark(c.Bookmark, c.Path)
+			var dates []string
+			if !c.Bookmark.AddDate.IsZero() {
+				dates = append(dates, "added "+c.Bookmark.AddDate.Format(time.DateOnly))
+			}
+			if !c.Bookmark.LastModified.IsZero() {
+				dates = append(dates, "modified "+c.Bookmark.LastModified.Format(time.DateOnly))
+			}
+			if len(dates) > 0 {
+				line += " (" + strings.Join(dates, ", ") + ")"
+			}
+			fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, line)
+		}
+
+		for {
+			fmt.Fprintf(os.Stderr, "Keep which copy? [1-%d, a = keep all, Enter = %d] ", len(copies), suggested+1)
+			response, err := promptReader.ReadString('\n')
+			if err != nil {
+				return 0, fmt.Errorf("reading input: %w", err)
+			}
+
+			keep := suggested
+			switch response = strings.TrimSpace(strings.ToLower(response)); response {
+			case "":
+			case "a", "all":
+				return models.KeepAll, nil
+			default:
+				n, err := strconv.Atoi(response)
+				if err != nil || n < 1 || n > len(copies) {
+					fmt.Fprintf(os.Stderr, "Please enter a number from 1 to %d, or a\n", len(copies))
+					continue
+				}
+				keep = n - 1
+			}
+
+			if strategy == models.MergeDuplicates {
+				models.MergeCopies(copies, keep)
+			}
+			return keep, nil
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// diffProperty is the property that holds the changes to a node in an
+// annotated Org diff
+const diffProperty = "DIFF"
+
+// diffMarkers are the line prefixes of the text diff
+var diffMarkers = map[models.ChangeType]string{
+	models.ChangeAdded:    "+",
+	models.ChangeRemoved:  "-",
+	models.ChangeMoved:    ">",
+	models.ChangeRenamed:  "~",
+	models.ChangeModified: "*",
+}
+
+// diffJSON is the JSON form of a diff
+type diffJSON struct {
+	Summary map[models.ChangeType]int `json:"summary"`
+	Changes []changeJSON              `json:"changes"`
+}
+
+// changeJSON is one change in a JSON diff
+type changeJSON struct {
+	Type     models.ChangeType `json:"type"`
+	Kind     string            `json:"kind"` // "bookmark" or "folder"
+	Title    string            `json:"title"`
+	URL      string            `json:"url,omitempty"`
+	OldPath  []string          `json:"old_path,omitempty"`
+	NewPath  []string          `json:"new_path,omitempty"`
+	Field    string            `json:"field,omitempty"`
+	OldValue string            `json:"old_value,omitempty"`
+	NewValue string            `json:"new_value,omitempty"`
+}
+
+// diffFormatFor returns the diff format for an output file: the one given
+// with --format, or chosen by the file extension
+func diffFormatFor(format, output string) (string, error) {
+	switch format {
+	case "text", "json", "org":
+		return format, nil
+	case "":
+		switch strings.ToLower(filepath.Ext(output)) {
+		case ".json":
+			return "json", nil
+		case ".org":
+			return "org", nil
+		}
+		return "text", nil
+	}
+	return "", fmt.Errorf("unknown diff format '%s' (valid: text, json, org)", format)
+}
+
+// writeDiff writes the changes to the new tree in the given format to a
+// file, or to standard output for "-"
+func writeDiff(new *models.Folder, changes []models.Change, format, output string) error {
+	if format == "org" {
+		return writeFile(annotateDiff(new, changes), output, outputOptions{to: "org"})
+	}
+
+	var buf bytes.Buffer
+	if format == "json" {
+		if err := writeDiffJSON(&buf, changes); err != nil {
+			return err
+		}
+	} else {
+		writeDiffText(&buf, changes)
+	}
+
+	if output == stdio {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write diff: %w", err)
+	}
+	return nil
+}
+
+// writeDiffText writes one line per change, marked + (added), - (removed),
+// > (moved), ~ (renamed) or * (modified)
+func writeDiffText(w io.Writer, changes []models.Change) {
+	for _, c := range changes {
+		path := c.NewPath
+		if c.Type == models.ChangeRemoved {
+			path = c.OldPath
+		}
+		line := diffMarkers[c.Type] + " " + formatNode(c.Node(), path)
+		if c.Type != models.ChangeAdded && c.Type != models.ChangeRemoved {
+			line += " (" + describeChange(c) + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// writeDiffJSON writes the changes as JSON
+func writeDiffJSON(w io.Writer, changes []models.Change) error {
+	out := diffJSON{
+		Summary: make(map[models.ChangeType]int),
+		Changes: make([]changeJSON, 0, len(changes)),
+	}
+	for _, c := range changes {
+		out.Summary[c.Type]++
+		change := changeJSON{
+			Type:     c.Type,
+			Kind:     "bookmark",
+			Title:    c.Node().GetTitle(),
+			OldPath:  c.OldPath,
+			NewPath:  c.NewPath,
+			Field:    c.Field,
+			OldValue: c.OldValue,
+			NewValue: c.NewValue,
+		}
+		if c.Node().IsFolder() {
+			change.Kind = "folder"
+		} else {
+			change.URL = c.Node().(*models.Bookmark).URL
+		}
+		// A removed node has no path in the new tree
+		if c.Type == models.ChangeRemoved {
+			change.NewPath = nil
+		}
+		out.Changes = append(out.Changes, change)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// annotateDiff marks the changes in the new tree with a DIFF property, and
+// puts removed bookmarks and folders back where they were, so the tree can
+// be written as an Org file showing the changes
+func annotateDiff(new *models.Folder, changes []models.Change) *models.Folder {
+	annotate := func(node models.Node, note string) {
+		var properties *map[string]string
+		if node.IsFolder() {
+			properties = &node.(*models.Folder).Properties
+		} else {
+			properties = &node.(*models.Bookmark).Properties
+		}
+		if *properties == nil {
+			*properties = make(map[string]string)
+		}
+		if existing := (*properties)[diffProperty]; existing != "" {
+			note = existing + "; " + note
+		}
+		(*properties)[diffProperty] = note
+	}
+
+	for _, c := range changes {
+		if c.Type != models.ChangeRemoved {
+			annotate(c.New, describeChange(c))
+			continue
+		}
+
+		// Removed folders come before the bookmarks that were in them, so
+		// those find their folder
+		var removed models.Node
+		if c.Old.IsFolder() {
+			folder := c.Old.(*models.Folder)
+			removed = &models.Folder{Title: folder.Title, AddDate: folder.AddDate, LastModified: folder.LastModified}
+		} else {
+			bookmark := *c.Old.(*models.Bookmark)
+			bookmark.Properties = make(map[string]string, len(bookmark.Properties)+1)
+			for key, value := range c.Old.(*models.Bookmark).Properties {
+				bookmark.Properties[key] = value
+			}
+			removed = &bookmark
+		}
+		annotate(removed, describeChange(c))
+		diffFolder(new, c.NewPath).AddChild(removed)
+	}
+	return new
+}
+
+// diffFolder returns the folder at a path in a tree, creating any folders
+// that are missing
+func diffFolder(root *models.Folder, path []string) *models.Folder {
+	folder := root
+	for _, title := range path {
+		var next *models.Folder
+		for _, child := range folder.Children {
+			if child.IsFolder() && child.GetTitle() == title {
+				next = child.(*models.Folder)
+				break
+			}
+		}
+		if next == nil {
+			next = &models.Folder{Title: title}
+			folder.AddChild(next)
+		}
+		folder = next
+	}
+	return folder
+}
+
+// describeChange describes a change in a few words, e.g. "moved from Dev/Go"
+func describeChange(c models.Change) string {
+	switch c.Type {
+	case models.ChangeMoved:
+		if len(c.OldPath) == 0 {
+			return "moved from the top level"
+		}
+		return "moved from " + strings.Join(c.OldPath, "/")
+	case models.ChangeRenamed:
+		return fmt.Sprintf("renamed from %q", c.OldValue)
+	case models.ChangeModified:
+		switch {
+		case c.Field == "description":
+			return "description changed"
+		case c.OldValue == "":
+			return fmt.Sprintf("%s added", c.Field)
+		case c.NewValue == "":
+			return fmt.Sprintf("%s removed (was %q)", c.Field, c.OldValue)
+		}
+		return fmt.Sprintf("%s changed from %q", c.Field, c.OldValue)
+	}
+	return string(c.Type)
+}
+
+// formatNode formats a bookmark or folder for display with its folder path
+func formatNode(node models.Node, path []string) string {
+	if node.IsFolder() {
+		return strings.Join(append(path[:len(path):len(path)], node.GetTitle()), "/") + "/"
+	}
+	return formatBookmark(node.(*models.Bookmark), path)
+}
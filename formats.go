@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/converter"
+	"github.com/drewherron/orgmarks/internal/models"
+	"github.com/drewherron/orgmarks/internal/parser"
+)
+
+// format identifies a bookmark file format
+type format string
+
+const (
+	formatOrg        format = "org"
+	formatHTML       format = "html"
+	formatFirefox    format = "firefox"
+	formatFirefoxLZ4 format = "jsonlz4"
+	formatChrome     format = "chrome"
+	formatXBEL       format = "xbel"
+	formatPlaces     format = "places"
+	formatSafari     format = "safari"
+	formatMarkdown   format = "markdown"
+	formatOPML       format = "opml"
+	formatCSV        format = "csv"
+	formatPinboard   format = "pinboard"
+	formatRaindrop   format = "raindrop"
+	formatPocket     format = "pocket"
+)
+
+// chromeFileName is the name Chromium-based browsers give their bookmarks file
+const chromeFileName = "Bookmarks"
+
+// stdio is the file name that stands for standard input or output
+const stdio = "-"
+
+// formatNames maps the format names accepted by --from and --to to formats
+var formatNames = map[string]format{
+	"org":      formatOrg,
+	"html":     formatHTML,
+	"firefox":  formatFirefox,
+	"json":     formatFirefox,
+	"jsonlz4":  formatFirefoxLZ4,
+	"chrome":   formatChrome,
+	"xbel":     formatXBEL,
+	"places":   formatPlaces,
+	"safari":   formatSafari,
+	"plist":    formatSafari,
+	"markdown": formatMarkdown,
+	"md":       formatMarkdown,
+	"opml":     formatOPML,
+	"csv":      formatCSV,
+	"pinboard": formatPinboard,
+	"raindrop": formatRaindrop,
+	"pocket":   formatPocket,
+}
+
+// inputOnlyFormats are the formats orgmarks can read but not write
+var inputOnlyFormats = map[format]bool{
+	formatPlaces:   true,
+	formatPinboard: true,
+	formatRaindrop: true,
+	formatPocket:   true,
+}
+
+// supportedFormats describes the supported file names for usage messages
+var supportedFormats = []string{
+	".org",
+	".html, .htm (Netscape bookmarks or Pocket export)",
+	".json (Firefox backup, Chrome bookmarks or Pinboard export)",
+	".jsonlz4 (compressed Firefox backup)",
+	"Bookmarks (Chrome profile file)",
+	".xbel",
+	".sqlite (Firefox places.sqlite, input only)",
+	".plist (Safari Bookmarks.plist)",
+	".md, .markdown",
+	".opml",
+	".csv (bookmark spreadsheet or Raindrop.io export)",
+}
+
+// resolveFormat determines the format of a file: the format named by --from
+// or --to if given, otherwise the format for the file name and content
+func resolveFormat(filename string, input bool, name string) (format, error) {
+	if name == "" {
+		return formatForFile(filename, input)
+	}
+
+	fileFormat, ok := formatNames[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown format '%s' (known formats: %s)", name, strings.Join(formatNameList(), ", "))
+	}
+	if !input && inputOnlyFormats[fileFormat] {
+		return "", fmt.Errorf("%s is not supported as an output format", name)
+	}
+	return fileFormat, nil
+}
+
+// formatNameList returns the names accepted by --from and --to, sorted
+func formatNameList() []string {
+	names := make([]string, 0, len(formatNames))
+	for name := range formatNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatForFile determines the format of a file from its name.
+// The content of .json, .html and .csv input files is sniffed to tell formats
+// sharing an extension apart, and the content of input files without a known
+// extension (including standard input) to find their format.
+func formatForFile(filename string, input bool) (format, error) {
+	if filename == stdio {
+		if !input {
+			return "", fmt.Errorf("use --to to choose the format of standard output")
+		}
+		if fileFormat, err := sniffFile(filename, sniffContent); err != nil || fileFormat != "" {
+			return fileFormat, err
+		}
+		return "", fmt.Errorf("unrecognized format on standard input; use --from to choose it")
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	switch ext {
+	case ".org":
+		return formatOrg, nil
+	case ".html", ".htm":
+		if input {
+			return sniffFile(filename, sniffHTML)
+		}
+		return formatHTML, nil
+	case ".jsonlz4":
+		return formatFirefoxLZ4, nil
+	case ".xbel":
+		return formatXBEL, nil
+	case ".plist":
+		return formatSafari, nil
+	case ".md", ".markdown":
+		return formatMarkdown, nil
+	case ".opml":
+		return formatOPML, nil
+	case ".csv":
+		if input {
+			return sniffFile(filename, sniffCSV)
+		}
+		return formatCSV, nil
+	case ".sqlite":
+		if input {
+			return formatPlaces, nil
+		}
+	case ".json":
+		if input {
+			return sniffFile(filename, sniffJSON)
+		}
+		return formatFirefox, nil
+	case "":
+		if filepath.Base(filename) == chromeFileName {
+			return formatChrome, nil
+		}
+	}
+
+	// Unknown extension: look at the content
+	if input {
+		if fileFormat, err := sniffFile(filename, sniffContent); err != nil || fileFormat != "" {
+			return fileFormat, err
+		}
+	}
+
+	if input {
+		return "", fmt.Errorf("unsupported file format: %s (use --from to choose the format)", filepath.Base(filename))
+	}
+	return "", fmt.Errorf("unsupported file format: %s (use --to to choose the format)", filepath.Base(filename))
+}
+
+// sniffFile determines a file's format from the start of its content
+func sniffFile(filename string, sniff func(header []byte) format) (format, error) {
+	if filename == stdio {
+		data, err := readStdin()
+		if err != nil {
+			return "", err
+		}
+		return sniff(data[:min(len(data), 1024)]), nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, 1024)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	return sniff(header[:n]), nil
+}
+
+// sniffContent guesses the format of a file without a known extension, or
+// returns "" if it can't tell
+func sniffContent(header []byte) format {
+	// Binary formats have a magic number
+	switch {
+	case bytes.HasPrefix(header, []byte("mozLz40\x00")):
+		return formatFirefoxLZ4
+	case bytes.HasPrefix(header, []byte("SQLite format 3\x00")):
+		return formatPlaces
+	case bytes.HasPrefix(header, []byte("bplist00")):
+		return formatSafari
+	}
+
+	text := bytes.TrimSpace(bytes.TrimPrefix(header, []byte("\xef\xbb\xbf")))
+	if len(text) == 0 {
+		return ""
+	}
+	firstLine, _, _ := bytes.Cut(text, []byte("\n"))
+	lower := bytes.ToLower(text)
+
+	switch {
+	case text[0] == '{' || text[0] == '[':
+		return sniffJSON(text)
+	case text[0] == '<':
+		switch {
+		case bytes.Contains(lower, []byte("<!doctype netscape-bookmark-file-1>")):
+			return formatHTML
+		case bytes.Contains(lower, []byte("<xbel")):
+			return formatXBEL
+		case bytes.Contains(lower, []byte("<opml")):
+			return formatOPML
+		case bytes.Contains(lower, []byte("<plist")):
+			return formatSafari
+		case bytes.Contains(lower, []byte("<html")) || bytes.Contains(lower, []byte("<dl")):
+			return sniffHTML(text)
+		}
+	case bytes.HasPrefix(firstLine, []byte("#+")) || bytes.HasPrefix(firstLine, []byte("* ")) && !bytes.Contains(firstLine, []byte("](")):
+		// Org keywords like #+TITLE: or a headline
+		return formatOrg
+	case firstLine[0] == '#' || bytes.HasPrefix(firstLine, []byte("- [")) || bytes.HasPrefix(firstLine, []byte("* [")):
+		// A Markdown heading or a list of links
+		return formatMarkdown
+	case bytes.Contains(bytes.ToLower(firstLine), []byte("url")) && bytes.Contains(firstLine, []byte(",")):
+		// A spreadsheet with a url column
+		return sniffCSV(text)
+	}
+
+	return ""
+}
+
+// stdinData holds standard input once it has been read, since it is both
+// sniffed and parsed
+var stdinData []byte
+
+// readStdin reads all of standard input the first time it is called
+func readStdin() ([]byte, error) {
+	if stdinData == nil {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read standard input: %w", err)
+		}
+		stdinData = data
+	}
+	return stdinData, nil
+}
+
+// sniffJSON guesses the JSON flavour from the start of a file. Chrome files
+// have a "roots" object, Pinboard exports are a list of posts, and Firefox
+// backups start with the places root node.
+func sniffJSON(header []byte) format {
+	if bytes.Contains(header, []byte(`"roots"`)) {
+		return formatChrome
+	}
+	if trimmed := bytes.TrimSpace(header); len(trimmed) > 0 && trimmed[0] == '[' {
+		return formatPinboard
+	}
+	return formatFirefox
+}
+
+// sniffHTML tells Pocket exports apart from Netscape bookmark files.
+// Pocket exports are titled "Pocket Export" and have time_added attributes.
+func sniffHTML(header []byte) format {
+	lower := bytes.ToLower(header)
+	if bytes.Contains(lower, []byte("<title>pocket export</title>")) || bytes.Contains(lower, []byte("time_added=")) {
+		return formatPocket
+	}
+	return formatHTML
+}
+
+// sniffCSV tells Raindrop.io exports apart from orgmarks spreadsheets by the
+// header row, which has Raindrop.io's excerpt column
+func sniffCSV(header []byte) format {
+	firstLine, _, _ := bytes.Cut(header, []byte("\n"))
+	for _, column := range bytes.Split(bytes.ToLower(firstLine), []byte(",")) {
+		if string(bytes.Trim(column, "\" \r")) == "excerpt" {
+			return formatRaindrop
+		}
+	}
+	return formatCSV
+}
+
+// parseFile parses a bookmark file in any supported format and returns the
+// root folder. The file name "-" reads standard input.
+func parseFile(filename string, inOpts inputOptions) (*models.Folder, error) {
+	fileFormat, err := resolveFormat(filename, true, inOpts.from)
+	if err != nil {
+		return nil, err
+	}
+
+	var r *bufio.Reader
+	if filename == stdio {
+		data, err := readStdin()
+		if err != nil {
+			return nil, err
+		}
+		r = bufio.NewReader(bytes.NewReader(data))
+	} else {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		r = bufio.NewReader(file)
+	}
+
+	root, err := parseReader(r, fileFormat, filename, inOpts)
+	if err != nil {
+		return nil, err
+	}
+	// Favicons already in an Org file are always kept
+	if inOpts.dropIcons && fileFormat != formatOrg {
+		converter.DropIcons(root)
+	}
+	return root, nil
+}
+
+// parseReader parses bookmarks in the given format
+func parseReader(r *bufio.Reader, fileFormat format, filename string, inOpts inputOptions) (*models.Folder, error) {
+	switch fileFormat {
+	case formatHTML:
+		htmlParser := parser.NewHTMLParser(r)
+		return htmlParser.Parse()
+	case formatOrg:
+		orgParser := parser.NewOrgParser(r)
+		root, err := orgParser.Parse()
+		if err != nil {
+			return nil, err
+		}
+		// Load favicons stored in sidecar files next to the org file
+		if inOpts.icons && filename != stdio {
+			for _, err := range converter.InlineIcons(root, filepath.Dir(filename), inOpts.iconDir) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		return root, nil
+	case formatFirefox, formatFirefoxLZ4:
+		firefoxParser := parser.NewFirefoxParser(r)
+		return firefoxParser.Parse()
+	case formatChrome:
+		chromeParser := parser.NewChromeParser(r)
+		return chromeParser.Parse()
+	case formatXBEL:
+		xbelParser := parser.NewXBELParser(r)
+		return xbelParser.Parse()
+	case formatPlaces:
+		// Recent changes may only be in the write-ahead log, which isn't read
+		if info, err := os.Stat(filename + "-wal"); err == nil && info.Size() > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s has a non-empty write-ahead log; close Firefox first to include recent changes\n", filepath.Base(filename))
+		}
+		placesParser := parser.NewPlacesParser(r)
+		return placesParser.Parse()
+	case formatSafari:
+		safariParser := parser.NewSafariParser(r)
+		return safariParser.Parse()
+	case formatMarkdown:
+		markdownParser := parser.NewMarkdownParser(r)
+		markdownParser.Tags = inOpts.markdownTags
+		return markdownParser.Parse()
+	case formatOPML:
+		opmlParser := parser.NewOPMLParser(r)
+		return opmlParser.Parse()
+	case formatCSV:
+		csvParser := parser.NewCSVParser(r)
+		return csvParser.Parse()
+	case formatPinboard:
+		pinboardParser := parser.NewPinboardParser(r)
+		return pinboardParser.Parse()
+	case formatRaindrop:
+		raindropParser := parser.NewRaindropParser(r)
+		return raindropParser.Parse()
+	case formatPocket:
+		pocketParser := parser.NewPocketParser(r)
+		return pocketParser.Parse()
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", fileFormat)
+	}
+}
+
+// writeFile writes a bookmark tree to a file, choosing the format by --to or
+// the file name. The file name "-" writes to standard output.
+func writeFile(root *models.Folder, filename string, outOpts outputOptions) error {
+	fileFormat, err := resolveFormat(filename, false, outOpts.to)
+	if err != nil {
+		return err
+	}
+
+	// Move favicons into sidecar files if requested
+	if fileFormat == formatOrg && outOpts.iconDir != "" {
+		if err := converter.ExternalizeIcons(root, filepath.Dir(filename), outOpts.iconDir); err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	var file *atomicFile
+	if filename != stdio {
+		// Write to a temporary file that replaces the output once complete
+		file, err = createAtomic(filename)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.abort()
+		out = file
+	}
+
+	switch fileFormat {
+	case formatOrg:
+		err = converter.ToOrgWithOptions(root, out, outOpts.org)
+	case formatHTML:
+		err = converter.ToHTML(root, out)
+	case formatFirefox:
+		err = converter.ToFirefoxJSON(root, out)
+	case formatFirefoxLZ4:
+		err = converter.ToFirefoxJSONLZ4(root, out)
+	case formatChrome:
+		err = converter.ToChrome(root, out)
+	case formatXBEL:
+		err = converter.ToXBEL(root, out)
+	case formatMarkdown:
+		err = converter.ToMarkdownWithOptions(root, out, outOpts.markdown)
+	case formatOPML:
+		err = converter.ToOPML(root, out)
+	case formatCSV:
+		err = converter.ToCSV(root, out)
+	case formatSafari:
+		if outOpts.plistXML {
+			err = converter.ToSafariXML(root, out)
+		} else {
+			err = converter.ToSafari(root, out)
+		}
+	default:
+		err = fmt.Errorf("%s is not supported as an output format", fileFormat)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", displayName(filename, false), err)
+	}
+
+	if file == nil {
+		return nil
+	}
+	if err := file.commit(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// displayName returns a file name for messages
+func displayName(filename string, input bool) string {
+	switch {
+	case filename != stdio:
+		return filename
+	case input:
+		return "standard input"
+	default:
+		return "standard output"
+	}
+}
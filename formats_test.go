@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSniffContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   format
+	}{
+		{"mozlz4", "mozLz40\x00\x10\x00\x00\x00", formatFirefoxLZ4},
+		{"sqlite", "SQLite format 3\x00\x10\x00", formatPlaces},
+		{"binary plist", "bplist00\xd1\x01\x02", formatSafari},
+		{"firefox", `{"guid":"root________","title":"","children":[]}`, formatFirefox},
+		{"chrome", "{\n   \"checksum\": \"0\",\n   \"roots\": {", formatChrome},
+		{"pinboard", `[{"href":"https://go.dev/","description":"Go"}]`, formatPinboard},
+		{"netscape", "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n<TITLE>Bookmarks</TITLE>", formatHTML},
+		{"html list", "<html><body><dl><dt><a href=\"https://go.dev/\">Go</a>", formatHTML},
+		{"pocket", "<!DOCTYPE html>\n<html><head><title>Pocket Export</title>", formatPocket},
+		{"xbel", "<?xml version=\"1.0\"?>\n<xbel version=\"1.0\">", formatXBEL},
+		{"opml", "<?xml version=\"1.0\"?>\n<opml version=\"2.0\">", formatOPML},
+		{"xml plist", "<?xml version=\"1.0\"?>\n<plist version=\"1.0\">", formatSafari},
+		{"org keyword", "#+TITLE: Bookmarks\n* Dev", formatOrg},
+		{"org headline", "* Bookmarks Toolbar\n** Go\n[[https://go.dev/]]", formatOrg},
+		{"org with BOM", "\xef\xbb\xbf* Dev", formatOrg},
+		{"markdown heading", "# Links\n\n- [Go](https://go.dev/)", formatMarkdown},
+		{"markdown list", "- [Go](https://go.dev/)\n- [Rust](https://rust-lang.org/)", formatMarkdown},
+		{"markdown star list", "* [Go](https://go.dev/)", formatMarkdown},
+		{"csv", "Title,URL,Folder,Tags\nGo,https://go.dev/,Dev,", formatCSV},
+		{"raindrop", "id,title,note,excerpt,url,folder,tags\n", formatRaindrop},
+		{"empty", "  \n", ""},
+		{"plain text", "just some notes", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sniffContent([]byte(tt.header)); got != tt.want {
+			t.Errorf("%s: sniffContent = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatForFile(t *testing.T) {
+	testdata := "test/testdata"
+	inputs := map[string]format{
+		"test_bookmarks.org": formatOrg,
+		"bookmarks.html":     formatHTML,
+		"pocket.html":        formatPocket,
+		"bookmarks.json":     formatFirefox,
+		"pinboard.json":      formatPinboard,
+		"Bookmarks":          formatChrome,
+		"bookmarks.xbel":     formatXBEL,
+		"places.sqlite":      formatPlaces,
+		"Bookmarks.plist":    formatSafari,
+		"awesome.md":         formatMarkdown,
+		"feeds.opml":         formatOPML,
+		"bookmarks.csv":      formatCSV,
+		"raindrop.csv":       formatRaindrop,
+	}
+
+	dir := t.TempDir()
+	for name, want := range inputs {
+		filename := filepath.Join(testdata, name)
+		if got, err := formatForFile(filename, true); err != nil || got != want {
+			t.Errorf("formatForFile(%s) = %q, %v; want %q", name, got, err, want)
+		}
+
+		// The same content without a known extension is sniffed, except for
+		// Chrome files, which are only recognized by their name
+		if want == formatChrome {
+			continue
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		renamed := filepath.Join(dir, name+".export")
+		if err := os.WriteFile(renamed, data, 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", renamed, err)
+		}
+		if got, err := formatForFile(renamed, true); err != nil || got != want {
+			t.Errorf("formatForFile(%s) without extension = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	outputs := map[string]format{
+		"out.org":       formatOrg,
+		"out.HTML":      formatHTML,
+		"out.json":      formatFirefox,
+		"out.jsonlz4":   formatFirefoxLZ4,
+		"dir/Bookmarks": formatChrome,
+		"out.csv":       formatCSV,
+	}
+	for name, want := range outputs {
+		if got, err := formatForFile(name, false); err != nil || got != want {
+			t.Errorf("formatForFile(%s) for output = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	// Input-only formats, unknown extensions and standard output need --to
+	for _, name := range []string{"out.sqlite", "out.txt", stdio} {
+		if got, err := formatForFile(name, false); err == nil {
+			t.Errorf("formatForFile(%s) for output = %q, expected an error", name, got)
+		}
+	}
+}
@@ -2,4 +2,6 @@ module github.com/drewherron/orgmarks
 
 go 1.24.8
 
-require golang.org/x/net v0.46.0 // indirect
+require golang.org/x/net v0.46.0
+
+require golang.org/x/text v0.30.0 // indirect
@@ -0,0 +1,243 @@
+package converter
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// webkitEpochOffset is the number of microseconds between the WebKit epoch
+// (1601-01-01 UTC), which Chromium uses for timestamps, and the Unix epoch
+const webkitEpochOffset = 11644473600000000
+
+// Chromium's fixed GUIDs for its permanent root folders
+const (
+	chromeBookmarkBarGUID = "0bc5d13f-2cba-5d74-951f-3f233fe6c908"
+	chromeOtherGUID       = "82b081ec-3dd3-529c-8475-ab6c344590dd"
+	chromeSyncedGUID      = "4cf2e351-0e85-532b-bb37-df045d8f8d0f"
+)
+
+// chromeGUIDPattern matches the lowercase UUIDs Chromium uses as GUIDs
+var chromeGUIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// chromeFile is the top level of a Chromium "Bookmarks" profile file
+type chromeFile struct {
+	Checksum string `json:"checksum"`
+	Roots    struct {
+		BookmarkBar *chromeNode `json:"bookmark_bar"`
+		Other       *chromeNode `json:"other"`
+		Synced      *chromeNode `json:"synced"`
+	} `json:"roots"`
+	Version int `json:"version"`
+}
+
+// chromeNode is a bookmark or folder. Fields are in alphabetical order, like
+// Chromium writes them. Folders must always have a children list.
+type chromeNode struct {
+	Children     []*chromeNode `json:"children,omitzero"`
+	DateAdded    string        `json:"date_added"`
+	DateLastUsed string        `json:"date_last_used"`
+	DateModified string        `json:"date_modified,omitempty"`
+	GUID         string        `json:"guid"`
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Type         string        `json:"type"`
+	URL          string        `json:"url,omitempty"`
+}
+
+// chromeWriter builds the bookmarks file, assigning ids as it goes
+type chromeWriter struct {
+	nextID int
+	now    time.Time
+}
+
+// ToChrome converts a bookmark tree to a Chromium "Bookmarks" profile file,
+// including the checksum Chromium uses to validate it. The browser must be
+// closed when the file is replaced, or it will overwrite it on exit.
+func ToChrome(root *models.Folder, w io.Writer) error {
+	cw := &chromeWriter{nextID: 1, now: time.Now()}
+
+	var file chromeFile
+	file.Version = 1
+	file.Roots.BookmarkBar = cw.rootNode(chromeBookmarkBarGUID, "Bookmarks bar", root)
+	file.Roots.Other = cw.rootNode(chromeOtherGUID, "Other bookmarks", root)
+	file.Roots.Synced = cw.rootNode(chromeSyncedGUID, "Mobile bookmarks", root)
+
+	roots := map[models.RootRole]*chromeNode{
+		models.RootToolbar: file.Roots.BookmarkBar,
+		models.RootOther:   file.Roots.Other,
+		models.RootMobile:  file.Roots.Synced,
+	}
+
+	for _, child := range root.Children {
+		if child.IsFolder() {
+			folder := child.(*models.Folder)
+			if rootNode, ok := roots[rootFolderRole(folder)]; ok {
+				// Merge the folder's contents into the matching root
+				rootNode.DateAdded, rootNode.DateModified = cw.timestamps(folder.AddDate, folder.LastModified)
+				for _, grandchild := range folder.Children {
+					rootNode.Children = cw.appendNode(rootNode.Children, grandchild)
+				}
+				continue
+			}
+		}
+		// Everything else goes into "Other bookmarks", like Chrome's HTML import
+		file.Roots.Other.Children = cw.appendNode(file.Roots.Other.Children, child)
+	}
+
+	file.Checksum = chromeChecksum(file.Roots.BookmarkBar, file.Roots.Other, file.Roots.Synced)
+
+	// Chromium writes its JSON indented by three spaces
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "   ")
+	return encoder.Encode(&file)
+}
+
+// rootNode creates one of Chromium's permanent root folders
+func (cw *chromeWriter) rootNode(guid, name string, folder *models.Folder) *chromeNode {
+	node := &chromeNode{
+		Children:     []*chromeNode{},
+		DateLastUsed: "0",
+		GUID:         guid,
+		ID:           cw.id(),
+		Name:         name,
+		Type:         "folder",
+	}
+	node.DateAdded, node.DateModified = cw.timestamps(folder.AddDate, folder.LastModified)
+	return node
+}
+
+// appendNode converts a node and appends it to a list of children.
+// Chromium has no separators, so those are left out.
+func (cw *chromeWriter) appendNode(children []*chromeNode, node models.Node) []*chromeNode {
+	if models.IsSeparator(node) {
+		return children
+	}
+	return append(children, cw.convert(node))
+}
+
+// convert converts a bookmark or folder and its children
+func (cw *chromeWriter) convert(node models.Node) *chromeNode {
+	if node.IsFolder() {
+		folder := node.(*models.Folder)
+		converted := &chromeNode{
+			Children:     []*chromeNode{},
+			DateLastUsed: "0",
+			GUID:         chromeGUID(folder.ID),
+			ID:           cw.id(),
+			Name:         folder.Title,
+			Type:         "folder",
+		}
+		converted.DateAdded, converted.DateModified = cw.timestamps(folder.AddDate, folder.LastModified)
+		for _, child := range folder.Children {
+			converted.Children = cw.appendNode(converted.Children, child)
+		}
+		return converted
+	}
+
+	bookmark := node.(*models.Bookmark)
+	converted := &chromeNode{
+		DateLastUsed: "0",
+		GUID:         chromeGUID(bookmark.ID),
+		ID:           cw.id(),
+		Name:         bookmark.Title,
+		Type:         "url",
+		URL:          bookmark.URL,
+	}
+	converted.DateAdded, _ = cw.timestamps(bookmark.AddDate, time.Time{})
+	return converted
+}
+
+// id returns the next node id
+func (cw *chromeWriter) id() string {
+	id := cw.nextID
+	cw.nextID++
+	return strconv.Itoa(id)
+}
+
+// timestamps converts times to Chromium timestamps, using the current time if not set.
+// A zero modification time is written as "0", like Chromium does.
+func (cw *chromeWriter) timestamps(added, modified time.Time) (string, string) {
+	if added.IsZero() {
+		added = cw.now
+	}
+	return chromeTime(added), chromeTime(modified)
+}
+
+// chromeTime formats a time as microseconds since 1601-01-01 UTC
+func chromeTime(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixMicro()+webkitEpochOffset, 10)
+}
+
+// chromeChecksum computes the MD5 checksum Chromium stores alongside the bookmarks.
+// Nodes are hashed depth-first: id, title as UTF-16LE, then "url" and the URL
+// for bookmarks or "folder" for folders (before their children).
+func chromeChecksum(roots ...*chromeNode) string {
+	h := md5.New()
+	for _, root := range roots {
+		updateChromeChecksum(h, root)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// updateChromeChecksum adds a node and its children to the checksum
+func updateChromeChecksum(h hash.Hash, node *chromeNode) {
+	io.WriteString(h, node.ID)
+
+	title := utf16.Encode([]rune(node.Name))
+	buf := make([]byte, 2*len(title))
+	for i, unit := range title {
+		binary.LittleEndian.PutUint16(buf[2*i:], unit)
+	}
+	h.Write(buf)
+
+	if node.Type == "url" {
+		io.WriteString(h, "url")
+		io.WriteString(h, node.URL)
+		return
+	}
+
+	io.WriteString(h, "folder")
+	for _, child := range node.Children {
+		updateChromeChecksum(h, child)
+	}
+}
+
+// chromeGUID returns a node's ID as a Chromium GUID: a lowercase UUID
+func chromeGUID(id string) string {
+	return uuidFor(id)
+}
+
+// uuidFor returns a node's ID if it is a UUID, in lowercase. Other IDs, like
+// Firefox GUIDs, are turned into a (version 8) UUID derived from them, so the
+// node gets the same UUID every time it is written. A node without an ID
+// gets a new random UUID.
+func uuidFor(id string) string {
+	if lower := strings.ToLower(id); chromeGUIDPattern.MatchString(lower) {
+		return lower
+	}
+	if id == "" {
+		return models.NewID()
+	}
+	sum := sha256.Sum256([]byte(id))
+	b := sum[:16]
+	b[6] = b[6]&0x0f | 0x80
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -2,9 +2,12 @@ package converter
 
 import (
 	"bytes"
+	"encoding/base64"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/drewherron/orgmarks/internal/models"
 	"github.com/drewherron/orgmarks/internal/parser"
@@ -204,3 +207,958 @@ func TestOrgToHTML(t *testing.T) {
 	}
 	t.Logf("First %d lines of HTML output:\n%s", sampleSize, strings.Join(lines[:sampleSize], "\n"))
 }
+
+func TestHTMLRootFolders(t *testing.T) {
+	root := &models.Folder{
+		Title: "Bookmarks",
+		Children: []models.Node{
+			// Marked by role, whatever the title
+			&models.Folder{Title: "Toolbar", Role: models.RootToolbar, Children: []models.Node{
+				&models.Folder{Title: "Bookmarks bar"},
+			}},
+			// Recognized by title
+			&models.Folder{Title: "Other Bookmarks"},
+			&models.Folder{Title: "Mobile Bookmarks", Role: models.RootMobile},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := ToHTML(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to HTML: %v", err)
+	}
+	output := buf.String()
+
+	// Only top-level folders are marked, and only those browsers know
+	expected := map[string]string{
+		"Toolbar":          ` PERSONAL_TOOLBAR_FOLDER="true"`,
+		"Bookmarks bar":    "",
+		"Other Bookmarks":  ` UNFILED_BOOKMARKS_FOLDER="true"`,
+		"Mobile Bookmarks": "",
+	}
+	for _, line := range strings.Split(output, "\n") {
+		start := strings.Index(line, `LAST_MODIFIED="`)
+		end := strings.Index(line, "</H3>")
+		if start < 0 || end < 0 {
+			continue
+		}
+		// Skip the timestamp to the attributes that follow it
+		rest := line[start+len(`LAST_MODIFIED="`) : end]
+		rest = rest[strings.Index(rest, `"`)+1:]
+		attrs, title, _ := strings.Cut(rest, ">")
+		if attrs != expected[title] {
+			t.Errorf("Expected '%s' to have attributes %q, got %q", title, expected[title], attrs)
+		}
+	}
+
+	// The roles survive a round trip through HTML and Org
+	root2, err := parser.NewHTMLParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse HTML back: %v", err)
+	}
+	var org bytes.Buffer
+	if err := ToOrg(root2, &org); err != nil {
+		t.Fatalf("Failed to convert to Org: %v", err)
+	}
+	if !strings.Contains(org.String(), "#+ROOT: toolbar") {
+		t.Errorf("Expected the toolbar role in the Org output:\n%s", org.String())
+	}
+	root3, err := parser.NewOrgParser(&org).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Org back: %v", err)
+	}
+	for i, role := range []models.RootRole{models.RootToolbar, models.RootOther, models.RootNone} {
+		folder := root3.Children[i].(*models.Folder)
+		if folder.Role != role {
+			t.Errorf("Expected '%s' to have role %q, got %q", folder.Title, role, folder.Role)
+		}
+		if _, ok := folder.Properties["ROOT"]; ok {
+			t.Errorf("Expected ROOT to be read into the role, not kept as a property")
+		}
+	}
+}
+
+func TestHTMLKeepsIDs(t *testing.T) {
+	root := &models.Folder{Title: "Bookmarks"}
+	folder := &models.Folder{ID: "folder-id", Title: "Dev"}
+	folder.AddChild(&models.Bookmark{ID: "bookmark-id", Title: "Go", URL: "https://go.dev/"})
+	root.AddChild(folder)
+
+	var buf bytes.Buffer
+	if err := ToHTML(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to HTML: %v", err)
+	}
+	root2, err := parser.NewHTMLParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse HTML back: %v", err)
+	}
+
+	folder2 := root2.Children[0].(*models.Folder)
+	if folder2.ID != "folder-id" || folder2.Children[0].(*models.Bookmark).ID != "bookmark-id" {
+		t.Errorf("Expected IDs to survive a round trip through HTML, got %q and %q",
+			folder2.ID, folder2.Children[0].(*models.Bookmark).ID)
+	}
+}
+
+func TestToOrgWithPropertyDrawers(t *testing.T) {
+	root := &models.Folder{Title: "Bookmarks"}
+	folder := &models.Folder{Title: "Tools"}
+	folder.AddChild(&models.Bookmark{
+		ID:          "abc123",
+		Title:       "Wiktionary",
+		URL:         "https://www.wiktionary.org/",
+		ShortcutURL: "wk",
+		Description: "Dictionary",
+		Properties:  map[string]string{"CUSTOM": "value"},
+	})
+	root.AddChild(folder)
+
+	var buf bytes.Buffer
+	if err := ToOrgWithOptions(root, &buf, OrgOptions{PropertyDrawers: true}); err != nil {
+		t.Fatalf("Failed to convert to org: %v", err)
+	}
+
+	expected := `* Tools
+** Wiktionary
+:PROPERTIES:
+:ID: abc123
+:SHORTCUTURL: wk
+:CUSTOM: value
+:END:
+[[https://www.wiktionary.org/]]
+Dictionary
+
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected org output:\n%s\nExpected:\n%s", buf.String(), expected)
+	}
+
+	// Parse it back and verify the metadata survives
+	root2, err := parser.NewOrgParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org back to model: %v", err)
+	}
+
+	bookmark := root2.Children[0].(*models.Folder).Children[0].(*models.Bookmark)
+	if bookmark.ShortcutURL != "wk" {
+		t.Errorf("Expected shortcut 'wk', got %q", bookmark.ShortcutURL)
+	}
+	if bookmark.ID != "abc123" || bookmark.Properties["CUSTOM"] != "value" {
+		t.Errorf("ID or properties not preserved: %q %v", bookmark.ID, bookmark.Properties)
+	}
+	if bookmark.Description != "Dictionary" {
+		t.Errorf("Expected description 'Dictionary', got %q", bookmark.Description)
+	}
+}
+
+func TestToOrgWritesIDsInDrawer(t *testing.T) {
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(&models.Bookmark{
+		ID:          "abc123",
+		Title:       "Wiktionary",
+		URL:         "https://www.wiktionary.org/",
+		ShortcutURL: "wk",
+	})
+
+	var buf bytes.Buffer
+	if err := ToOrg(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to org: %v", err)
+	}
+
+	// Org only knows the ID in a drawer, the rest stays on #+KEY: lines
+	expected := `* Wiktionary
+:PROPERTIES:
+:ID: abc123
+:END:
+#+SHORTCUTURL: wk
+[[https://www.wiktionary.org/]]
+
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected org output:\n%s\nExpected:\n%s", buf.String(), expected)
+	}
+
+	root2, err := parser.NewOrgParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org back to model: %v", err)
+	}
+	bookmark := root2.Children[0].(*models.Bookmark)
+	if bookmark.ID != "abc123" || bookmark.ShortcutURL != "wk" || len(bookmark.Properties) != 0 {
+		t.Errorf("Metadata not preserved: %q %q %v", bookmark.ID, bookmark.ShortcutURL, bookmark.Properties)
+	}
+}
+
+func TestRoundTripTimestamps(t *testing.T) {
+	for _, drawers := range []bool{false, true} {
+		htmlFile, err := os.Open("../../test/testdata/bookmarks.html")
+		if err != nil {
+			t.Fatalf("Failed to open HTML file: %v", err)
+		}
+		root1, err := parser.NewHTMLParser(htmlFile).Parse()
+		htmlFile.Close()
+		if err != nil {
+			t.Fatalf("Failed to parse HTML: %v", err)
+		}
+
+		var orgBuf bytes.Buffer
+		opts := OrgOptions{PropertyDrawers: drawers}
+		if err := ToOrgWithOptions(root1, &orgBuf, opts); err != nil {
+			t.Fatalf("Failed to convert to org: %v", err)
+		}
+
+		if !strings.Contains(orgBuf.String(), "ADD_DATE: [") {
+			t.Errorf("Expected inactive Org timestamps in output:\n%s", orgBuf.String())
+		}
+
+		root2, err := parser.NewOrgParser(&orgBuf).Parse()
+		if err != nil {
+			t.Fatalf("Failed to parse org back to model: %v", err)
+		}
+
+		// Index original timestamps by title
+		type dates struct{ added, modified int64 }
+		original := make(map[string]dates)
+		models.Walk(root1, 0, func(node models.Node, depth int) {
+			switch n := node.(type) {
+			case *models.Bookmark:
+				original[n.Title] = dates{n.AddDate.Unix(), n.LastModified.Unix()}
+			case *models.Folder:
+				if depth > 0 {
+					original[n.Title] = dates{n.AddDate.Unix(), n.LastModified.Unix()}
+				}
+			}
+		})
+
+		checked := 0
+		models.Walk(root2, 0, func(node models.Node, depth int) {
+			if depth == 0 {
+				return
+			}
+			var got dates
+			switch n := node.(type) {
+			case *models.Bookmark:
+				got = dates{n.AddDate.Unix(), n.LastModified.Unix()}
+			case *models.Folder:
+				got = dates{n.AddDate.Unix(), n.LastModified.Unix()}
+			}
+			want, ok := original[node.GetTitle()]
+			if !ok {
+				return
+			}
+			checked++
+			if got != want {
+				t.Errorf("drawers=%v: timestamps of %q changed: got %v, want %v", drawers, node.GetTitle(), got, want)
+			}
+		})
+
+		if checked == 0 {
+			t.Error("No nodes compared after round-trip")
+		}
+
+		// The original Unix timestamps should make it back into the HTML
+		var htmlBuf bytes.Buffer
+		if err := ToHTML(root2, &htmlBuf); err != nil {
+			t.Fatalf("Failed to convert to HTML: %v", err)
+		}
+		if !strings.Contains(htmlBuf.String(), `ADD_DATE="1503757590" LAST_MODIFIED="1503757595"`) {
+			t.Errorf("drawers=%v: original timestamps not found in HTML output", drawers)
+		}
+	}
+}
+
+func TestToOrgNoTimestamps(t *testing.T) {
+	added := time.Date(2024, 11, 3, 1, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(&models.Bookmark{Title: "Example", URL: "https://example.com/", AddDate: added})
+
+	var buf bytes.Buffer
+	if err := ToOrg(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to org: %v", err)
+	}
+	if expected := "#+ADD_DATE: " + added.Local().Format(orgTimestampLayout); !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected %q by default, got:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := ToOrgWithOptions(root, &buf, OrgOptions{NoTimestamps: true}); err != nil {
+		t.Fatalf("Failed to convert to org: %v", err)
+	}
+	if strings.Contains(buf.String(), "ADD_DATE") {
+		t.Errorf("Expected no timestamps, got:\n%s", buf.String())
+	}
+}
+
+func TestToHTMLWithoutDates(t *testing.T) {
+	root := &models.Folder{Title: "Bookmarks"}
+	folder := &models.Folder{Title: "Dev"}
+	folder.AddChild(&models.Bookmark{Title: "Go", URL: "https://go.dev/"})
+	root.AddChild(folder)
+
+	var first, second bytes.Buffer
+	if err := ToHTML(root, &first); err != nil {
+		t.Fatalf("Failed to convert to HTML: %v", err)
+	}
+	if strings.Contains(first.String(), "ADD_DATE") || strings.Contains(first.String(), "LAST_MODIFIED") {
+		t.Errorf("Expected no dates for nodes without them, got:\n%s", first.String())
+	}
+	for _, expected := range []string{"<DT><H3>Dev</H3>", `<DT><A HREF="https://go.dev/">Go</A>`} {
+		if !strings.Contains(first.String(), expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, first.String())
+		}
+	}
+
+	// Nothing is made up, so the output doesn't change between exports
+	if err := ToHTML(root, &second); err != nil {
+		t.Fatalf("Failed to convert to HTML: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Error("Expected the same output when exporting twice")
+	}
+}
+
+func TestRoundTripInlineIcons(t *testing.T) {
+	// Icons can be much larger than bufio.Scanner's default 64KB line limit
+	icon := "data:image/png;base64," + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 50000))
+
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(&models.Bookmark{
+		Title:   "Example",
+		URL:     "https://example.com/",
+		Icon:    icon,
+		IconURI: "https://example.com/favicon.ico",
+	})
+
+	var orgBuf bytes.Buffer
+	if err := ToOrgWithOptions(root, &orgBuf, OrgOptions{PropertyDrawers: true}); err != nil {
+		t.Fatalf("Failed to convert to org: %v", err)
+	}
+
+	root2, err := parser.NewOrgParser(&orgBuf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org with long icon line: %v", err)
+	}
+
+	bookmark := root2.Children[0].(*models.Bookmark)
+	if bookmark.Icon != icon {
+		t.Errorf("Icon not preserved (got %d bytes, want %d)", len(bookmark.Icon), len(icon))
+	}
+	if bookmark.IconURI != "https://example.com/favicon.ico" {
+		t.Errorf("Expected ICON_URI to be preserved, got %q", bookmark.IconURI)
+	}
+
+	var htmlBuf bytes.Buffer
+	if err := ToHTML(root2, &htmlBuf); err != nil {
+		t.Fatalf("Failed to convert to HTML: %v", err)
+	}
+	if !strings.Contains(htmlBuf.String(), `ICON_URI="https://example.com/favicon.ico" ICON="data:image/png;base64,`) {
+		t.Error("ICON and ICON_URI attributes not written to HTML")
+	}
+
+	// Dropped icons are left out of the org file
+	DropIcons(root)
+	var plainBuf bytes.Buffer
+	if err := ToOrg(root, &plainBuf); err != nil {
+		t.Fatalf("Failed to convert to org: %v", err)
+	}
+	if strings.Contains(plainBuf.String(), "ICON") {
+		t.Error("Dropped icons should not be written")
+	}
+}
+
+func TestExternalizeAndInlineIcons(t *testing.T) {
+	icon := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("fake png data"))
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(&models.Bookmark{Title: "Example", URL: "https://example.com/", Icon: icon})
+	root.AddChild(&models.Bookmark{Title: "Broken", URL: "https://broken.example.com/", Icon: "data:image/png;base64,example"})
+
+	baseDir := t.TempDir()
+	if err := ExternalizeIcons(root, baseDir, "icons"); err != nil {
+		t.Fatalf("Failed to externalize icons: %v", err)
+	}
+
+	bookmark := root.Children[0].(*models.Bookmark)
+	if !strings.HasPrefix(bookmark.Icon, "file:icons/") || !strings.HasSuffix(bookmark.Icon, ".png") {
+		t.Fatalf("Expected file: reference into icons/, got %q", bookmark.Icon)
+	}
+	data, err := os.ReadFile(filepath.Join(baseDir, strings.TrimPrefix(bookmark.Icon, "file:")))
+	if err != nil {
+		t.Fatalf("Sidecar icon file not written: %v", err)
+	}
+	if string(data) != "fake png data" {
+		t.Errorf("Unexpected sidecar content: %q", data)
+	}
+
+	// Invalid data URIs are left inline
+	if broken := root.Children[1].(*models.Bookmark); broken.Icon != "data:image/png;base64,example" {
+		t.Errorf("Expected invalid icon to stay inline, got %q", broken.Icon)
+	}
+
+	if errs := InlineIcons(root, baseDir, "icons"); len(errs) != 0 {
+		t.Fatalf("Failed to inline icons: %v", errs)
+	}
+	if bookmark.Icon != icon {
+		t.Errorf("Expected icon to be restored, got %q", bookmark.Icon)
+	}
+}
+
+func TestInlineIconsStaysInIconDir(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(baseDir, "icons"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(baseDir, "secret"), filepath.Join(baseDir, "icons", "link.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	refs := []string{
+		"file:/etc/passwd",
+		"file:secret",
+		"file:icons/../secret",
+		"file:../secret",
+		"file:icons/link.png",
+		"file:icons/missing.png",
+	}
+	root := &models.Folder{Title: "Bookmarks"}
+	for _, ref := range refs {
+		root.AddChild(&models.Bookmark{Title: ref, URL: "https://example.com/", Icon: ref})
+	}
+
+	errs := InlineIcons(root, baseDir, "icons")
+	if len(errs) != len(refs) {
+		t.Errorf("Expected %d errors, got %d: %v", len(refs), len(errs), errs)
+	}
+	for i, child := range root.Children {
+		if icon := child.(*models.Bookmark).Icon; icon != refs[i] {
+			t.Errorf("Expected %s to keep its reference, got %q", refs[i], icon)
+		}
+	}
+}
+
+func TestFirefoxJSONRoundTrip(t *testing.T) {
+	root := models.SampleBookmarkTree()
+
+	for _, compressed := range []bool{false, true} {
+		var buf bytes.Buffer
+		var err error
+		if compressed {
+			err = ToFirefoxJSONLZ4(root, &buf)
+		} else {
+			err = ToFirefoxJSON(root, &buf)
+		}
+		if err != nil {
+			t.Fatalf("Failed to convert to Firefox JSON: %v", err)
+		}
+
+		if !compressed {
+			output := buf.String()
+			for _, expected := range []string{`"guid":"root________"`, `"guid":"toolbar_____"`, `"root":"toolbarFolder"`, `"keyword":"magazine"`, `"tags":"news"`} {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain %s", expected)
+				}
+			}
+		}
+
+		root2, err := parser.NewFirefoxParser(&buf).Parse()
+		if err != nil {
+			t.Fatalf("Failed to parse Firefox JSON back: %v", err)
+		}
+
+		// The sample's "Bookmarks Toolbar" folder maps to the toolbar root and back,
+		// and the (empty) "Other Bookmarks" root is added
+		if models.CountNodes(root2) != models.CountNodes(root)+1 {
+			t.Errorf("compressed=%v: expected %d nodes, got %d", compressed, models.CountNodes(root)+1, models.CountNodes(root2))
+		}
+		toolbar := root2.Children[1].(*models.Folder)
+		if toolbar.Title != "Bookmarks Toolbar" || len(toolbar.Children) != 3 {
+			t.Errorf("compressed=%v: toolbar not preserved: %q with %d children", compressed, toolbar.Title, len(toolbar.Children))
+		}
+
+		magazine := toolbar.Children[1].(*models.Bookmark)
+		if magazine.ShortcutURL != "magazine" || len(magazine.Tags) != 1 {
+			t.Errorf("compressed=%v: bookmark metadata not preserved: %+v", compressed, magazine)
+		}
+		if !magazine.AddDate.Equal(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("compressed=%v: timestamp not preserved: %v", compressed, magazine.AddDate)
+		}
+		if len(magazine.ID) != 12 {
+			t.Errorf("compressed=%v: expected a generated GUID, got %q", compressed, magazine.ID)
+		}
+	}
+}
+
+func TestFirefoxJSONKeepsGUIDs(t *testing.T) {
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(&models.Bookmark{
+		ID:    "Xq2c8GgU4TbN",
+		Title: "Example",
+		URL:   "https://example.com/",
+	})
+	root.AddChild(&models.Bookmark{
+		ID:    "5b1f3a0c-6d2e-4c8b-9f7a-1e2d3c4b5a69",
+		Title: "Chrome",
+		URL:   "https://example.org/",
+	})
+
+	var buf bytes.Buffer
+	if err := ToFirefoxJSON(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to Firefox JSON: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"guid":"Xq2c8GgU4TbN"`) {
+		t.Error("Existing GUID was not reused")
+	}
+
+	// Other IDs always map to the same GUID
+	guid := firefoxGUID(root.Children[1].(*models.Bookmark).ID)
+	if !firefoxGUIDPattern.MatchString(guid) || guid != firefoxGUID("5b1f3a0c-6d2e-4c8b-9f7a-1e2d3c4b5a69") {
+		t.Errorf("Expected a stable GUID derived from the ID, got %q", guid)
+	}
+	if !strings.Contains(buf.String(), `"guid":"`+guid+`"`) {
+		t.Error("Derived GUID was not used")
+	}
+}
+
+func TestFirefoxJSONKeepsSeparators(t *testing.T) {
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(&models.Bookmark{Title: "Example", URL: "https://example.com/"})
+	root.AddChild(&models.Separator{ID: "sEp4r4t0r___"})
+	root.AddChild(&models.Bookmark{Title: "Example Org", URL: "https://example.org/"})
+
+	var buf bytes.Buffer
+	if err := ToFirefoxJSON(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to Firefox JSON: %v", err)
+	}
+	for _, expected := range []string{`"guid":"sEp4r4t0r___"`, `"typeCode":3`, `"type":"text/x-moz-place-separator"`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected output to contain %s", expected)
+		}
+	}
+
+	root2, err := parser.NewFirefoxParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Firefox JSON back: %v", err)
+	}
+	// The top level comes back from the menu, followed by the empty roots
+	if len(root2.Children) < 3 {
+		t.Fatalf("Expected at least 3 children, got %d", len(root2.Children))
+	}
+	if separator, ok := root2.Children[1].(*models.Separator); !ok || separator.ID != "sEp4r4t0r___" {
+		t.Errorf("Separator not preserved: %#v", root2.Children[1])
+	}
+}
+
+func TestSeparatorsRoundTrip(t *testing.T) {
+	folder := &models.Folder{Title: "Dev"}
+	folder.AddChild(&models.Bookmark{Title: "Go", URL: "https://go.dev/"})
+	folder.AddChild(&models.Separator{ID: "sEp4r4t0r___"})
+	folder.AddChild(&models.Bookmark{Title: "Rust", URL: "https://www.rust-lang.org/"})
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(folder)
+
+	for _, format := range []string{"org", "html"} {
+		var buf bytes.Buffer
+		var root2 *models.Folder
+		var err error
+		if format == "org" {
+			if err := ToOrg(root, &buf); err != nil {
+				t.Fatalf("Failed to convert to Org: %v", err)
+			}
+			root2, err = parser.NewOrgParser(&buf).Parse()
+		} else {
+			if err := ToHTML(root, &buf); err != nil {
+				t.Fatalf("Failed to convert to HTML: %v", err)
+			}
+			root2, err = parser.NewHTMLParser(&buf).Parse()
+		}
+		if err != nil {
+			t.Fatalf("%s: failed to parse back: %v", format, err)
+		}
+
+		folder2 := root2.Children[0].(*models.Folder)
+		if len(folder2.Children) != 3 || !models.IsSeparator(folder2.Children[1]) {
+			t.Fatalf("%s: separator not preserved: %#v", format, folder2.Children)
+		}
+		// Only Org keeps the separator's ID
+		if format == "org" && folder2.Children[1].(*models.Separator).ID != "sEp4r4t0r___" {
+			t.Errorf("org: separator ID not preserved: %#v", folder2.Children[1])
+		}
+	}
+}
+
+func TestChromeRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../test/testdata/Bookmarks")
+	if err != nil {
+		t.Fatalf("Failed to read Chrome bookmarks file: %v", err)
+	}
+
+	root, err := parser.NewChromeParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Chrome bookmarks: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ToChrome(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to Chrome bookmarks: %v", err)
+	}
+	output := buf.String()
+
+	// Ids are assigned in the same order as in the fixture, so the checksum matches
+	if !strings.Contains(output, `"checksum": "5f45469b20dd3f432681f5d61a2e6658"`) {
+		t.Errorf("Checksum does not match the original file:\n%s", output)
+	}
+	for _, expected := range []string{
+		`"guid": "5b1f3a0c-6d2e-4c8b-9f7a-1e2d3c4b5a69"`,
+		`"date_added": "13347158791000000"`,
+		`"name": "Ünïcödé & <Tags>"`,
+		`"url": "https://example.com/?a=1&b=2"`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %s", expected)
+		}
+	}
+
+	root2, err := parser.NewChromeParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Chrome bookmarks back: %v", err)
+	}
+	if models.CountNodes(root2) != models.CountNodes(root) {
+		t.Errorf("Expected %d nodes, got %d", models.CountNodes(root), models.CountNodes(root2))
+	}
+}
+
+func TestChromeEmptyFoldersHaveChildren(t *testing.T) {
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(&models.Folder{Title: "Empty"})
+
+	var buf bytes.Buffer
+	if err := ToChrome(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to Chrome bookmarks: %v", err)
+	}
+
+	// Chrome rejects folders without a children list
+	if count := strings.Count(buf.String(), `"children": [`); count != 4 {
+		t.Errorf("Expected 4 folders with children lists, got %d", count)
+	}
+}
+
+func TestXBELRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../test/testdata/bookmarks.xbel")
+	if err != nil {
+		t.Fatalf("Failed to read XBEL file: %v", err)
+	}
+
+	root, err := parser.NewXBELParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse XBEL: %v", err)
+	}
+
+	// A new bookmark gets an id above the highest existing one
+	root.AddChild(&models.Bookmark{Title: "New", URL: "https://example.com/"})
+
+	var buf bytes.Buffer
+	if err := ToXBEL(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to XBEL: %v", err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		`<!--- highestId :11: for Floccus bookmark sync browser extension -->`,
+		`<bookmark href="https://orgmode.org/" id="1" added="2023-12-16T00:06:31Z">`,
+		`<folder id="2" added="2017-08-26T14:26:30Z">`,
+		`<desc>Distributions and news</desc>`,
+		`<bookmark href="https://fedoramagazine.org/?a=1&amp;b=2" id="3" added="2013-04-30T17:00:24Z" modified="2017-08-26T14:29:46Z">`,
+		`<property name="TAGS">news,linux</property>`,
+		`<title>GNU &lt;Project&gt;</title>`,
+		`<bookmark href="https://example.com/" id="11">`,
+		`    <separator/>`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %s", expected)
+		}
+	}
+	if strings.Contains(output, "XBEL_ID") {
+		t.Error("XBEL ids should be written as attributes, not metadata")
+	}
+
+	root2, err := parser.NewXBELParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse XBEL back: %v\n%s", err, output)
+	}
+	if models.CountNodes(root2) != models.CountNodes(root) {
+		t.Errorf("Expected %d nodes, got %d", models.CountNodes(root), models.CountNodes(root2))
+	}
+	magazine := root2.Children[1].(*models.Folder).Children[0].(*models.Bookmark)
+	if magazine.ShortcutURL != "mag" || len(magazine.Tags) != 2 || magazine.ID != "V2n0aJ7kP1sD" {
+		t.Errorf("Bookmark metadata not preserved: %+v", magazine)
+	}
+}
+
+func TestSafariRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../test/testdata/Bookmarks.plist")
+	if err != nil {
+		t.Fatalf("Failed to read Safari bookmarks: %v", err)
+	}
+
+	root, err := parser.NewSafariParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Safari bookmarks: %v", err)
+	}
+
+	for _, xml := range []bool{false, true} {
+		var buf bytes.Buffer
+		if xml {
+			err = ToSafariXML(root, &buf)
+		} else {
+			err = ToSafari(root, &buf)
+		}
+		if err != nil {
+			t.Fatalf("Failed to convert to Safari bookmarks: %v", err)
+		}
+		if xml != strings.HasPrefix(buf.String(), "<?xml") {
+			t.Errorf("xml=%v: wrong plist format", xml)
+		}
+
+		root2, err := parser.NewSafariParser(&buf).Parse()
+		if err != nil {
+			t.Fatalf("xml=%v: failed to parse Safari bookmarks back: %v", xml, err)
+		}
+		if models.CountNodes(root2) != models.CountNodes(root) {
+			t.Errorf("xml=%v: expected %d nodes, got %d", xml, models.CountNodes(root), models.CountNodes(root2))
+		}
+
+		// Favorites and Reading List go back into their lists
+		if root2.Children[2].GetTitle() != "Favorites" || root2.Children[3].GetTitle() != "Reading List" {
+			t.Errorf("xml=%v: special lists not preserved", xml)
+		}
+		article := root2.Children[3].(*models.Folder).Children[0].(*models.Bookmark)
+		if article.Description == "" || article.AddDate.IsZero() {
+			t.Errorf("xml=%v: Reading List metadata not preserved: %+v", xml, article)
+		}
+		gnu := root2.Children[1].(*models.Folder).Children[0].(*models.Bookmark)
+		if gnu.ID != "B1E2A3C4-0000-4000-8000-000000000023" {
+			t.Errorf("xml=%v: UUID not preserved: %q", xml, gnu.ID)
+		}
+		if favorites := root2.Children[2].(*models.Folder); favorites.ID == "" || favorites.ID != root.Children[2].(*models.Folder).ID {
+			t.Errorf("xml=%v: favorites bar UUID not preserved: %q", xml, favorites.ID)
+		}
+	}
+
+	// The special lists get the same UUIDs every time
+	var first, second bytes.Buffer
+	if err := ToSafariXML(root, &first); err != nil {
+		t.Fatalf("Failed to convert to Safari bookmarks: %v", err)
+	}
+	if err := ToSafariXML(root, &second); err != nil {
+		t.Fatalf("Failed to convert to Safari bookmarks: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Error("Expected writing the same tree twice to give the same file")
+	}
+}
+
+// markdownSampleTree returns a small tree with nested and duplicate folder names
+func markdownSampleTree() *models.Folder {
+	root := &models.Folder{Title: "Links"}
+	root.AddChild(&models.Bookmark{Title: "Top [level]", URL: "https://example.com/a_(b)"})
+
+	tools := &models.Folder{Title: "Dev Tools"}
+	tools.AddChild(&models.Bookmark{
+		Title:       "Go",
+		URL:         "https://go.dev/",
+		Tags:        []string{"golang", "docs"},
+		Description: "The Go website\nwith docs",
+	})
+	nested := &models.Folder{Title: "Editors"}
+	nested.AddChild(&models.Bookmark{Title: "Emacs", URL: "https://www.gnu.org/software/emacs/"})
+	deeper := &models.Folder{Title: "Packages"}
+	deeper.AddChild(&models.Bookmark{Title: "Org", URL: "https://orgmode.org/"})
+	nested.AddChild(deeper)
+	tools.AddChild(nested)
+	root.AddChild(tools)
+
+	other := &models.Folder{Title: "Editors"}
+	other.AddChild(&models.Bookmark{Title: "Vim", URL: "https://www.vim.org/"})
+	root.AddChild(other)
+
+	return root
+}
+
+func TestToMarkdownHeadings(t *testing.T) {
+	var buf bytes.Buffer
+	opts := MarkdownOptions{MaxHeadingLevel: 3, TableOfContents: true}
+	if err := ToMarkdownWithOptions(markdownSampleTree(), &buf, opts); err != nil {
+		t.Fatalf("Failed to convert to Markdown: %v", err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		"# Links\n",
+		"- [Dev Tools](#dev-tools)\n  - [Editors](#editors)\n- [Editors](#editors-1)\n",
+		"- [Top \\[level\\]](<https://example.com/a_(b)>)\n",
+		"## Dev Tools\n\n- [Go](https://go.dev/) - The Go website\n  with docs #golang #docs\n",
+		"### Editors\n\n- [Emacs](https://www.gnu.org/software/emacs/)\n- **Packages**\n  - [Org](https://orgmode.org/)\n",
+		"## Editors\n\n- [Vim](https://www.vim.org/)\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q\n%s", expected, output)
+		}
+	}
+}
+
+func TestToMarkdownLists(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ToMarkdownWithOptions(markdownSampleTree(), &buf, MarkdownOptions{Lists: true, HeadingLevel: 1}); err != nil {
+		t.Fatalf("Failed to convert to Markdown: %v", err)
+	}
+	output := buf.String()
+
+	if strings.Contains(output, "# Links") {
+		t.Error("Heading level 1 should not write a document title")
+	}
+	expected := "- **Dev Tools**\n  - [Go](https://go.dev/) - The Go website\n    with docs #golang #docs\n  - **Editors**\n    - [Emacs]"
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected output to contain %q\n%s", expected, output)
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	root := markdownSampleTree()
+
+	for _, opts := range []MarkdownOptions{{}, {Lists: true}, {MaxHeadingLevel: 3, TableOfContents: true}} {
+		var buf bytes.Buffer
+		if err := ToMarkdownWithOptions(root, &buf, opts); err != nil {
+			t.Fatalf("Failed to convert to Markdown: %v", err)
+		}
+
+		markdownParser := parser.NewMarkdownParser(&buf)
+		markdownParser.Tags = true
+		root2, err := markdownParser.Parse()
+		if err != nil {
+			t.Fatalf("%+v: failed to parse Markdown back: %v", opts, err)
+		}
+
+		if models.CountNodes(root2) != models.CountNodes(root) {
+			t.Errorf("%+v: expected %d nodes, got %d", opts, models.CountNodes(root), models.CountNodes(root2))
+		}
+
+		top := root2.Children[0].(*models.Bookmark)
+		if top.Title != "Top [level]" || top.URL != "https://example.com/a_(b)" {
+			t.Errorf("%+v: escaping not preserved: %+v", opts, top)
+		}
+		tools := root2.Children[1].(*models.Folder)
+		goBookmark := tools.Children[0].(*models.Bookmark)
+		if goBookmark.Description != "The Go website\nwith docs" || strings.Join(goBookmark.Tags, ",") != "golang,docs" {
+			t.Errorf("%+v: bookmark metadata not preserved: %+v", opts, goBookmark)
+		}
+	}
+}
+
+func TestOPMLRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../test/testdata/feeds.opml")
+	if err != nil {
+		t.Fatalf("Failed to read OPML file: %v", err)
+	}
+
+	root, err := parser.NewOPMLParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse OPML: %v", err)
+	}
+	root.Children[0].(*models.Bookmark).Description = "Your life\nin plain text"
+	root.Children[0].(*models.Bookmark).ID = "org-id"
+
+	var buf bytes.Buffer
+	if err := ToOPML(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to OPML: %v", err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		`<title>Reading &amp; Links</title>`,
+		`<outline text="Org Mode" type="link" url="https://orgmode.org/" description="Your life&#10;in plain text" category="emacs,org" created="Sat, 16 Dec 2023 00:06:31 +0000" id="org-id"/>`,
+		`<outline text="Linux" description="Distributions and news">`,
+		`<outline text="Fedora Magazine" type="rss" xmlUrl="https://fedoramagazine.org/feed/" htmlUrl="https://fedoramagazine.org/" category="news"/>`,
+		`url="https://lwn.net/?a=1&amp;b=2"`,
+		`<outline text="Empty"/>`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %s\n%s", expected, output)
+		}
+	}
+
+	root2, err := parser.NewOPMLParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse OPML back: %v", err)
+	}
+	if models.CountNodes(root2) != models.CountNodes(root) {
+		t.Errorf("Expected %d nodes, got %d", models.CountNodes(root), models.CountNodes(root2))
+	}
+	if org := root2.Children[0].(*models.Bookmark); org.Description != "Your life\nin plain text" {
+		t.Errorf("Line breaks in attributes not preserved: %q", org.Description)
+	} else if org.ID != "org-id" {
+		t.Errorf("Expected the ID to be kept, got %q", org.ID)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	root := &models.Folder{Title: "Bookmarks"}
+	root.AddChild(&models.Bookmark{
+		ID:           "go-id",
+		Title:        "Go, the language",
+		URL:          "https://go.dev/",
+		Tags:         []string{"golang", "docs"},
+		ShortcutURL:  "go",
+		Description:  "The \"Go\" website\nwith docs",
+		AddDate:      time.Unix(1704164645, 0),
+		LastModified: time.Unix(1704164700, 0),
+	})
+	music := &models.Folder{ID: "music-id", Title: "Music"}
+	acdc := &models.Folder{ID: "acdc-id", Title: `AC/DC \ Live`}
+	acdc.AddChild(&models.Bookmark{Title: "AC/DC", URL: "https://www.acdc.com/"})
+	music.AddChild(acdc)
+	music.AddChild(&models.Folder{ID: "empty-id", Title: "Empty"})
+	root.AddChild(music)
+
+	var buf bytes.Buffer
+	if err := ToCSV(root, &buf); err != nil {
+		t.Fatalf("Failed to convert to CSV: %v", err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		"folder,title,url,tags,shortcut,description,add_date,last_modified,id\n",
+		",\"Go, the language\",https://go.dev/,\"golang,docs\",go,\"The \"\"Go\"\" website\nwith docs\",2024-01-02T03:04:05Z,2024-01-02T03:05:00Z,go-id\n",
+		`Music/AC\/DC \\ Live,AC/DC,https://www.acdc.com/,,,,,,` + "\n",
+		"Music/Empty,,,,,,,,empty-id\n",
+		"Music,,,,,,,,music-id\n",
+		`Music/AC\/DC \\ Live,,,,,,,,acdc-id` + "\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q\n%s", expected, output)
+		}
+	}
+
+	root2, err := parser.NewCSVParser(&buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV back: %v", err)
+	}
+	if models.CountNodes(root2) != models.CountNodes(root) {
+		t.Errorf("Expected %d nodes, got %d", models.CountNodes(root), models.CountNodes(root2))
+	}
+
+	goBookmark := root2.Children[0].(*models.Bookmark)
+	if goBookmark.Description != "The \"Go\" website\nwith docs" || len(goBookmark.Tags) != 2 ||
+		!goBookmark.LastModified.Equal(time.Unix(1704164700, 0)) || goBookmark.ID != "go-id" {
+		t.Errorf("Bookmark not preserved: %+v", goBookmark)
+	}
+	if empty := root2.Children[1].(*models.Folder).Children[1].(*models.Folder); empty.ID != "empty-id" {
+		t.Errorf("Expected the empty folder to keep its ID, got %q", empty.ID)
+	}
+	folder := root2.Children[1].(*models.Folder).Children[0].(*models.Folder)
+	if folder.Title != `AC/DC \ Live` {
+		t.Errorf("Expected folder title with slash and backslash, got %q", folder.Title)
+	}
+	if music := root2.Children[1].(*models.Folder); music.ID != "music-id" || folder.ID != "acdc-id" {
+		t.Errorf("Expected non-empty folders to keep their IDs, got %q and %q", music.ID, folder.ID)
+	}
+}
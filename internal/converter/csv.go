@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// csvHeader is the header row written by ToCSV
+var csvHeader = []string{"folder", "title", "url", "tags", "shortcut", "description", "add_date", "last_modified", "id"}
+
+// csvPathEscaper escapes folder titles for use in a folder path
+var csvPathEscaper = strings.NewReplacer(`\`, `\\`, "/", `\/`)
+
+// ToCSV converts a bookmark tree to CSV, one bookmark per row.
+// The folder column holds the path of the bookmark's folder, like
+// "Linux/News", with slashes in folder titles escaped as "\/". Every folder
+// also gets a row without a URL, which keeps its ID and lets empty folders
+// survive a round trip.
+func ToCSV(root *models.Folder, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	if err := writeCSVFolder(cw, root, ""); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeCSVFolder writes the rows for a folder's bookmarks and subfolders
+func writeCSVFolder(cw *csv.Writer, folder *models.Folder, path string) error {
+	for _, child := range folder.Children {
+		if child.IsFolder() {
+			subfolder := child.(*models.Folder)
+			subpath := csvPathEscaper.Replace(subfolder.Title)
+			if path != "" {
+				subpath = path + "/" + subpath
+			}
+
+			if err := cw.Write([]string{subpath, "", "", "", "", "", "", "", subfolder.ID}); err != nil {
+				return err
+			}
+			if err := writeCSVFolder(cw, subfolder, subpath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// CSV has no separators
+		bookmark, ok := child.(*models.Bookmark)
+		if !ok {
+			continue
+		}
+		record := []string{
+			path,
+			bookmark.Title,
+			bookmark.URL,
+			strings.Join(bookmark.Tags, ","),
+			bookmark.ShortcutURL,
+			bookmark.Description,
+			csvTime(bookmark.AddDate),
+			csvTime(bookmark.LastModified),
+			bookmark.ID,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// csvTime formats a date column as ISO 8601, or "" if not set
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
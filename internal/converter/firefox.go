@@ -0,0 +1,242 @@
+package converter
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+	"github.com/drewherron/orgmarks/internal/mozlz4"
+)
+
+// Firefox bookmark node types (the typeCode field) and their MIME-style names
+const (
+	firefoxTypeBookmark  = 1
+	firefoxTypeFolder    = 2
+	firefoxTypeSeparator = 3
+
+	firefoxBookmarkType  = "text/x-moz-place"
+	firefoxFolderType    = "text/x-moz-place-container"
+	firefoxSeparatorType = "text/x-moz-place-separator"
+)
+
+// Firefox root folder GUIDs
+const (
+	firefoxRootGUID    = "root________"
+	firefoxMenuGUID    = "menu________"
+	firefoxToolbarGUID = "toolbar_____"
+	firefoxTagsGUID    = "tags________"
+	firefoxUnfiledGUID = "unfiled_____"
+	firefoxMobileGUID  = "mobile______"
+)
+
+// firefoxRootGUIDs maps special root folders to the Firefox roots they belong in.
+// Everything else goes into the bookmarks menu.
+var firefoxRootGUIDs = map[models.RootRole]string{
+	models.RootToolbar: firefoxToolbarGUID,
+	models.RootOther:   firefoxUnfiledGUID,
+	models.RootMobile:  firefoxMobileGUID,
+}
+
+// firefoxGUIDPattern matches valid Firefox bookmark GUIDs
+var firefoxGUIDPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_]{12}$`)
+
+// guidAlphabet is the URL-safe base64 alphabet Firefox uses for GUIDs
+const guidAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// firefoxNode is a node in a Firefox JSON bookmark backup
+type firefoxNode struct {
+	GUID         string         `json:"guid"`
+	Title        string         `json:"title"`
+	Index        int            `json:"index"`
+	DateAdded    int64          `json:"dateAdded"`
+	LastModified int64          `json:"lastModified"`
+	ID           int            `json:"id"`
+	TypeCode     int            `json:"typeCode"`
+	Type         string         `json:"type"`
+	Root         string         `json:"root,omitempty"`
+	IconURI      string         `json:"iconUri,omitempty"`
+	URI          string         `json:"uri,omitempty"`
+	Keyword      string         `json:"keyword,omitempty"`
+	Tags         string         `json:"tags,omitempty"`
+	Children     []*firefoxNode `json:"children,omitempty"`
+}
+
+// firefoxWriter builds the backup tree, assigning ids as it goes
+type firefoxWriter struct {
+	nextID int
+	now    time.Time
+}
+
+// ToFirefoxJSON converts a bookmark tree to a Firefox JSON bookmark backup
+func ToFirefoxJSON(root *models.Folder, w io.Writer) error {
+	fw := &firefoxWriter{nextID: 1, now: time.Now()}
+	placesRoot := fw.build(root)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(placesRoot)
+}
+
+// ToFirefoxJSONLZ4 converts a bookmark tree to a compressed Firefox backup (.jsonlz4)
+func ToFirefoxJSONLZ4(root *models.Folder, w io.Writer) error {
+	var buf bytes.Buffer
+	if err := ToFirefoxJSON(root, &buf); err != nil {
+		return err
+	}
+
+	_, err := w.Write(mozlz4.Encode(buf.Bytes()))
+	return err
+}
+
+// build creates the places root with its fixed child roots and distributes
+// the top-level folders and bookmarks among them
+func (fw *firefoxWriter) build(root *models.Folder) *firefoxNode {
+	placesRoot := fw.rootNode(firefoxRootGUID, "", "placesRoot", root)
+	roots := map[string]*firefoxNode{
+		firefoxMenuGUID:    fw.rootNode(firefoxMenuGUID, "menu", "bookmarksMenuFolder", root),
+		firefoxToolbarGUID: fw.rootNode(firefoxToolbarGUID, "toolbar", "toolbarFolder", root),
+		firefoxTagsGUID:    fw.rootNode(firefoxTagsGUID, "tags", "tagsFolder", root),
+		firefoxUnfiledGUID: fw.rootNode(firefoxUnfiledGUID, "unfiled", "unfiledBookmarksFolder", root),
+		firefoxMobileGUID:  fw.rootNode(firefoxMobileGUID, "mobile", "mobileFolder", root),
+	}
+
+	for _, child := range root.Children {
+		if child.IsFolder() {
+			folder := child.(*models.Folder)
+			if guid, ok := firefoxRootGUIDs[rootFolderRole(folder)]; ok {
+				// Merge the folder's contents into the matching root
+				rootNode := roots[guid]
+				rootNode.DateAdded, rootNode.LastModified = fw.timestamps(folder.AddDate, folder.LastModified)
+				for _, grandchild := range folder.Children {
+					fw.addChild(rootNode, grandchild)
+				}
+				continue
+			}
+		}
+		fw.addChild(roots[firefoxMenuGUID], child)
+	}
+
+	for _, guid := range []string{firefoxMenuGUID, firefoxToolbarGUID, firefoxTagsGUID, firefoxUnfiledGUID, firefoxMobileGUID} {
+		fw.append(placesRoot, roots[guid])
+	}
+
+	return placesRoot
+}
+
+// rootNode creates one of the fixed Firefox root folders
+func (fw *firefoxWriter) rootNode(guid, title, rootName string, folder *models.Folder) *firefoxNode {
+	node := &firefoxNode{
+		GUID:     guid,
+		Title:    title,
+		ID:       fw.id(),
+		TypeCode: firefoxTypeFolder,
+		Type:     firefoxFolderType,
+		Root:     rootName,
+	}
+	node.DateAdded, node.LastModified = fw.timestamps(folder.AddDate, folder.LastModified)
+	return node
+}
+
+// addChild converts a node and appends it to the parent
+func (fw *firefoxWriter) addChild(parent *firefoxNode, node models.Node) {
+	switch node := node.(type) {
+	case *models.Folder:
+		folder := node
+		converted := &firefoxNode{
+			GUID:     firefoxGUID(folder.ID),
+			Title:    folder.Title,
+			ID:       fw.id(),
+			TypeCode: firefoxTypeFolder,
+			Type:     firefoxFolderType,
+		}
+		converted.DateAdded, converted.LastModified = fw.timestamps(folder.AddDate, folder.LastModified)
+		for _, child := range folder.Children {
+			fw.addChild(converted, child)
+		}
+		fw.append(parent, converted)
+	case *models.Bookmark:
+		bookmark := node
+		converted := &firefoxNode{
+			GUID:     firefoxGUID(bookmark.ID),
+			Title:    bookmark.Title,
+			ID:       fw.id(),
+			TypeCode: firefoxTypeBookmark,
+			Type:     firefoxBookmarkType,
+			IconURI:  bookmark.IconURI,
+			URI:      bookmark.URL,
+			Keyword:  bookmark.ShortcutURL,
+			Tags:     strings.Join(bookmark.Tags, ","),
+		}
+		converted.DateAdded, converted.LastModified = fw.timestamps(bookmark.AddDate, bookmark.LastModified)
+		fw.append(parent, converted)
+	case *models.Separator:
+		converted := &firefoxNode{
+			GUID:     firefoxGUID(node.ID),
+			ID:       fw.id(),
+			TypeCode: firefoxTypeSeparator,
+			Type:     firefoxSeparatorType,
+		}
+		converted.DateAdded, converted.LastModified = fw.timestamps(time.Time{}, time.Time{})
+		fw.append(parent, converted)
+	}
+}
+
+// append adds a child node, setting its index within the parent
+func (fw *firefoxWriter) append(parent, child *firefoxNode) {
+	child.Index = len(parent.Children)
+	parent.Children = append(parent.Children, child)
+}
+
+// id returns the next item id
+func (fw *firefoxWriter) id() int {
+	id := fw.nextID
+	fw.nextID++
+	return id
+}
+
+// timestamps converts times to Firefox timestamps (microseconds since the epoch),
+// using the current time if not set
+func (fw *firefoxWriter) timestamps(added, modified time.Time) (int64, int64) {
+	if added.IsZero() {
+		added = fw.now
+	}
+	if modified.IsZero() {
+		modified = added
+	}
+	return added.UnixMicro(), modified.UnixMicro()
+}
+
+// firefoxGUID returns a node's ID if it is a valid Firefox GUID. Other IDs,
+// like Chromium's UUIDs, are turned into a GUID derived from them, so the
+// node gets the same GUID every time it is written. A node without an ID
+// gets a new random GUID.
+func firefoxGUID(id string) string {
+	if firefoxGUIDPattern.MatchString(id) {
+		return id
+	}
+	if id == "" {
+		return newFirefoxGUID()
+	}
+	sum := sha256.Sum256([]byte(id))
+	b := sum[:12]
+	for i := range b {
+		b[i] = guidAlphabet[b[i]&63]
+	}
+	return string(b)
+}
+
+// newFirefoxGUID generates a random 12 character Firefox GUID
+func newFirefoxGUID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	for i := range b {
+		b[i] = guidAlphabet[b[i]&63]
+	}
+	return string(b)
+}
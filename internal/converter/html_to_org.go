@@ -1,27 +1,79 @@
 // Package converter provides functions for converting between the internal
-// bookmark tree representation and external formats (HTML and Org-mode).
+// bookmark tree representation and external formats.
 //
 // ToOrg converts the internal model to Org-mode format.
 // ToHTML converts the internal model to Netscape Bookmark HTML format.
+// ToFirefoxJSON and ToFirefoxJSONLZ4 convert it to Firefox bookmark backups.
+// ToChrome converts it to a Chrome/Chromium "Bookmarks" profile file.
+// ToXBEL converts it to XBEL (used by Floccus).
+// ToSafari and ToSafariXML convert it to a Safari Bookmarks.plist.
+// ToMarkdown renders it as Markdown, for publishing link collections.
+// ToOPML converts it to an OPML outline, and ToCSV to a spreadsheet.
 package converter
 
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/drewherron/orgmarks/internal/models"
 )
 
+// OrgOptions controls how metadata is written by ToOrgWithOptions
+type OrgOptions struct {
+	// PropertyDrawers writes metadata into :PROPERTIES: drawers
+	// instead of #+KEY: lines
+	PropertyDrawers bool
+
+	// NoTimestamps leaves out the ADD_DATE and LAST_MODIFIED properties,
+	// which are otherwise written as inactive Org timestamps
+	NoTimestamps bool
+}
+
+// orgTimestampLayout is the layout of inactive Org timestamps. Seconds and
+// the UTC offset are included so that timestamps survive a round trip through
+// Org unchanged, also in another time zone or across a DST change.
+const orgTimestampLayout = "[2006-01-02 Mon 15:04:05 -0700]"
+
+// orgProperty is a single key/value pair written below a headline
+type orgProperty struct {
+	key   string
+	value string
+}
+
 // ToOrg converts a bookmark tree to org-mode format
 func ToOrg(root *models.Folder, w io.Writer) error {
+	return ToOrgWithOptions(root, w, OrgOptions{})
+}
+
+// ToOrgWithOptions converts a bookmark tree to org-mode format using the given options
+func ToOrgWithOptions(root *models.Folder, w io.Writer, opts OrgOptions) error {
 	// Walk the tree and write org-mode format
-	err := writeOrgNode(root, 0, w)
+	err := writeOrgNode(root, 0, w, opts)
 	return err
 }
 
+// orgSeparatorTitle is the title of the headline written for a separator
+const orgSeparatorTitle = "-----"
+
 // writeOrgNode recursively writes a node in org-mode format
-func writeOrgNode(node models.Node, depth int, w io.Writer) error {
+func writeOrgNode(node models.Node, depth int, w io.Writer, opts OrgOptions) error {
+	if separator, ok := node.(*models.Separator); ok {
+		// A headline rather than a bare horizontal rule, so its level is kept
+		stars := strings.Repeat("*", depth)
+		if _, err := fmt.Fprintf(w, "%s %s\n", stars, orgSeparatorTitle); err != nil {
+			return err
+		}
+		props := append(idProperty(separator.ID), orgProperty{"SEPARATOR", "t"})
+		if err := writeOrgProperties(w, props, opts); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+
 	if node.IsFolder() {
 		folder := node.(*models.Folder)
 
@@ -32,11 +84,20 @@ func writeOrgNode(node models.Node, depth int, w io.Writer) error {
 			if _, err := fmt.Fprintf(w, "%s %s\n", stars, folder.Title); err != nil {
 				return err
 			}
+
+			// Write folder properties if present
+			props := idProperty(folder.ID)
+			props = append(props, roleProperty(folder.Role)...)
+			props = append(props, timestampProperties(folder.AddDate, folder.LastModified, opts)...)
+			props = append(props, extraProperties(folder.Properties)...)
+			if err := writeOrgProperties(w, props, opts); err != nil {
+				return err
+			}
 		}
 
 		// Write children (handles empty folders gracefully - just writes headline)
 		for _, child := range folder.Children {
-			if err := writeOrgNode(child, depth+1, w); err != nil {
+			if err := writeOrgNode(child, depth+1, w, opts); err != nil {
 				return err
 			}
 		}
@@ -70,11 +131,21 @@ func writeOrgNode(node models.Node, depth int, w io.Writer) error {
 			return err
 		}
 
-		// Write SHORTCUTURL property if present
+		// Write ID, SHORTCUTURL and any other properties
+		props := idProperty(bookmark.ID)
 		if bookmark.ShortcutURL != "" {
-			if _, err := fmt.Fprintf(w, "#+SHORTCUTURL: %s\n", bookmark.ShortcutURL); err != nil {
-				return err
-			}
+			props = append(props, orgProperty{"SHORTCUTURL", bookmark.ShortcutURL})
+		}
+		props = append(props, timestampProperties(bookmark.AddDate, bookmark.LastModified, opts)...)
+		if bookmark.IconURI != "" {
+			props = append(props, orgProperty{"ICON_URI", bookmark.IconURI})
+		}
+		if bookmark.Icon != "" {
+			props = append(props, orgProperty{"ICON", bookmark.Icon})
+		}
+		props = append(props, extraProperties(bookmark.Properties)...)
+		if err := writeOrgProperties(w, props, opts); err != nil {
+			return err
 		}
 
 		// Write link
@@ -97,3 +168,108 @@ func writeOrgNode(node models.Node, depth int, w io.Writer) error {
 
 	return nil
 }
+
+// idProperty returns the ID property if the node has an ID
+func idProperty(id string) []orgProperty {
+	if id == "" {
+		return nil
+	}
+	return []orgProperty{{"ID", id}}
+}
+
+// roleProperty returns the ROOT property if the folder is a browser root folder
+func roleProperty(role models.RootRole) []orgProperty {
+	if role == models.RootNone {
+		return nil
+	}
+	return []orgProperty{{"ROOT", string(role)}}
+}
+
+// timestampProperties returns the ADD_DATE and LAST_MODIFIED properties
+// that are set, unless timestamps are turned off
+func timestampProperties(addDate, lastModified time.Time, opts OrgOptions) []orgProperty {
+	if opts.NoTimestamps {
+		return nil
+	}
+
+	var props []orgProperty
+	if !addDate.IsZero() {
+		props = append(props, orgProperty{"ADD_DATE", formatOrgTimestamp(addDate)})
+	}
+	if !lastModified.IsZero() {
+		props = append(props, orgProperty{"LAST_MODIFIED", formatOrgTimestamp(lastModified)})
+	}
+	return props
+}
+
+// formatOrgTimestamp formats a time as an inactive Org timestamp in local
+// time, with its offset from UTC
+func formatOrgTimestamp(t time.Time) string {
+	return t.Local().Format(orgTimestampLayout)
+}
+
+// extraProperties returns the additional properties of a node sorted by key
+func extraProperties(properties map[string]string) []orgProperty {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	props := make([]orgProperty, 0, len(keys))
+	for _, key := range keys {
+		props = append(props, orgProperty{key, properties[key]})
+	}
+	return props
+}
+
+// orgDrawerKeys are the properties that are always written to a :PROPERTIES:
+// drawer, because Org only looks for them there (org-id finds entries by ID)
+var orgDrawerKeys = map[string]bool{"ID": true}
+
+// writeOrgProperties writes properties either as a :PROPERTIES: drawer
+// or as #+KEY: lines, depending on the options. Properties that Org only
+// recognizes in a drawer go into one either way, before the #+KEY: lines.
+func writeOrgProperties(w io.Writer, props []orgProperty, opts OrgOptions) error {
+	if len(props) == 0 {
+		return nil
+	}
+
+	if !opts.PropertyDrawers {
+		var drawer, keywords []orgProperty
+		for _, prop := range props {
+			if orgDrawerKeys[prop.key] {
+				drawer = append(drawer, prop)
+			} else {
+				keywords = append(keywords, prop)
+			}
+		}
+		if len(drawer) > 0 {
+			if err := writeOrgDrawer(w, drawer); err != nil {
+				return err
+			}
+		}
+		for _, prop := range keywords {
+			if _, err := fmt.Fprintf(w, "#+%s: %s\n", prop.key, prop.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return writeOrgDrawer(w, props)
+}
+
+// writeOrgDrawer writes properties as a :PROPERTIES: drawer
+func writeOrgDrawer(w io.Writer, props []orgProperty) error {
+	if _, err := fmt.Fprintln(w, ":PROPERTIES:"); err != nil {
+		return err
+	}
+	for _, prop := range props {
+		if _, err := fmt.Fprintf(w, ":%s: %s\n", prop.key, prop.value); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, ":END:")
+	return err
+}
@@ -0,0 +1,197 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// iconExtensions maps favicon MIME types to sidecar file extensions
+var iconExtensions = map[string]string{
+	"image/png":                ".png",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+	"image/gif":                ".gif",
+	"image/jpeg":               ".jpg",
+	"image/svg+xml":            ".svg",
+	"image/webp":               ".webp",
+}
+
+// iconMIMETypes maps sidecar file extensions back to MIME types
+var iconMIMETypes = map[string]string{
+	".png":  "image/png",
+	".ico":  "image/x-icon",
+	".gif":  "image/gif",
+	".jpg":  "image/jpeg",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+// ExternalizeIcons moves inline favicons out of the tree into a sidecar directory.
+// Each icon is written to baseDir/iconDir/<hash of bookmark URL>.<ext> and the
+// bookmark's Icon is replaced by a file: reference relative to baseDir
+// (typically the directory of the Org file). Icons that aren't valid data: URIs
+// are left inline.
+func ExternalizeIcons(root *models.Folder, baseDir, iconDir string) error {
+	var walkErr error
+	models.Walk(root, 0, func(node models.Node, depth int) {
+		bookmark, ok := node.(*models.Bookmark)
+		if walkErr != nil || !ok {
+			return
+		}
+
+		mimeType, data, ok := decodeDataURI(bookmark.Icon)
+		if !ok {
+			return
+		}
+
+		ext, ok := iconExtensions[mimeType]
+		if !ok {
+			ext = ".bin"
+		}
+
+		// Key the file by URL so the same bookmark always maps to the same file
+		sum := sha256.Sum256([]byte(bookmark.URL))
+		name := hex.EncodeToString(sum[:8]) + ext
+
+		dir := filepath.Join(baseDir, iconDir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			walkErr = fmt.Errorf("failed to create icon directory: %w", err)
+			return
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			walkErr = fmt.Errorf("failed to write icon: %w", err)
+			return
+		}
+
+		bookmark.Icon = "file:" + path.Join(filepath.ToSlash(iconDir), name)
+	})
+	return walkErr
+}
+
+// DropIcons removes the favicons from all bookmarks
+func DropIcons(root *models.Folder) {
+	models.Walk(root, 0, func(node models.Node, depth int) {
+		if bookmark, ok := node.(*models.Bookmark); ok {
+			bookmark.Icon = ""
+			bookmark.IconURI = ""
+		}
+	})
+}
+
+// InlineIcons replaces file: icon references with data: URIs, reading the
+// sidecar files in baseDir/iconDir (typically the directory of the Org file
+// and the directory given with --icon-dir). This is the inverse of
+// ExternalizeIcons. References outside that directory are never read, so an
+// Org file can't pull arbitrary files into the output. Icons that can't be
+// read keep their file: reference, and the problems are returned.
+func InlineIcons(root *models.Folder, baseDir, iconDir string) []error {
+	dir, err := filepath.Abs(filepath.Join(baseDir, iconDir))
+	if err != nil {
+		return []error{fmt.Errorf("failed to resolve icon directory: %w", err)}
+	}
+
+	var errs []error
+	models.Walk(root, 0, func(node models.Node, depth int) {
+		bookmark, ok := node.(*models.Bookmark)
+		if !ok {
+			return
+		}
+
+		if !strings.HasPrefix(bookmark.Icon, "file:") {
+			return
+		}
+
+		iconPath, err := sidecarPath(dir, baseDir, strings.TrimPrefix(bookmark.Icon, "file:"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("icon for %s: %w", bookmark.URL, err))
+			return
+		}
+
+		data, err := os.ReadFile(iconPath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read icon for %s: %w", bookmark.URL, err))
+			return
+		}
+
+		mimeType, ok := iconMIMETypes[strings.ToLower(filepath.Ext(iconPath))]
+		if !ok {
+			mimeType = "application/octet-stream"
+		}
+
+		bookmark.Icon = "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	})
+	return errs
+}
+
+// sidecarPath resolves an icon reference relative to baseDir and checks that
+// it is inside the icon directory dir, also after following symlinks
+func sidecarPath(dir, baseDir, ref string) (string, error) {
+	ref = filepath.FromSlash(ref)
+	if filepath.IsAbs(ref) || filepath.VolumeName(ref) != "" {
+		return "", fmt.Errorf("absolute path %q is not allowed", ref)
+	}
+	iconPath, err := filepath.Abs(filepath.Join(baseDir, ref))
+	if err != nil {
+		return "", err
+	}
+	if !insideDir(dir, iconPath) {
+		return "", fmt.Errorf("%q is outside the icon directory", ref)
+	}
+
+	resolved, err := filepath.EvalSymlinks(iconPath)
+	if err != nil {
+		return "", err
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	if !insideDir(realDir, resolved) {
+		return "", fmt.Errorf("%q is outside the icon directory", ref)
+	}
+	return resolved, nil
+}
+
+// insideDir reports whether path is below dir
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// decodeDataURI decodes a data: URI into its MIME type and content
+func decodeDataURI(uri string) (mimeType string, data []byte, ok bool) {
+	if !strings.HasPrefix(uri, "data:") {
+		return "", nil, false
+	}
+
+	header, content, found := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !found {
+		return "", nil, false
+	}
+
+	params := strings.Split(header, ";")
+	mimeType = strings.ToLower(params[0])
+
+	if params[len(params)-1] == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return "", nil, false
+		}
+		return mimeType, decoded, true
+	}
+
+	unescaped, err := url.PathUnescape(content)
+	if err != nil {
+		return "", nil, false
+	}
+	return mimeType, []byte(unescaped), true
+}
@@ -0,0 +1,252 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// MarkdownOptions controls how folders are rendered by ToMarkdownWithOptions
+type MarkdownOptions struct {
+	// HeadingLevel is the heading level of top-level folders (default 2).
+	// Above level 1, the document starts with the root title as "# Title".
+	HeadingLevel int
+
+	// MaxHeadingLevel is the deepest heading level used (default 6).
+	// Folders nested deeper are rendered as nested lists.
+	MaxHeadingLevel int
+
+	// Lists renders all folders as nested lists instead of headings
+	Lists bool
+
+	// TableOfContents adds a table of contents linking to the folder headings
+	TableOfContents bool
+}
+
+// markdownHeading is a folder rendered as a heading
+type markdownHeading struct {
+	level int
+	title string
+	slug  string
+}
+
+// markdownWriter renders a bookmark tree as Markdown
+type markdownWriter struct {
+	w        io.Writer
+	opts     MarkdownOptions
+	headings []markdownHeading
+	slugs    map[*models.Folder]string
+	used     map[string]int
+}
+
+// ToMarkdown converts a bookmark tree to Markdown, with folders as headings
+func ToMarkdown(root *models.Folder, w io.Writer) error {
+	return ToMarkdownWithOptions(root, w, MarkdownOptions{})
+}
+
+// ToMarkdownWithOptions converts a bookmark tree to Markdown using the given options.
+// Bookmarks are written as list items: "- [Title](url) - Description #tag".
+// With headings, the bookmarks of a folder come before its subfolders.
+// Markdown is meant to be read, so like dates and shortcuts, IDs are left
+// out: there is no place for them that wouldn't show up in the rendered page.
+func ToMarkdownWithOptions(root *models.Folder, w io.Writer, opts MarkdownOptions) error {
+	if opts.HeadingLevel < 1 || opts.HeadingLevel > 6 {
+		opts.HeadingLevel = 2
+	}
+	if opts.MaxHeadingLevel < opts.HeadingLevel || opts.MaxHeadingLevel > 6 {
+		opts.MaxHeadingLevel = 6
+	}
+
+	mw := &markdownWriter{
+		w:     w,
+		opts:  opts,
+		slugs: make(map[*models.Folder]string),
+		used:  make(map[string]int),
+	}
+
+	if opts.HeadingLevel > 1 && root.Title != "" {
+		if _, err := fmt.Fprintf(w, "# %s\n\n", escapeMarkdown(root.Title)); err != nil {
+			return err
+		}
+		mw.slug(root.Title)
+	}
+
+	// Collect headings first so the table of contents can come before them
+	if !opts.Lists {
+		mw.collectHeadings(root, opts.HeadingLevel)
+	}
+	if opts.TableOfContents && len(mw.headings) > 0 {
+		if err := mw.writeTableOfContents(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Lists {
+		if err := mw.writeList(root.Children, 0); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+
+	return mw.writeSection(root, opts.HeadingLevel)
+}
+
+// collectHeadings records the folders rendered as headings, in document order
+func (mw *markdownWriter) collectHeadings(folder *models.Folder, level int) {
+	if level > mw.opts.MaxHeadingLevel {
+		return
+	}
+	for _, child := range folder.Children {
+		if child.IsFolder() {
+			subfolder := child.(*models.Folder)
+			slug := mw.slug(subfolder.Title)
+			mw.slugs[subfolder] = slug
+			mw.headings = append(mw.headings, markdownHeading{level, subfolder.Title, slug})
+			mw.collectHeadings(subfolder, level+1)
+		}
+	}
+}
+
+// writeTableOfContents writes a nested list of links to the headings
+func (mw *markdownWriter) writeTableOfContents() error {
+	for _, heading := range mw.headings {
+		indent := strings.Repeat("  ", heading.level-mw.opts.HeadingLevel)
+		if _, err := fmt.Fprintf(mw.w, "%s- [%s](#%s)\n", indent, escapeMarkdown(heading.title), heading.slug); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(mw.w)
+	return err
+}
+
+// writeSection writes a folder's bookmarks (and folders too deep for headings)
+// as a list, followed by its subfolders as headings
+func (mw *markdownWriter) writeSection(folder *models.Folder, level int) error {
+	var items, sections []models.Node
+	for _, child := range folder.Children {
+		switch {
+		case models.IsSeparator(child):
+			// Separators aren't rendered
+		case child.IsFolder() && level <= mw.opts.MaxHeadingLevel:
+			sections = append(sections, child)
+		default:
+			items = append(items, child)
+		}
+	}
+
+	if len(items) > 0 {
+		if err := mw.writeList(items, 0); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(mw.w); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range sections {
+		subfolder := node.(*models.Folder)
+		if _, err := fmt.Fprintf(mw.w, "%s %s\n\n", strings.Repeat("#", level), escapeMarkdown(subfolder.Title)); err != nil {
+			return err
+		}
+		if err := mw.writeSection(subfolder, level+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeList writes bookmarks and folders as nested list items
+func (mw *markdownWriter) writeList(nodes []models.Node, depth int) error {
+	indent := strings.Repeat("  ", depth)
+
+	for _, node := range nodes {
+		if node.IsFolder() {
+			folder := node.(*models.Folder)
+			if _, err := fmt.Fprintf(mw.w, "%s- **%s**\n", indent, escapeMarkdown(folder.Title)); err != nil {
+				return err
+			}
+			if err := mw.writeList(folder.Children, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		bookmark, ok := node.(*models.Bookmark)
+		if !ok {
+			continue
+		}
+		if _, err := fmt.Fprintf(mw.w, "%s- %s\n", indent, markdownBookmark(bookmark, indent+"  ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markdownBookmark renders a bookmark as a link followed by its description and tags.
+// Additional description lines are indented to stay inside the list item.
+func markdownBookmark(bookmark *models.Bookmark, indent string) string {
+	title := bookmark.Title
+	if title == "" {
+		title = bookmark.URL
+	}
+
+	url := bookmark.URL
+	if strings.ContainsAny(url, " ()<>") {
+		url = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+
+	item := fmt.Sprintf("[%s](%s)", escapeMarkdown(title), url)
+
+	if description := strings.TrimSpace(bookmark.Description); description != "" {
+		lines := strings.Split(description, "\n")
+		for i := range lines {
+			lines[i] = strings.TrimSpace(lines[i])
+		}
+		item += " - " + strings.Join(lines, "\n"+indent)
+	}
+
+	for _, tag := range bookmark.Tags {
+		item += " #" + tag
+	}
+
+	return item
+}
+
+// slug returns a unique GitHub-style anchor for a heading
+func (mw *markdownWriter) slug(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(title)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+
+	slug := b.String()
+	count := mw.used[slug]
+	mw.used[slug] = count + 1
+	if count > 0 {
+		slug = fmt.Sprintf("%s-%d", slug, count)
+	}
+	return slug
+}
+
+// escapeMarkdown escapes characters that would be read as link syntax or emphasis
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"[", `\[`,
+		"]", `\]`,
+		"*", `\*`,
+		"_", `\_`,
+		"`", "\\`",
+	).Replace(s)
+}
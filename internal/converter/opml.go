@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// ToOPML converts a bookmark tree to an OPML 2.0 outline.
+// Folders become outlines containing their children and bookmarks become
+// link outlines. Bookmarks with an HTML_URL property (feeds read from OPML)
+// are written back as feed outlines, so RSS readers can import them.
+func ToOPML(root *models.Folder, w io.Writer) error {
+	title := root.Title
+	if title == "" {
+		title = "Bookmarks"
+	}
+
+	header := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head>
+    <title>%s</title>
+  </head>
+  <body>
+`, escapeHTML(title))
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	for _, child := range root.Children {
+		if err := writeOPMLOutline(w, child, 2); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "  </body>\n</opml>\n")
+	return err
+}
+
+// writeOPMLOutline recursively writes an outline element
+func writeOPMLOutline(w io.Writer, node models.Node, depth int) error {
+	// OPML has no separators
+	if models.IsSeparator(node) {
+		return nil
+	}
+
+	indent := strings.Repeat("  ", depth)
+
+	if node.IsFolder() {
+		folder := node.(*models.Folder)
+		attrs := []string{opmlAttr("text", folder.Title)}
+		if desc := folder.Properties["DESCRIPTION"]; desc != "" {
+			attrs = append(attrs, opmlAttr("description", desc))
+		}
+		if !folder.AddDate.IsZero() {
+			attrs = append(attrs, opmlAttr("created", folder.AddDate.UTC().Format(time.RFC1123Z)))
+		}
+		if folder.ID != "" {
+			attrs = append(attrs, opmlAttr("id", folder.ID))
+		}
+
+		if len(folder.Children) == 0 {
+			_, err := fmt.Fprintf(w, "%s<outline %s/>\n", indent, strings.Join(attrs, " "))
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "%s<outline %s>\n", indent, strings.Join(attrs, " ")); err != nil {
+			return err
+		}
+		for _, child := range folder.Children {
+			if err := writeOPMLOutline(w, child, depth+1); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s</outline>\n", indent)
+		return err
+	}
+
+	bookmark := node.(*models.Bookmark)
+	attrs := []string{opmlAttr("text", bookmark.Title)}
+	if htmlURL := bookmark.Properties["HTML_URL"]; htmlURL != "" {
+		attrs = append(attrs, `type="rss"`, opmlAttr("xmlUrl", bookmark.URL), opmlAttr("htmlUrl", htmlURL))
+	} else {
+		attrs = append(attrs, `type="link"`, opmlAttr("url", bookmark.URL))
+	}
+	if bookmark.Description != "" {
+		attrs = append(attrs, opmlAttr("description", bookmark.Description))
+	}
+	if len(bookmark.Tags) > 0 {
+		attrs = append(attrs, opmlAttr("category", strings.Join(bookmark.Tags, ",")))
+	}
+	if !bookmark.AddDate.IsZero() {
+		attrs = append(attrs, opmlAttr("created", bookmark.AddDate.UTC().Format(time.RFC1123Z)))
+	}
+	if bookmark.ShortcutURL != "" {
+		// Not part of OPML, but outlines may carry any attribute
+		attrs = append(attrs, opmlAttr("shortcut", bookmark.ShortcutURL))
+	}
+	if bookmark.ID != "" {
+		attrs = append(attrs, opmlAttr("id", bookmark.ID))
+	}
+
+	_, err := fmt.Fprintf(w, "%s<outline %s/>\n", indent, strings.Join(attrs, " "))
+	return err
+}
+
+// opmlAttr formats an attribute
+func opmlAttr(name, value string) string {
+	return fmt.Sprintf(`%s="%s"`, name, escapeXMLAttr(value))
+}
+
+// escapeXMLAttr escapes a value for use in an XML attribute. Unlike
+// escapeHTML, it also keeps line breaks, which XML parsers would turn into spaces.
+func escapeXMLAttr(s string) string {
+	s = escapeHTML(s)
+	s = strings.ReplaceAll(s, "\r", "&#13;")
+	s = strings.ReplaceAll(s, "\n", "&#10;")
+	s = strings.ReplaceAll(s, "\t", "&#9;")
+	return s
+}
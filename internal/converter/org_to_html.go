@@ -39,21 +39,47 @@ func writeHTMLHeader(w io.Writer) error {
 	return err
 }
 
+// htmlRootAttributes are the folder attributes that tell browsers importing
+// the file which of their root folders a folder is. Firefox, Chrome, Edge and
+// Brave all put a PERSONAL_TOOLBAR_FOLDER in their toolbar; only Firefox has
+// an attribute for its other bookmarks.
+var htmlRootAttributes = map[models.RootRole]string{
+	models.RootToolbar: ` PERSONAL_TOOLBAR_FOLDER="true"`,
+	models.RootOther:   ` UNFILED_BOOKMARKS_FOLDER="true"`,
+}
+
 // writeHTMLNode recursively writes a node in HTML format
 func writeHTMLNode(node models.Node, depth int, w io.Writer) error {
 	indent := strings.Repeat("    ", depth)
 
+	// Firefox exports separators as horizontal rules
+	if models.IsSeparator(node) {
+		_, err := fmt.Fprintf(w, "%s<HR>\n", indent)
+		return err
+	}
+
 	if node.IsFolder() {
 		folder := node.(*models.Folder)
 
 		// Skip root folder (depth 0), only write its children
 		if depth > 0 {
 			// Write folder header
-			addDate := formatTimestamp(folder.AddDate)
-			lastModified := formatTimestamp(folder.LastModified)
+			var attrs strings.Builder
+			for _, attr := range timestampAttributes(folder.AddDate, folder.LastModified) {
+				attrs.WriteString(" " + attr)
+			}
+
+			// Browsers only look for their root folders at the top level
+			if depth == 1 {
+				attrs.WriteString(htmlRootAttributes[rootFolderRole(folder)])
+			}
+			// Browsers ignore the ID, but it survives a round trip through HTML
+			if folder.ID != "" {
+				fmt.Fprintf(&attrs, " ID=\"%s\"", escapeHTML(folder.ID))
+			}
 
-			_, err := fmt.Fprintf(w, "%s<DT><H3 ADD_DATE=\"%s\" LAST_MODIFIED=\"%s\">%s</H3>\n",
-				indent, addDate, lastModified, escapeHTML(folder.Title))
+			_, err := fmt.Fprintf(w, "%s<DT><H3%s>%s</H3>\n",
+				indent, attrs.String(), escapeHTML(folder.Title))
 			if err != nil {
 				return err
 			}
@@ -84,11 +110,8 @@ func writeHTMLNode(node models.Node, depth int, w io.Writer) error {
 		bookmark := node.(*models.Bookmark)
 
 		// Build attributes
-		attrs := []string{
-			fmt.Sprintf("HREF=\"%s\"", escapeHTML(bookmark.URL)),
-			fmt.Sprintf("ADD_DATE=\"%s\"", formatTimestamp(bookmark.AddDate)),
-			fmt.Sprintf("LAST_MODIFIED=\"%s\"", formatTimestamp(bookmark.LastModified)),
-		}
+		attrs := []string{fmt.Sprintf("HREF=\"%s\"", escapeHTML(bookmark.URL))}
+		attrs = append(attrs, timestampAttributes(bookmark.AddDate, bookmark.LastModified)...)
 
 		// Add tags if present
 		if len(bookmark.Tags) > 0 {
@@ -100,6 +123,18 @@ func writeHTMLNode(node models.Node, depth int, w io.Writer) error {
 			attrs = append(attrs, fmt.Sprintf("SHORTCUTURL=\"%s\"", bookmark.ShortcutURL))
 		}
 
+		// Add favicon if present (sidecar file references must be inlined first)
+		if bookmark.IconURI != "" {
+			attrs = append(attrs, fmt.Sprintf("ICON_URI=\"%s\"", escapeHTML(bookmark.IconURI)))
+		}
+		if strings.HasPrefix(bookmark.Icon, "data:") {
+			attrs = append(attrs, fmt.Sprintf("ICON=\"%s\"", escapeHTML(bookmark.Icon)))
+		}
+
+		if bookmark.ID != "" {
+			attrs = append(attrs, fmt.Sprintf("ID=\"%s\"", escapeHTML(bookmark.ID)))
+		}
+
 		// Write bookmark
 		_, err := fmt.Fprintf(w, "%s<DT><A %s>%s</A>\n",
 			indent, strings.Join(attrs, " "), escapeHTML(bookmark.Title))
@@ -117,13 +152,18 @@ func writeHTMLNode(node models.Node, depth int, w io.Writer) error {
 	return nil
 }
 
-// formatTimestamp converts time.Time to Unix timestamp string
-func formatTimestamp(t time.Time) string {
-	if t.IsZero() {
-		// Use current time if not set
-		return fmt.Sprintf("%d", time.Now().Unix())
+// timestampAttributes returns the ADD_DATE and LAST_MODIFIED attributes as
+// Unix timestamps. Dates that aren't known are left out rather than made up,
+// so exporting the same bookmarks twice gives the same file.
+func timestampAttributes(addDate, lastModified time.Time) []string {
+	var attrs []string
+	if !addDate.IsZero() {
+		attrs = append(attrs, fmt.Sprintf("ADD_DATE=\"%d\"", addDate.Unix()))
+	}
+	if !lastModified.IsZero() {
+		attrs = append(attrs, fmt.Sprintf("LAST_MODIFIED=\"%d\"", lastModified.Unix()))
 	}
-	return fmt.Sprintf("%d", t.Unix())
+	return attrs
 }
 
 // escapeHTML escapes special HTML characters
@@ -0,0 +1,32 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// rootFolderTitles maps the (lowercase) names browsers give their special root
+// folders to the root they represent
+var rootFolderTitles = map[string]models.RootRole{
+	"bookmarks toolbar":  models.RootToolbar, // Firefox
+	"bookmarks bar":      models.RootToolbar, // Chrome, Brave
+	"favorites bar":      models.RootToolbar, // Edge
+	"favorites":          models.RootToolbar, // Safari
+	"other bookmarks":    models.RootOther,   // Firefox, Chrome
+	"unsorted bookmarks": models.RootOther,   // Older Firefox
+	"other favorites":    models.RootOther,   // Edge
+	"mobile bookmarks":   models.RootMobile,  // Firefox, Chrome
+	"mobile favorites":   models.RootMobile,  // Edge
+	"reading list":       models.RootReading, // Safari
+}
+
+// rootFolderRole returns which special root a top-level folder stands for:
+// its role if it has one, otherwise guessed from its title. Regular folders
+// get models.RootNone.
+func rootFolderRole(folder *models.Folder) models.RootRole {
+	if folder.Role != models.RootNone {
+		return folder.Role
+	}
+	return rootFolderTitles[strings.ToLower(strings.TrimSpace(folder.Title))]
+}
@@ -0,0 +1,142 @@
+package converter
+
+import (
+	"io"
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/models"
+	"github.com/drewherron/orgmarks/internal/plist"
+)
+
+// ToSafari converts a bookmark tree to a binary Safari Bookmarks.plist
+func ToSafari(root *models.Folder, w io.Writer) error {
+	data, err := plist.EncodeBinary(safariTree(root))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ToSafariXML converts a bookmark tree to a Safari Bookmarks.plist in XML format
+func ToSafariXML(root *models.Folder, w io.Writer) error {
+	data, err := plist.EncodeXML(safariTree(root))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// safariTree builds the plist structure: the History proxy, the favorites bar,
+// the bookmarks menu and the Reading List. Top-level folders named like the
+// toolbar or "Reading List" go into those lists, everything else into the menu.
+// The lists keep the UUIDs of the folders that go into them.
+func safariTree(root *models.Folder) map[string]any {
+	bar := safariList("BookmarksBar", safariListUUID("BookmarksBar"))
+	menu := safariList("BookmarksMenu", safariListUUID("BookmarksMenu"))
+	reading := safariList("com.apple.ReadingList", safariListUUID("com.apple.ReadingList"))
+	reading["ShouldOmitFromUI"] = true
+
+	lists := map[models.RootRole]map[string]any{
+		models.RootToolbar: bar,
+		models.RootReading: reading,
+	}
+
+	for _, child := range root.Children {
+		if child.IsFolder() {
+			folder := child.(*models.Folder)
+			role := rootFolderRole(folder)
+			if list, ok := lists[role]; ok {
+				// Merge the folder's contents into the matching list
+				if folder.ID != "" {
+					list["WebBookmarkUUID"] = safariUUID(folder.ID)
+				}
+				for _, grandchild := range folder.Children {
+					appendSafariChild(list, safariNode(grandchild, role == models.RootReading))
+				}
+				continue
+			}
+		}
+		appendSafariChild(menu, safariNode(child, false))
+	}
+
+	history := map[string]any{
+		"Title":                 "History",
+		"WebBookmarkIdentifier": "History Bookmark Proxy Identifier",
+		"WebBookmarkType":       "WebBookmarkTypeProxy",
+		"WebBookmarkUUID":       safariListUUID("History"),
+	}
+
+	tree := safariList("", safariListUUID(""))
+	tree["WebBookmarkFileVersion"] = 1
+	tree["Children"] = []any{history, bar, menu, reading}
+	return tree
+}
+
+// safariList creates an empty list node
+func safariList(title, uuid string) map[string]any {
+	return map[string]any{
+		"Children":        []any{},
+		"Title":           title,
+		"WebBookmarkType": "WebBookmarkTypeList",
+		"WebBookmarkUUID": uuid,
+	}
+}
+
+// safariNode converts a bookmark or folder and its children.
+// Reading List items also get the date they were added and a preview text.
+// Safari has no separators, so those convert to nil.
+func safariNode(node models.Node, readingList bool) map[string]any {
+	if models.IsSeparator(node) {
+		return nil
+	}
+	if node.IsFolder() {
+		folder := node.(*models.Folder)
+		list := safariList(folder.Title, safariUUID(folder.ID))
+		for _, child := range folder.Children {
+			appendSafariChild(list, safariNode(child, readingList))
+		}
+		return list
+	}
+
+	bookmark := node.(*models.Bookmark)
+	leaf := map[string]any{
+		"URIDictionary":   map[string]any{"title": bookmark.Title},
+		"URLString":       bookmark.URL,
+		"WebBookmarkType": "WebBookmarkTypeLeaf",
+		"WebBookmarkUUID": safariUUID(bookmark.ID),
+	}
+
+	if readingList {
+		item := map[string]any{}
+		if !bookmark.AddDate.IsZero() {
+			item["DateAdded"] = bookmark.AddDate
+		}
+		if bookmark.Description != "" {
+			item["PreviewText"] = bookmark.Description
+		}
+		leaf["ReadingList"] = item
+	}
+
+	return leaf
+}
+
+// appendSafariChild adds a node to a list's children, if there is one
+func appendSafariChild(list, child map[string]any) {
+	if child == nil {
+		return
+	}
+	list["Children"] = append(list["Children"].([]any), child)
+}
+
+// safariUUID returns a node's ID as a UUID in Safari's uppercase form
+func safariUUID(id string) string {
+	return strings.ToUpper(uuidFor(id))
+}
+
+// safariListUUID returns the UUID of one of Safari's special lists, derived
+// from its title so that writing the same tree twice gives the same file
+func safariListUUID(title string) string {
+	return safariUUID("safari:" + title)
+}
@@ -0,0 +1,224 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// xbelMetadataOwner identifies the <metadata> blocks orgmarks uses to store
+// fields XBEL has no elements for (tags, keywords, favicons, properties)
+const xbelMetadataOwner = "https://github.com/drewherron/orgmarks"
+
+// xbelWriter writes an XBEL document, keeping existing ids where possible
+type xbelWriter struct {
+	w       io.Writer
+	ids     map[models.Node]int
+	highest int
+}
+
+// ToXBEL converts a bookmark tree to an XBEL document.
+// Ids from a previously read XBEL file are kept, so sync tools like Floccus
+// recognize unchanged bookmarks; new items get ids above the highest one.
+func ToXBEL(root *models.Folder, w io.Writer) error {
+	xw := &xbelWriter{w: w, ids: make(map[models.Node]int)}
+	xw.assignIDs(root)
+
+	header := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE xbel PUBLIC "+//IDN python.org//DTD XML Bookmark Exchange Language 1.0//EN//XML" "http://pyxml.sourceforge.net/topics/dtds/xbel.dtd">
+<xbel version="1.0">
+`
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	// Floccus reads the highest id from this comment
+	if _, err := fmt.Fprintf(w, "<!--- highestId :%d: for Floccus bookmark sync browser extension -->\n", xw.highest); err != nil {
+		return err
+	}
+
+	for _, child := range root.Children {
+		if err := xw.writeNode(child, 1); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "</xbel>\n")
+	return err
+}
+
+// assignIDs gives every node an id. Existing XBEL ids are kept unless they
+// are used twice; everything else is numbered after the highest existing id.
+func (xw *xbelWriter) assignIDs(root *models.Folder) {
+	used := make(map[int]bool)
+	var pending []models.Node
+
+	var walk func(folder *models.Folder)
+	walk = func(folder *models.Folder) {
+		for _, child := range folder.Children {
+			if models.IsSeparator(child) {
+				continue
+			}
+			id, err := strconv.Atoi(nodeProperties(child)["XBEL_ID"])
+			if err == nil && id > 0 && !used[id] {
+				used[id] = true
+				xw.ids[child] = id
+				xw.highest = max(xw.highest, id)
+			} else {
+				pending = append(pending, child)
+			}
+			if child.IsFolder() {
+				walk(child.(*models.Folder))
+			}
+		}
+	}
+	walk(root)
+
+	for _, node := range pending {
+		xw.highest++
+		xw.ids[node] = xw.highest
+	}
+}
+
+// writeNode recursively writes a folder, bookmark or separator element
+func (xw *xbelWriter) writeNode(node models.Node, depth int) error {
+	indent := strings.Repeat("  ", depth)
+
+	// XBEL separators have no id or other attributes
+	if models.IsSeparator(node) {
+		_, err := fmt.Fprintf(xw.w, "%s<separator/>\n", indent)
+		return err
+	}
+
+	id := xw.ids[node]
+
+	if node.IsFolder() {
+		folder := node.(*models.Folder)
+
+		if _, err := fmt.Fprintf(xw.w, "%s<folder id=\"%d\"%s>\n", indent, id, xbelDateAttr("added", folder.AddDate)); err != nil {
+			return err
+		}
+		if err := xw.writeElement(indent+"  ", "title", folder.Title); err != nil {
+			return err
+		}
+		if desc := folder.Properties["DESCRIPTION"]; desc != "" {
+			if err := xw.writeElement(indent+"  ", "desc", desc); err != nil {
+				return err
+			}
+		}
+
+		props := idProperty(folder.ID)
+		props = append(props, roleProperty(folder.Role)...)
+		if !folder.LastModified.IsZero() {
+			props = append(props, orgProperty{"LAST_MODIFIED", strconv.FormatInt(folder.LastModified.Unix(), 10)})
+		}
+		props = append(props, xbelExtraProperties(folder.Properties)...)
+		if err := xw.writeMetadata(indent+"  ", props); err != nil {
+			return err
+		}
+
+		for _, child := range folder.Children {
+			if err := xw.writeNode(child, depth+1); err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(xw.w, "%s</folder>\n", indent)
+		return err
+	}
+
+	bookmark := node.(*models.Bookmark)
+
+	_, err := fmt.Fprintf(xw.w, "%s<bookmark href=\"%s\" id=\"%d\"%s%s>\n", indent, escapeHTML(bookmark.URL), id,
+		xbelDateAttr("added", bookmark.AddDate), xbelDateAttr("modified", bookmark.LastModified))
+	if err != nil {
+		return err
+	}
+	if err := xw.writeElement(indent+"  ", "title", bookmark.Title); err != nil {
+		return err
+	}
+	if bookmark.Description != "" {
+		if err := xw.writeElement(indent+"  ", "desc", bookmark.Description); err != nil {
+			return err
+		}
+	}
+
+	// Fields without an XBEL element go into orgmarks metadata
+	props := idProperty(bookmark.ID)
+	if len(bookmark.Tags) > 0 {
+		props = append(props, orgProperty{"TAGS", strings.Join(bookmark.Tags, ",")})
+	}
+	if bookmark.ShortcutURL != "" {
+		props = append(props, orgProperty{"SHORTCUTURL", bookmark.ShortcutURL})
+	}
+	if bookmark.IconURI != "" {
+		props = append(props, orgProperty{"ICON_URI", bookmark.IconURI})
+	}
+	if strings.HasPrefix(bookmark.Icon, "data:") {
+		props = append(props, orgProperty{"ICON", bookmark.Icon})
+	}
+	props = append(props, xbelExtraProperties(bookmark.Properties)...)
+	if err := xw.writeMetadata(indent+"  ", props); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(xw.w, "%s</bookmark>\n", indent)
+	return err
+}
+
+// writeElement writes a simple text element such as <title> or <desc>
+func (xw *xbelWriter) writeElement(indent, name, text string) error {
+	_, err := fmt.Fprintf(xw.w, "%s<%s>%s</%s>\n", indent, name, escapeHTML(text), name)
+	return err
+}
+
+// writeMetadata writes an <info> block with orgmarks metadata, if there is any
+func (xw *xbelWriter) writeMetadata(indent string, props []orgProperty) error {
+	if len(props) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(xw.w, "%s<info>\n%s  <metadata owner=\"%s\">\n", indent, indent, xbelMetadataOwner); err != nil {
+		return err
+	}
+	for _, prop := range props {
+		_, err := fmt.Fprintf(xw.w, "%s    <property name=\"%s\">%s</property>\n", indent, escapeHTML(prop.key), escapeHTML(prop.value))
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(xw.w, "%s  </metadata>\n%s</info>\n", indent, indent)
+	return err
+}
+
+// xbelExtraProperties returns the properties to store as metadata, without
+// those XBEL has its own attributes or elements for
+func xbelExtraProperties(properties map[string]string) []orgProperty {
+	var props []orgProperty
+	for _, prop := range extraProperties(properties) {
+		if prop.key != "XBEL_ID" && prop.key != "DESCRIPTION" {
+			props = append(props, prop)
+		}
+	}
+	return props
+}
+
+// xbelDateAttr formats a date attribute as ISO 8601, or nothing if not set
+func xbelDateAttr(name string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" %s=\"%s\"", name, t.UTC().Format(time.RFC3339))
+}
+
+// nodeProperties returns the extra properties of a folder or bookmark
+func nodeProperties(node models.Node) map[string]string {
+	if node.IsFolder() {
+		return node.(*models.Folder).Properties
+	}
+	return node.(*models.Bookmark).Properties
+}
@@ -8,7 +8,7 @@ package models
 
 import "time"
 
-// Node is the interface that both Bookmark and Folder implement
+// Node is the interface that Bookmark, Folder and Separator implement
 // This allows building a tree structure with mixed node types
 type Node interface {
 	IsFolder() bool
@@ -17,21 +17,35 @@ type Node interface {
 
 // Bookmark represents a single bookmark entry
 type Bookmark struct {
-	URL          string    // The bookmark URL
-	Title        string    // The bookmark title/name
-	Tags         []string  // Tags associated with the bookmark
-	ShortcutURL  string    // Firefox SHORTCUTURL attribute (optional)
-	AddDate      time.Time // When the bookmark was added
-	LastModified time.Time // When the bookmark was last modified
-	Description  string    // Optional description text (below the link in org-mode)
+	ID           string            // Stable identity: the browser's GUID, or a generated UUID
+	URL          string            // The bookmark URL
+	Title        string            // The bookmark title/name
+	Tags         []string          // Tags associated with the bookmark
+	ShortcutURL  string            // Firefox SHORTCUTURL attribute (optional)
+	AddDate      time.Time         // When the bookmark was added
+	LastModified time.Time         // When the bookmark was last modified
+	Description  string            // Optional description text (below the link in org-mode)
+	Icon         string            // Favicon as a data: URI, or a file: reference to a sidecar file
+	IconURI      string            // Where the favicon was originally loaded from
+	Properties   map[string]string // Additional metadata (e.g. from org-mode property drawers)
 }
 
 // Folder represents a bookmark folder/directory
 type Folder struct {
-	Title        string      // The folder name
-	Children     []Node      // Child nodes (can be bookmarks or folders)
-	AddDate      time.Time   // When the folder was created
-	LastModified time.Time   // When the folder was last modified
+	ID           string            // Stable identity: the browser's GUID, or a generated UUID
+	Title        string            // The folder name
+	Role         RootRole          // The browser root folder this stands for, if any
+	Children     []Node            // Child nodes (can be bookmarks or folders)
+	AddDate      time.Time         // When the folder was created
+	LastModified time.Time         // When the folder was last modified
+	Properties   map[string]string // Additional metadata (e.g. from org-mode property drawers)
+}
+
+// Separator represents a separator line between bookmarks, as Firefox and
+// XBEL have them. It has no title and is skipped by everything that works
+// on bookmarks.
+type Separator struct {
+	ID string // Stable identity: the browser's GUID, or a generated UUID
 }
 
 // IsFolder returns false for Bookmark nodes
@@ -54,6 +68,22 @@ func (f *Folder) GetTitle() string {
 	return f.Title
 }
 
+// IsFolder returns false for Separator nodes
+func (s *Separator) IsFolder() bool {
+	return false
+}
+
+// GetTitle returns an empty title, separators have none
+func (s *Separator) GetTitle() string {
+	return ""
+}
+
+// IsSeparator reports whether a node is a separator
+func IsSeparator(node Node) bool {
+	_, ok := node.(*Separator)
+	return ok
+}
+
 // AddChild adds a node to the folder's children
 func (f *Folder) AddChild(node Node) {
 	f.Children = append(f.Children, node)
@@ -71,7 +101,7 @@ func Walk(node Node, depth int, visitor func(Node, int)) {
 	}
 }
 
-// CountNodes returns the total number of nodes (folders + bookmarks) in the tree
+// CountNodes returns the total number of nodes (folders, bookmarks and separators) in the tree
 func CountNodes(node Node) int {
 	count := 1
 	if node.IsFolder() {
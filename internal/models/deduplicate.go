@@ -1,31 +1,139 @@
 package models
 
+import "fmt"
+
+// DeduplicateOptions controls how duplicate bookmarks are found and resolved
+type DeduplicateOptions struct {
+	URLs     URLNormalization  // How strictly URLs are compared (default: byte-for-byte)
+	Strategy DuplicateStrategy // Which copy is kept (default: the first)
+
+	// Resolve, if set, is used instead of Strategy to choose the copy to keep.
+	// It returns an index into copies, which are in tree order, or KeepAll
+	// to leave all copies in place.
+	Resolve func(copies []Duplicate) (int, error)
+}
+
+// KeepAll is returned by a DeduplicateOptions.Resolve function to keep all
+// copies of a duplicate
+const KeepAll = -1
+
+// DuplicateGroup is a URL that appeared more than once, with all its copies
+type DuplicateGroup struct {
+	URL    string      // The URL, in the normalized form used for comparing
+	Copies []Duplicate // All copies, in tree order
+	Kept   int         // Index of the copy that was kept, or KeepAll
+}
+
+// DeduplicateReport describes what deduplication found and removed
+type DeduplicateReport struct {
+	Groups []DuplicateGroup // The duplicated URLs, in the order they first appear
+}
+
+// Removed returns the number of bookmarks that were removed
+func (r *DeduplicateReport) Removed() int {
+	removed := 0
+	for _, group := range r.Groups {
+		if group.Kept != KeepAll {
+			removed += len(group.Copies) - 1
+		}
+	}
+	return removed
+}
+
 // Deduplicate removes duplicate bookmarks with the same URL, keeping only the first occurrence.
 // It walks the tree in depth-first order and tracks seen URLs.
-func Deduplicate(root *Folder) {
-	seen := make(map[string]bool)
-	deduplicateFolder(root, seen)
+// The report lists the duplicates that were found.
+func Deduplicate(root *Folder) *DeduplicateReport {
+	report, _ := DeduplicateWithOptions(root, DeduplicateOptions{})
+	return report
+}
+
+// DeduplicateWithOptions removes duplicate bookmarks like Deduplicate, comparing
+// URLs after normalizing them at the level given in the options, and keeping
+// the copy chosen by the strategy. If the Resolve function fails, no bookmarks
+// are removed and the error is returned.
+func DeduplicateWithOptions(root *Folder, opts DeduplicateOptions) (*DeduplicateReport, error) {
+	resolve := opts.Resolve
+	if resolve == nil {
+		resolve = func(copies []Duplicate) (int, error) {
+			return opts.Strategy.Resolve(copies), nil
+		}
+	}
+
+	report := &DeduplicateReport{}
+	remove := make(map[*Bookmark]bool)
+	for _, group := range findDuplicates(root, opts.URLs) {
+		copies := group.Copies
+		keep, err := resolve(copies)
+		if err != nil {
+			return nil, err
+		}
+		if keep != KeepAll && (keep < 0 || keep >= len(copies)) {
+			return nil, fmt.Errorf("invalid copy %d of %s", keep, group.URL)
+		}
+		group.Kept = keep
+		report.Groups = append(report.Groups, group)
+		if keep == KeepAll {
+			continue
+		}
+		for i, c := range copies {
+			// The same bookmark may be in the tree twice; don't remove the kept one
+			if i != keep && c.Bookmark != copies[keep].Bookmark {
+				remove[c.Bookmark] = true
+			}
+		}
+	}
+	removeBookmarks(root, remove)
+	return report, nil
+}
+
+// findDuplicates returns the copies of every URL that appears more than once,
+// in the order the URLs first appear in the tree
+func findDuplicates(root *Folder, level URLNormalization) []DuplicateGroup {
+	var keys []string
+	groups := make(map[string][]Duplicate)
+
+	var walk func(folder *Folder, path []string)
+	walk = func(folder *Folder, path []string) {
+		for _, child := range folder.Children {
+			if child.IsFolder() {
+				subfolder := child.(*Folder)
+				walk(subfolder, append(path[:len(path):len(path)], subfolder.Title))
+				continue
+			}
+			bookmark, ok := child.(*Bookmark)
+			if !ok {
+				continue
+			}
+			key := NormalizeURL(bookmark.URL, level)
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], Duplicate{Bookmark: bookmark, Folder: folder, Path: path})
+		}
+	}
+	walk(root, nil)
+
+	var duplicates []DuplicateGroup
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			duplicates = append(duplicates, DuplicateGroup{URL: key, Copies: groups[key]})
+		}
+	}
+	return duplicates
 }
 
-// deduplicateFolder recursively removes duplicate bookmarks from a folder
-func deduplicateFolder(folder *Folder, seen map[string]bool) {
-	// Filter children, keeping only non-duplicate bookmarks
+// removeBookmarks recursively removes the given bookmarks from a folder
+func removeBookmarks(folder *Folder, remove map[*Bookmark]bool) {
 	filtered := make([]Node, 0, len(folder.Children))
 
 	for _, child := range folder.Children {
 		if child.IsFolder() {
-			// Recursively deduplicate subfolders
-			subfolder := child.(*Folder)
-			deduplicateFolder(subfolder, seen)
+			// Recursively remove from subfolders
+			removeBookmarks(child.(*Folder), remove)
+			filtered = append(filtered, child)
+		} else if bookmark, ok := child.(*Bookmark); !ok || !remove[bookmark] {
 			filtered = append(filtered, child)
-		} else {
-			// Check if bookmark URL has been seen
-			bookmark := child.(*Bookmark)
-			if !seen[bookmark.URL] {
-				seen[bookmark.URL] = true
-				filtered = append(filtered, child)
-			}
-			// If URL was already seen, skip this bookmark (don't append)
 		}
 	}
 
@@ -0,0 +1,310 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// ChangeType is the kind of a difference between two bookmark trees
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"    // Only in the new tree
+	ChangeRemoved  ChangeType = "removed"  // Only in the old tree
+	ChangeMoved    ChangeType = "moved"    // In another folder
+	ChangeRenamed  ChangeType = "renamed"  // Title changed
+	ChangeModified ChangeType = "modified" // URL, tags, description, shortcut or a property changed
+)
+
+// Change is a difference between two bookmark trees. A bookmark or folder
+// that was both moved and renamed, or had several fields changed, has one
+// change for each.
+type Change struct {
+	Type     ChangeType
+	Old      Node     // The bookmark or folder in the old tree (nil if added)
+	New      Node     // The bookmark or folder in the new tree (nil if removed)
+	OldPath  []string // Titles of the folders containing Old, not including the root
+	NewPath  []string // Titles of the folders containing New; if removed, where Old's folder is now
+	Field    string   // For modified: "url", "tags", "description", "shortcut" or a property name
+	OldValue string   // For renamed and modified: the old title or value
+	NewValue string   // For renamed and modified: the new title or value
+}
+
+// Node returns the changed bookmark or folder, from the new tree if it
+// is still there
+func (c Change) Node() Node {
+	if c.New != nil {
+		return c.New
+	}
+	return c.Old
+}
+
+// folderEntry is a folder in one of the trees being compared
+type folderEntry struct {
+	folder *Folder
+	path   []string        // Titles of the folders from the root, including this one
+	urls   map[string]bool // Keys of the URLs of the bookmarks directly in it
+}
+
+// diffKey returns the key bookmarks are matched by in a diff: the URL in
+// standard canonical form, ignoring the difference between http and https
+func diffKey(url string) string {
+	key := NormalizeURL(url, NormalizeStandard)
+	if rest, ok := strings.CutPrefix(key, "http://"); ok {
+		key = "https://" + rest
+	}
+	return key
+}
+
+// DiffTrees compares two bookmark trees and returns the changes from old to
+// new: bookmarks and folders that were added, removed, moved, renamed or had
+// their metadata changed.
+//
+// Bookmarks and folders with the same ID are always matched. Otherwise
+// bookmarks are matched by URL, ignoring trivial differences like a trailing
+// slash, the scheme or a fragment, and a bookmark whose URL changed is matched
+// by its title and folder. Folders are matched by path; a folder that was
+// renamed or moved is recognized by the bookmarks in it, and the bookmarks in
+// it don't count as moved.
+//
+// Folder changes come first, in the order of the old tree followed by added
+// folders, then bookmark changes in the same order.
+func DiffTrees(old, new *Folder) []Change {
+	changes, translate := diffFolders(old, new)
+	return append(changes, diffBookmarks(old, new, translate)...)
+}
+
+// diffFolders matches the folders of two trees. It returns the changes to
+// folders, and a function that maps an old folder path to the path of the
+// same folder in the new tree.
+func diffFolders(old, new *Folder) ([]Change, func(path []string) []string) {
+	oldFolders := listFolders(old)
+	newFolders := listFolders(new)
+	newByKey := make(map[string]*folderEntry, len(newFolders))
+	newByID := make(map[string]*folderEntry)
+	for _, n := range newFolders {
+		newByKey[folderKey(n.path)] = n
+		if n.folder.ID != "" {
+			newByID[n.folder.ID] = n
+		}
+	}
+
+	// New paths of matched old folders, by old folder key. A path below a
+	// matched folder follows it.
+	moved := make(map[string][]string)
+	translate := func(path []string) []string {
+		for i := len(path); i > 0; i-- {
+			if newPath, ok := moved[folderKey(path[:i])]; ok {
+				return append(newPath[:len(newPath):len(newPath)], path[i:]...)
+			}
+		}
+		return path
+	}
+
+	var changes []Change
+	claimed := make(map[*folderEntry]bool)
+	for _, o := range oldFolders {
+		oldParent := o.path[:len(o.path)-1]
+		newParent := translate(oldParent)
+
+		// Same ID, or the same title in the same (possibly renamed) parent
+		n := newByID[o.folder.ID]
+		if n == nil || o.folder.ID == "" {
+			n = newByKey[folderKey(append(newParent[:len(newParent):len(newParent)], o.folder.Title))]
+		}
+		if n != nil && claimed[n] {
+			n = nil
+		}
+
+		// Renamed or moved: most of the bookmarks are in another folder
+		if n == nil {
+			best := 0
+			for _, candidate := range newFolders {
+				if claimed[candidate] {
+					continue
+				}
+				overlap := 0
+				for url := range o.urls {
+					if candidate.urls[url] {
+						overlap++
+					}
+				}
+				if overlap > best && overlap*2 >= len(o.urls) {
+					n, best = candidate, overlap
+				}
+			}
+		}
+
+		if n == nil {
+			changes = append(changes, Change{Type: ChangeRemoved, Old: o.folder, OldPath: oldParent, NewPath: newParent})
+			continue
+		}
+		claimed[n] = true
+		moved[folderKey(o.path)] = n.path
+
+		change := Change{Old: o.folder, New: n.folder, OldPath: oldParent, NewPath: n.path[:len(n.path)-1]}
+		if !samePath(newParent, change.NewPath) {
+			c := change
+			c.Type = ChangeMoved
+			changes = append(changes, c)
+		}
+		if o.folder.Title != n.folder.Title {
+			c := change
+			c.Type, c.OldValue, c.NewValue = ChangeRenamed, o.folder.Title, n.folder.Title
+			changes = append(changes, c)
+		}
+		for _, field := range diffProperties(o.folder.Properties, n.folder.Properties) {
+			c := change
+			c.Type, c.Field, c.OldValue, c.NewValue = ChangeModified, field, o.folder.Properties[field], n.folder.Properties[field]
+			changes = append(changes, c)
+		}
+	}
+
+	for _, n := range newFolders {
+		if !claimed[n] {
+			changes = append(changes, Change{Type: ChangeAdded, New: n.folder, NewPath: n.path[:len(n.path)-1]})
+		}
+	}
+	return changes, translate
+}
+
+// diffBookmarks matches the bookmarks of two trees and returns the changes
+// to bookmarks. Folder paths of the old tree are translated to the new tree.
+func diffBookmarks(old, new *Folder, translate func(path []string) []string) []Change {
+	oldEntries := indexBookmarks(old, diffKey)
+	newEntries := indexBookmarks(new, diffKey)
+	newByKey := entryMap(newEntries)
+
+	newByID := make(map[string]*bookmarkEntry)
+	for _, n := range newEntries {
+		if n.bookmark.ID != "" {
+			newByID[n.bookmark.ID] = n
+		}
+	}
+
+	// Same ID, then the same URL
+	matches := make(map[*bookmarkEntry]*bookmarkEntry)
+	claimed := make(map[*bookmarkEntry]bool)
+	for _, o := range oldEntries {
+		if n := newByID[o.bookmark.ID]; o.bookmark.ID != "" && n != nil {
+			matches[o] = n
+			claimed[n] = true
+		}
+	}
+	for _, o := range oldEntries {
+		if n := newByKey[o.key]; matches[o] == nil && n != nil && !claimed[n] {
+			matches[o] = n
+			claimed[n] = true
+		}
+	}
+
+	// A bookmark whose URL changed has the same title in the same folder
+	oldByKey := entryMap(oldEntries)
+	for _, o := range oldEntries {
+		if matches[o] != nil {
+			continue
+		}
+		for _, n := range newEntries {
+			if !claimed[n] && oldByKey[n.key] == nil && n.bookmark.Title == o.bookmark.Title && samePath(n.path, translate(o.path)) {
+				matches[o] = n
+				claimed[n] = true
+				break
+			}
+		}
+	}
+
+	var changes []Change
+	for _, o := range oldEntries {
+		n := matches[o]
+		if n == nil {
+			changes = append(changes, Change{Type: ChangeRemoved, Old: o.bookmark, OldPath: o.path, NewPath: translate(o.path)})
+			continue
+		}
+
+		change := Change{Old: o.bookmark, New: n.bookmark, OldPath: o.path, NewPath: n.path}
+		if !samePath(translate(o.path), n.path) {
+			c := change
+			c.Type = ChangeMoved
+			changes = append(changes, c)
+		}
+		if o.bookmark.Title != n.bookmark.Title {
+			c := change
+			c.Type, c.OldValue, c.NewValue = ChangeRenamed, o.bookmark.Title, n.bookmark.Title
+			changes = append(changes, c)
+		}
+
+		modified := func(field, oldValue, newValue string) {
+			c := change
+			c.Type, c.Field, c.OldValue, c.NewValue = ChangeModified, field, oldValue, newValue
+			changes = append(changes, c)
+		}
+		if diffKey(o.bookmark.URL) != diffKey(n.bookmark.URL) {
+			modified("url", o.bookmark.URL, n.bookmark.URL)
+		}
+		if !sameTags(o.bookmark.Tags, n.bookmark.Tags) {
+			modified("tags", strings.Join(o.bookmark.Tags, " "), strings.Join(n.bookmark.Tags, " "))
+		}
+		if o.bookmark.Description != n.bookmark.Description {
+			modified("description", o.bookmark.Description, n.bookmark.Description)
+		}
+		if o.bookmark.ShortcutURL != n.bookmark.ShortcutURL {
+			modified("shortcut", o.bookmark.ShortcutURL, n.bookmark.ShortcutURL)
+		}
+		for _, field := range diffProperties(o.bookmark.Properties, n.bookmark.Properties) {
+			modified(field, o.bookmark.Properties[field], n.bookmark.Properties[field])
+		}
+	}
+
+	for _, n := range newEntries {
+		if !claimed[n] {
+			changes = append(changes, Change{Type: ChangeAdded, New: n.bookmark, NewPath: n.path})
+		}
+	}
+	return changes
+}
+
+// diffProperties returns the names of the properties that differ, sorted
+func diffProperties(old, new map[string]string) []string {
+	var fields []string
+	for key, value := range old {
+		if newValue, ok := new[key]; !ok || newValue != value {
+			fields = append(fields, key)
+		}
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// listFolders lists the folders below root in tree order, with the keys of
+// the URLs of their bookmarks
+func listFolders(root *Folder) []*folderEntry {
+	var folders []*folderEntry
+	var walk func(folder *Folder, path []string)
+	walk = func(folder *Folder, path []string) {
+		for _, child := range folder.Children {
+			if !child.IsFolder() {
+				continue
+			}
+			subfolder := child.(*Folder)
+			entry := &folderEntry{
+				folder: subfolder,
+				path:   append(path[:len(path):len(path)], subfolder.Title),
+				urls:   make(map[string]bool),
+			}
+			for _, grandchild := range subfolder.Children {
+				if bookmark, ok := grandchild.(*Bookmark); ok {
+					entry.urls[diffKey(bookmark.URL)] = true
+				}
+			}
+			folders = append(folders, entry)
+			walk(subfolder, entry.path)
+		}
+	}
+	walk(root, nil)
+	return folders
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestDiffTrees(t *testing.T) {
+	old := &Folder{
+		Title: "Bookmarks",
+		Children: []Node{
+			&Folder{
+				Title: "Dev",
+				Children: []Node{
+					&Bookmark{Title: "Go", URL: "https://go.dev/"},
+					&Bookmark{Title: "Rust", URL: "https://rust-lang.org/", Tags: []string{"lang"}},
+					&Bookmark{Title: "Zig", URL: "https://ziglang.org/"},
+				},
+			},
+			&Folder{
+				Title: "Reading",
+				Children: []Node{
+					&Bookmark{Title: "Book", URL: "https://book.example.com/"},
+					&Bookmark{Title: "Blog", URL: "https://blog.example.com/"},
+					&Bookmark{Title: "Stale", URL: "https://stale.example.com/"},
+				},
+			},
+			&Bookmark{Title: "Old", URL: "https://old.example.com/"},
+			&Bookmark{Title: "Docs", URL: "https://docs.example.com/v1"},
+		},
+	}
+
+	new := &Folder{
+		Title: "Bookmarks",
+		Children: []Node{
+			&Folder{
+				Title: "Dev",
+				Children: []Node{
+					// Only trivially different
+					&Bookmark{Title: "Go", URL: "http://go.dev"},
+					&Bookmark{Title: "Rust language", URL: "https://rust-lang.org/", Tags: []string{"lang", "systems"}},
+				},
+			},
+			// Renamed folder: its bookmarks don't count as moved
+			&Folder{
+				Title: "To Read",
+				Children: []Node{
+					&Bookmark{Title: "Book", URL: "https://book.example.com/"},
+					&Bookmark{Title: "Blog", URL: "https://blog.example.com/"},
+					&Bookmark{Title: "Zig", URL: "https://ziglang.org/"},
+				},
+			},
+			&Folder{Title: "Empty"},
+			&Bookmark{Title: "Docs", URL: "https://docs.example.com/v2"},
+			&Bookmark{Title: "New", URL: "https://new.example.com/"},
+		},
+	}
+
+	changes := DiffTrees(old, new)
+
+	expected := []struct {
+		changeType ChangeType
+		title      string
+		field      string
+	}{
+		{ChangeRenamed, "To Read", ""},
+		{ChangeAdded, "Empty", ""},
+		{ChangeRenamed, "Rust language", ""},
+		{ChangeModified, "Rust language", "tags"},
+		{ChangeMoved, "Zig", ""},
+		{ChangeRemoved, "Stale", ""},
+		{ChangeRemoved, "Old", ""},
+		{ChangeModified, "Docs", "url"},
+		{ChangeAdded, "New", ""},
+	}
+
+	if len(changes) != len(expected) {
+		for _, c := range changes {
+			t.Logf("%s %s %s", c.Type, c.Node().GetTitle(), c.Field)
+		}
+		t.Fatalf("Expected %d changes, got %d", len(expected), len(changes))
+	}
+	for i, want := range expected {
+		c := changes[i]
+		if c.Type != want.changeType || c.Node().GetTitle() != want.title || c.Field != want.field {
+			t.Errorf("Change %d: expected %s %s %s, got %s %s %s", i,
+				want.changeType, want.title, want.field, c.Type, c.Node().GetTitle(), c.Field)
+		}
+	}
+
+	if changes[0].OldValue != "Reading" || changes[0].NewValue != "To Read" {
+		t.Errorf("Expected folder rename from 'Reading' to 'To Read', got '%s' to '%s'", changes[0].OldValue, changes[0].NewValue)
+	}
+	if zig := changes[4]; len(zig.OldPath) != 1 || zig.OldPath[0] != "Dev" || zig.NewPath[0] != "To Read" {
+		t.Errorf("Expected Zig to move from Dev to To Read, got %v to %v", zig.OldPath, zig.NewPath)
+	}
+	if stale := changes[5]; len(stale.NewPath) != 1 || stale.NewPath[0] != "To Read" {
+		t.Errorf("Expected a removed bookmark to be placed in the renamed folder, got %v", stale.NewPath)
+	}
+	if tags := changes[3]; tags.OldValue != "lang" || tags.NewValue != "lang systems" {
+		t.Errorf("Unexpected tag change: %q to %q", tags.OldValue, tags.NewValue)
+	}
+
+	if changes := DiffTrees(old, old); len(changes) != 0 {
+		t.Errorf("Expected no changes between a tree and itself, got %d", len(changes))
+	}
+}
+
+func TestDiffTreesMatchesIDs(t *testing.T) {
+	old := &Folder{
+		Title: "Bookmarks",
+		Children: []Node{
+			&Folder{ID: "folder", Title: "Dev", Children: []Node{
+				&Bookmark{ID: "bookmark", Title: "Docs", URL: "https://docs.example.com/v1"},
+			}},
+		},
+	}
+	new := &Folder{
+		Title: "Bookmarks",
+		Children: []Node{
+			&Folder{ID: "folder", Title: "Programming"},
+			&Bookmark{ID: "bookmark", Title: "Documentation", URL: "https://example.com/docs"},
+		},
+	}
+
+	changes := DiffTrees(old, new)
+	var types []ChangeType
+	for _, c := range changes {
+		types = append(types, c.Type)
+	}
+	// Folder renamed; bookmark moved, renamed and its URL changed
+	expected := []ChangeType{ChangeRenamed, ChangeMoved, ChangeRenamed, ChangeModified}
+	if len(types) != len(expected) {
+		t.Fatalf("Expected changes %v, got %v", expected, types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("Expected changes %v, got %v", expected, types)
+			break
+		}
+	}
+}
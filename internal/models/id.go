@@ -0,0 +1,37 @@
+package models
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// NewID generates a random (version 4) UUID for a bookmark or folder
+func NewID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// AssignIDs gives every bookmark, folder and separator below root that has
+// no ID a new one. IDs that came from the source, such as browser GUIDs, are kept.
+func AssignIDs(root *Folder) {
+	for _, child := range root.Children {
+		switch node := child.(type) {
+		case *Folder:
+			if node.ID == "" {
+				node.ID = NewID()
+			}
+			AssignIDs(node)
+		case *Bookmark:
+			if node.ID == "" {
+				node.ID = NewID()
+			}
+		case *Separator:
+			if node.ID == "" {
+				node.ID = NewID()
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestAssignIDs(t *testing.T) {
+	root := &Folder{
+		Title: "Root",
+		Children: []Node{
+			&Bookmark{ID: "V2n0aJ7kP1sD", Title: "Kept", URL: "https://example.com/1"},
+			&Folder{Title: "Folder", Children: []Node{
+				&Bookmark{Title: "New", URL: "https://example.com/2"},
+			}},
+		},
+	}
+
+	AssignIDs(root)
+
+	if root.ID != "" {
+		t.Errorf("Expected the root to get no ID, got '%s'", root.ID)
+	}
+	if id := root.Children[0].(*Bookmark).ID; id != "V2n0aJ7kP1sD" {
+		t.Errorf("Expected an existing ID to be kept, got '%s'", id)
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	folder := root.Children[1].(*Folder)
+	bookmark := folder.Children[0].(*Bookmark)
+	if !uuid.MatchString(folder.ID) || !uuid.MatchString(bookmark.ID) {
+		t.Errorf("Expected generated UUIDs, got '%s' and '%s'", folder.ID, bookmark.ID)
+	}
+	if folder.ID == bookmark.ID {
+		t.Errorf("Expected generated IDs to be unique")
+	}
+}
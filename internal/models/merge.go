@@ -1,6 +1,9 @@
 package models
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
 // MergeFolders merges two folder trees into a single tree.
 // Folders with matching titles (case-insensitive) have their children combined.
@@ -8,19 +11,105 @@ import "strings"
 // This ordering ensures that when deduplication is applied afterward,
 // bookmarks from folder1 take precedence over duplicates in folder2.
 func MergeFolders(folder1, folder2 *Folder) *Folder {
+	return mergeFolders(folder1, folder2, titleKey)
+}
+
+// MergeOptions controls how MergeFoldersWithOptions combines two trees
+type MergeOptions struct {
+	// Target is the path of the folder in the first tree that the second tree
+	// is grafted under. Folders on the path that don't exist are created.
+	Target []string
+
+	// Aliases are groups of folder titles that count as the same folder, like
+	// the names browsers give their toolbar. Titles are compared ignoring case.
+	// The merged folder keeps the title from the first tree.
+	Aliases [][]string
+
+	// Inbox is the title of a folder that the bookmarks of the second tree
+	// are put in if it has no folders at all, like a Pocket export. Several
+	// such inputs end up in the same folder.
+	Inbox string
+}
+
+// BrowserFolderAliases are the names browsers give their special folders,
+// for MergeOptions.Aliases
+var BrowserFolderAliases = [][]string{
+	{"Bookmarks Toolbar", "Bookmarks bar", "Favorites bar", "Favorites"},
+	{"Other Bookmarks", "Unsorted Bookmarks", "Other favorites"},
+	{"Mobile Bookmarks", "Mobile favorites"},
+}
+
+// MergeFoldersWithOptions merges two folder trees like MergeFolders, with
+// the second tree grafted under a target folder, folders matched by aliases,
+// and bookmark-only trees collected in an inbox folder
+func MergeFoldersWithOptions(folder1, folder2 *Folder, opts MergeOptions) *Folder {
+	key := titleKey
+	if len(opts.Aliases) > 0 {
+		canonical := make(map[string]string)
+		for _, group := range opts.Aliases {
+			for _, title := range group {
+				canonical[titleKey(title)] = titleKey(group[0])
+			}
+		}
+		key = func(title string) string {
+			if name, ok := canonical[titleKey(title)]; ok {
+				return name
+			}
+			return titleKey(title)
+		}
+	}
+
+	// Wrap the second tree in the folders it should end up in, so they are
+	// merged with the existing ones
+	graft := folder2
+	if opts.Inbox != "" && len(graft.Children) > 0 && !slices.ContainsFunc(graft.Children, Node.IsFolder) {
+		graft = wrapChildren(graft, opts.Inbox)
+	}
+	for i := len(opts.Target) - 1; i >= 0; i-- {
+		graft = wrapChildren(graft, opts.Target[i])
+	}
+
+	return mergeFolders(folder1, graft, key)
+}
+
+// wrapChildren returns a copy of a folder whose children have been moved
+// into a single subfolder
+func wrapChildren(folder *Folder, title string) *Folder {
+	wrapped := *folder
+	wrapped.Children = []Node{&Folder{Title: title, Children: folder.Children}}
+	return &wrapped
+}
+
+// titleKey returns the key folders are matched by: the title, ignoring case
+// and surrounding space
+func titleKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// mergeFolders merges two folder trees, matching folders by the key of their
+// title. Browser root folders with the same role match whatever their titles.
+func mergeFolders(folder1, folder2 *Folder, key func(title string) string) *Folder {
 	// Create the merged root folder
 	merged := &Folder{
+		ID:           folder1.ID,
 		Title:        folder1.Title,
+		Role:         folder1.Role,
 		AddDate:      folder1.AddDate,
 		LastModified: folder1.LastModified,
 	}
+	if merged.ID == "" {
+		merged.ID = folder2.ID
+	}
+	if merged.Role == RootNone {
+		merged.Role = folder2.Role
+	}
 
 	// Build a map of folder1's children by normalized title (for folders only)
 	folderMap := make(map[string]*Folder)
 	for _, child := range folder1.Children {
 		if child.IsFolder() {
 			subfolder := child.(*Folder)
-			normalizedTitle := strings.ToLower(strings.TrimSpace(subfolder.Title))
+			normalizedTitle := key(subfolder.Title)
 			folderMap[normalizedTitle] = subfolder
 		}
 	}
@@ -36,12 +125,18 @@ func MergeFolders(folder1, folder2 *Folder) *Folder {
 		}
 	}
 
-	// Build a list of folders from folder2 that need to be merged or added
+	// Build a list of folders from folder2 that need to be merged or added.
+	// Separators are only taken from folder2 if folder1 has none, so merging
+	// two copies of a tree doesn't double them.
 	folder2Subfolders := make([]*Folder, 0)
+	keepSeparators := !slices.ContainsFunc(folder1.Children, IsSeparator)
 	for _, child := range folder2.Children {
-		if child.IsFolder() {
+		switch {
+		case child.IsFolder():
 			folder2Subfolders = append(folder2Subfolders, child.(*Folder))
-		} else {
+		case IsSeparator(child) && !keepSeparators:
+			// folder1 has its own
+		default:
 			// Add bookmarks from folder2
 			merged.AddChild(child)
 		}
@@ -50,18 +145,20 @@ func MergeFolders(folder1, folder2 *Folder) *Folder {
 	// Now handle folder merging
 	// First, recursively merge folders that exist in both trees
 	processedFolders := make(map[string]bool)
+	matchedFolders := make(map[*Folder]bool)
 	for _, folder1Child := range folder1.Children {
 		if !folder1Child.IsFolder() {
 			continue
 		}
 		subfolder1 := folder1Child.(*Folder)
-		normalizedTitle := strings.ToLower(strings.TrimSpace(subfolder1.Title))
+		normalizedTitle := key(subfolder1.Title)
 
 		// Look for a matching folder in folder2
 		var matchingFolder2 *Folder
 		for _, folder2Child := range folder2Subfolders {
-			folder2Normalized := strings.ToLower(strings.TrimSpace(folder2Child.Title))
-			if folder2Normalized == normalizedTitle {
+			folder2Normalized := key(folder2Child.Title)
+			sameRole := subfolder1.Role != RootNone && subfolder1.Role == folder2Child.Role
+			if (folder2Normalized == normalizedTitle || sameRole) && !matchedFolders[folder2Child] {
 				matchingFolder2 = folder2Child
 				break
 			}
@@ -69,9 +166,10 @@ func MergeFolders(folder1, folder2 *Folder) *Folder {
 
 		if matchingFolder2 != nil {
 			// Recursively merge the two folders
-			mergedSubfolder := MergeFolders(subfolder1, matchingFolder2)
+			mergedSubfolder := mergeFolders(subfolder1, matchingFolder2, key)
 			merged.AddChild(mergedSubfolder)
 			processedFolders[normalizedTitle] = true
+			matchedFolders[matchingFolder2] = true
 		} else {
 			// Only in folder1, add as-is
 			merged.AddChild(subfolder1)
@@ -81,8 +179,8 @@ func MergeFolders(folder1, folder2 *Folder) *Folder {
 
 	// Add folders from folder2 that weren't matched
 	for _, folder2Child := range folder2Subfolders {
-		normalizedTitle := strings.ToLower(strings.TrimSpace(folder2Child.Title))
-		if !processedFolders[normalizedTitle] {
+		normalizedTitle := key(folder2Child.Title)
+		if !processedFolders[normalizedTitle] && !matchedFolders[folder2Child] {
 			merged.AddChild(folder2Child)
 		}
 	}
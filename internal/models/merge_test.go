@@ -351,3 +351,142 @@ func TestMergeFoldersPreservesOrder(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeFoldersKeepsIDs(t *testing.T) {
+	folder1 := &Folder{
+		Title: "Root",
+		Children: []Node{
+			&Folder{Title: "Dev", Children: []Node{
+				&Bookmark{ID: "bookmark-1", Title: "Go", URL: "https://go.dev/"},
+			}},
+			&Folder{ID: "folder-1", Title: "News"},
+		},
+	}
+	folder2 := &Folder{
+		Title: "Root",
+		Children: []Node{
+			&Folder{ID: "folder-2", Title: "dev"},
+			&Folder{ID: "folder-3", Title: "News"},
+		},
+	}
+
+	merged := MergeFolders(folder1, folder2)
+
+	dev := merged.Children[0].(*Folder)
+	if dev.ID != "folder-2" {
+		t.Errorf("Expected the merged folder to take the only ID, got '%s'", dev.ID)
+	}
+	if dev.Children[0].(*Bookmark).ID != "bookmark-1" {
+		t.Errorf("Expected bookmark IDs to be kept")
+	}
+	if news := merged.Children[1].(*Folder); news.ID != "folder-1" {
+		t.Errorf("Expected the first folder's ID to win, got '%s'", news.ID)
+	}
+}
+
+func TestMergeFoldersWithOptions(t *testing.T) {
+	organized := func() *Folder {
+		return &Folder{
+			Title: "Root",
+			Children: []Node{
+				&Folder{Title: "Bookmarks Toolbar", Children: []Node{
+					&Bookmark{Title: "Go", URL: "https://go.dev/"},
+				}},
+				&Folder{Title: "Imported"},
+			},
+		}
+	}
+	chrome := &Folder{
+		Title: "Bookmarks",
+		Children: []Node{
+			&Folder{Title: "Bookmarks bar", Children: []Node{
+				&Bookmark{Title: "Rust", URL: "https://rust-lang.org/"},
+			}},
+		},
+	}
+	pocket := &Folder{
+		Title: "Bookmarks",
+		Children: []Node{
+			&Bookmark{Title: "Article", URL: "https://example.com/article"},
+		},
+	}
+
+	// Aliases match the toolbar folders
+	merged := MergeFoldersWithOptions(organized(), chrome, MergeOptions{Aliases: BrowserFolderAliases})
+	if len(merged.Children) != 2 {
+		t.Fatalf("Expected the toolbar folders to be merged, got %d top-level folders", len(merged.Children))
+	}
+	toolbar := merged.Children[0].(*Folder)
+	if toolbar.Title != "Bookmarks Toolbar" || len(toolbar.Children) != 2 {
+		t.Errorf("Expected 'Bookmarks Toolbar' with 2 bookmarks, got '%s' with %d", toolbar.Title, len(toolbar.Children))
+	}
+
+	// Without aliases they stay separate
+	if merged := MergeFolders(organized(), chrome); len(merged.Children) != 3 {
+		t.Errorf("Expected 3 top-level folders without aliases, got %d", len(merged.Children))
+	}
+
+	// Grafted under a target path, with a bookmark-only input in an inbox
+	merged = MergeFoldersWithOptions(organized(), pocket, MergeOptions{Target: []string{"imported", "Pocket"}, Inbox: "Inbox"})
+	imported := merged.Children[1].(*Folder)
+	if imported.Title != "Imported" || len(imported.Children) != 1 {
+		t.Fatalf("Expected the existing Imported folder to be used, got %+v", imported)
+	}
+	pocketFolder := imported.Children[0].(*Folder)
+	if pocketFolder.Title != "Pocket" || pocketFolder.Children[0].GetTitle() != "Inbox" {
+		t.Errorf("Expected Imported/Pocket/Inbox, got %s/%s", imported.Title, pocketFolder.Title)
+	}
+
+	// The inbox is only used for inputs without folders
+	merged = MergeFoldersWithOptions(organized(), chrome, MergeOptions{Inbox: "Inbox"})
+	for _, child := range merged.Children {
+		if child.GetTitle() == "Inbox" {
+			t.Errorf("Expected no inbox for an input with folders")
+		}
+	}
+}
+
+func TestMergeFoldersMatchesRoles(t *testing.T) {
+	folder1 := &Folder{
+		Title: "Root",
+		Children: []Node{
+			&Folder{Title: "Bookmarks Toolbar", Role: RootToolbar, Children: []Node{
+				&Bookmark{Title: "Go", URL: "https://go.dev/"},
+			}},
+		},
+	}
+	folder2 := &Folder{
+		Title: "Root",
+		Children: []Node{
+			&Folder{Title: "Bookmarks bar", Role: RootToolbar, Children: []Node{
+				&Bookmark{Title: "Rust", URL: "https://rust-lang.org/"},
+			}},
+			&Folder{Title: "Other bookmarks", Role: RootOther},
+		},
+	}
+
+	merged := MergeFolders(folder1, folder2)
+
+	if len(merged.Children) != 2 {
+		t.Fatalf("Expected 2 folders, got %d", len(merged.Children))
+	}
+	toolbar := merged.Children[0].(*Folder)
+	if toolbar.Title != "Bookmarks Toolbar" || toolbar.Role != RootToolbar {
+		t.Errorf("Expected the first toolbar's title and role, got '%s' (%q)", toolbar.Title, toolbar.Role)
+	}
+	if len(toolbar.Children) != 2 {
+		t.Errorf("Expected both toolbars' bookmarks in one folder, got %d", len(toolbar.Children))
+	}
+	if other := merged.Children[1].(*Folder); other.Role != RootOther {
+		t.Errorf("Expected the unmatched folder to keep its role, got %q", other.Role)
+	}
+}
+
+func TestParseRootRole(t *testing.T) {
+	if role, err := ParseRootRole(" Toolbar "); err != nil || role != RootToolbar {
+		t.Errorf("Expected the toolbar role, got %q (%v)", role, err)
+	}
+	if _, err := ParseRootRole("menu"); err == nil {
+		t.Error("Expected an error for an unknown role")
+	}
+}
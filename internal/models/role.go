@@ -0,0 +1,32 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RootRole is the special root folder a browser keeps outside the regular
+// folder tree that a folder stands for, like the bookmarks toolbar. Browsers
+// name these folders differently, so the role is how they are recognized.
+type RootRole string
+
+const (
+	RootNone    RootRole = ""        // A regular folder
+	RootToolbar RootRole = "toolbar" // Bookmarks toolbar or bar, Safari's favorites
+	RootOther   RootRole = "other"   // Other (unsorted) bookmarks
+	RootMobile  RootRole = "mobile"  // Mobile bookmarks
+	RootReading RootRole = "reading" // Safari's Reading List
+)
+
+// rootRoleNames are the names of the roles of special folders, as used in
+// Org files
+var rootRoleNames = []string{string(RootToolbar), string(RootOther), string(RootMobile), string(RootReading)}
+
+// ParseRootRole returns the root role with the given name
+func ParseRootRole(name string) (RootRole, error) {
+	if i := slices.Index(rootRoleNames, strings.ToLower(strings.TrimSpace(name))); i >= 0 {
+		return RootRole(rootRoleNames[i]), nil
+	}
+	return RootNone, fmt.Errorf("unknown root folder role %q (use %s)", name, strings.Join(rootRoleNames, ", "))
+}
@@ -0,0 +1,312 @@
+package models
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DefaultSimilarityThreshold is the score from which two bookmarks count as
+// near-duplicates, if no other threshold is given
+const DefaultSimilarityThreshold = 0.7
+
+// Weights of the URL and title similarity in the score of a pair. A pair with
+// the same page URL reaches the default threshold on its own.
+const (
+	urlSimilarityWeight   = 0.7
+	titleSimilarityWeight = 0.3
+)
+
+// SimilarityOptions controls how near-duplicate bookmarks are found
+type SimilarityOptions struct {
+	Threshold float64 // Lowest score for a pair to be reported (default: DefaultSimilarityThreshold)
+
+	// URLs is how strictly URLs are compared to tell exact duplicates, as in
+	// DeduplicateOptions. Pairs of exact duplicates are left to Deduplicate
+	// and not reported, unless IncludeDuplicates is set.
+	URLs              URLNormalization
+	IncludeDuplicates bool
+}
+
+// SimilarPair is a pair of bookmarks in a cluster that look like the same page
+type SimilarPair struct {
+	A, B       int     // Indices of the two bookmarks in the cluster
+	Score      float64 // Combined score, from 0 to 1
+	URLScore   float64 // Similarity of the URLs, from 0 to 1
+	TitleScore float64 // Similarity of the titles, from 0 to 1
+}
+
+// SimilarCluster is a group of bookmarks that are probably the same page.
+// Every bookmark is linked to at least one other by a pair above the threshold.
+type SimilarCluster struct {
+	Bookmarks []Duplicate   // The bookmarks, in tree order
+	Pairs     []SimilarPair // The pairs that scored above the threshold
+}
+
+// similarCandidate is a bookmark with the parts of it that are compared
+type similarCandidate struct {
+	Duplicate
+	dedupeKey   string          // URL as compared by Deduplicate
+	key         string          // Page URL with AMP, mobile and print variations removed
+	urlTokens   map[string]bool // Words of the path and query of key
+	titleTokens map[string]bool // Words of the title
+}
+
+// FindSimilar finds bookmarks that are probably the same page under different
+// URLs: AMP and mobile versions, print views, or URLs with the same words.
+// Exact duplicates, which Deduplicate finds, are left out unless the options
+// ask for them. Pairs are scored by the similarity of their URLs (after
+// normalizing them) and titles, and linked pairs are returned as clusters, in
+// the order they first appear in the tree. Nothing is removed.
+func FindSimilar(root *Folder, opts SimilarityOptions) []SimilarCluster {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultSimilarityThreshold
+	}
+
+	// Only bookmarks on the same host are compared; the URL similarity
+	// of bookmarks on different hosts is 0, so they can't reach the threshold
+	var candidates []*similarCandidate
+	hosts := make(map[string][]int)
+	var walk func(folder *Folder, path []string)
+	walk = func(folder *Folder, path []string) {
+		for _, child := range folder.Children {
+			if child.IsFolder() {
+				subfolder := child.(*Folder)
+				walk(subfolder, append(path[:len(path):len(path)], subfolder.Title))
+				continue
+			}
+			bookmark, ok := child.(*Bookmark)
+			if !ok {
+				continue
+			}
+			key := pageURL(bookmark.URL)
+			host, rest, _ := strings.Cut(strings.TrimPrefix(key, "https://"), "/")
+			hosts[host] = append(hosts[host], len(candidates))
+			candidates = append(candidates, &similarCandidate{
+				Duplicate:   Duplicate{Bookmark: bookmark, Folder: folder, Path: path},
+				dedupeKey:   NormalizeURL(bookmark.URL, opts.URLs),
+				key:         key,
+				urlTokens:   wordSet(rest),
+				titleTokens: wordSet(bookmark.Title),
+			})
+		}
+	}
+	walk(root, nil)
+
+	// Link the pairs above the threshold, with a union-find over the candidates
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	type scoredPair struct {
+		a, b int
+		SimilarPair
+	}
+	var pairs []scoredPair
+	for _, members := range hosts {
+		for x, i := range members {
+			for _, j := range members[x+1:] {
+				if !opts.IncludeDuplicates && candidates[i].dedupeKey == candidates[j].dedupeKey {
+					continue
+				}
+				pair := scorePair(candidates[i], candidates[j])
+				if pair.Score < threshold {
+					continue
+				}
+				pairs = append(pairs, scoredPair{i, j, pair})
+				if ri, rj := find(i), find(j); ri != rj {
+					parent[max(ri, rj)] = min(ri, rj)
+				}
+			}
+		}
+	}
+
+	// Build the clusters in tree order
+	clusterOf := make(map[int]int)
+	index := make([]int, len(candidates)) // Index of each candidate in its cluster
+	var clusters []SimilarCluster
+	linked := make(map[int]bool)
+	for _, pair := range pairs {
+		linked[pair.a] = true
+		linked[pair.b] = true
+	}
+	for i, c := range candidates {
+		if !linked[i] {
+			continue
+		}
+		root := find(i)
+		n, ok := clusterOf[root]
+		if !ok {
+			n = len(clusters)
+			clusterOf[root] = n
+			clusters = append(clusters, SimilarCluster{})
+		}
+		index[i] = len(clusters[n].Bookmarks)
+		clusters[n].Bookmarks = append(clusters[n].Bookmarks, c.Duplicate)
+	}
+	for _, pair := range pairs {
+		n := clusterOf[find(pair.a)]
+		p := pair.SimilarPair
+		p.A, p.B = index[pair.a], index[pair.b]
+		if p.A > p.B {
+			p.A, p.B = p.B, p.A
+		}
+		clusters[n].Pairs = append(clusters[n].Pairs, p)
+	}
+	for n := range clusters {
+		slices.SortFunc(clusters[n].Pairs, func(a, b SimilarPair) int {
+			return cmp.Or(cmp.Compare(a.A, b.A), cmp.Compare(a.B, b.B))
+		})
+	}
+
+	return clusters
+}
+
+// scorePair compares two bookmarks on the same host
+func scorePair(a, b *similarCandidate) SimilarPair {
+	pair := SimilarPair{
+		URLScore:   jaccard(a.urlTokens, b.urlTokens),
+		TitleScore: jaccard(a.titleTokens, b.titleTokens),
+	}
+	if a.key == b.key {
+		pair.URLScore = 1
+	}
+	pair.Score = urlSimilarityWeight*pair.URLScore + titleSimilarityWeight*pair.TitleScore
+	return pair
+}
+
+// mobileHostPrefixes are host name prefixes of mobile and AMP versions of sites
+var mobileHostPrefixes = []string{"m.", "mobile.", "amp.", "www."}
+
+// ampCachePath returns the path prefix under which an AMP cache serves pages
+// of other sites, followed by their host and path, as in
+// https://www.google.com/amp/s/example.com/article
+func ampCachePath(host string) (string, bool) {
+	switch {
+	case host == "google.com":
+		return "/amp/s/", true
+	case host == "cdn.ampproject.org" || strings.HasSuffix(host, ".cdn.ampproject.org"),
+		host == "bing-amp.com" || strings.HasSuffix(host, ".bing-amp.com"):
+		return "/c/s/", true
+	}
+	return "", false
+}
+
+// variantParams are query parameters that select an AMP, mobile or print
+// version of a page
+var variantParams = map[string]bool{
+	"amp":       true,
+	"print":     true,
+	"printable": true,
+	"mobile":    true,
+	"m":         true,
+}
+
+// variantValueParams are query parameters that select an AMP, mobile or print
+// version of a page with one of the variantSegments as value, as in
+// view=print or outputType=amp
+var variantValueParams = map[string]bool{
+	"view":       true,
+	"output":     true,
+	"outputtype": true,
+	"format":     true,
+}
+
+// variantSegments are last path segments that select an AMP or print version
+// of a page, as in /article/amp or /article/print. An AMP version can also
+// have amp as the first segment.
+var variantSegments = map[string]bool{
+	"amp":       true,
+	"print":     true,
+	"printable": true,
+	"mobile":    true,
+}
+
+// pageURL returns the URL of the page a bookmark points to, with the
+// variations of AMP, mobile and print versions removed, in the aggressive
+// canonical form
+func pageURL(rawURL string) string {
+	normalized := NormalizeURL(rawURL, NormalizeAggressive)
+	u, err := url.Parse(normalized)
+	if err != nil || u.Host == "" {
+		return normalized
+	}
+
+	if prefix, ok := ampCachePath(u.Hostname()); ok {
+		if cached, ok := strings.CutPrefix(u.EscapedPath(), prefix); ok {
+			if inner, err := url.Parse("https://" + cached); err == nil && inner.Host != "" {
+				u = inner
+			}
+		}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range mobileHostPrefixes {
+		host = strings.TrimPrefix(host, prefix)
+	}
+
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	if last := len(segments) - 1; last > 0 && variantSegments[strings.ToLower(segments[last])] {
+		segments = segments[:last]
+	} else if last > 0 && strings.EqualFold(segments[0], "amp") {
+		// example.com/amp/article
+		segments = segments[1:]
+	}
+	path := strings.Join(segments, "/")
+	if page, ok := strings.CutSuffix(path, ".amp.html"); ok {
+		path = page + ".html"
+	} else {
+		path = strings.TrimSuffix(path, ".amp")
+	}
+
+	query := u.Query()
+	for name, values := range query {
+		lower := strings.ToLower(name)
+		if variantParams[lower] || variantValueParams[lower] && len(values) == 1 && variantSegments[strings.ToLower(values[0])] {
+			query.Del(name)
+		}
+	}
+
+	key := "https://" + host + "/" + path
+	if encoded := query.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+	return key
+}
+
+// wordSet returns the lowercase words (runs of letters and digits) in s
+func wordSet(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
+
+// jaccard returns the Jaccard similarity of two word sets: the number of
+// words in both divided by the number of words in either
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	both := 0
+	for word := range a {
+		if b[word] {
+			both++
+		}
+	}
+	return float64(both) / float64(len(a)+len(b)-both)
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestPageURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/news/story", "https://example.com/news/story"},
+		{"http://www.example.com/news/story/", "https://example.com/news/story"},
+		{"https://m.example.com/news/story", "https://example.com/news/story"},
+		{"https://example.com/news/story/amp", "https://example.com/news/story"},
+		{"https://example.com/amp/news/story", "https://example.com/news/story"},
+		{"https://example.com/news/story.amp.html", "https://example.com/news/story.html"},
+		{"https://example.com/news/story?outputType=amp", "https://example.com/news/story"},
+		{"https://example.com/news/story?view=print&page=2", "https://example.com/news/story?page=2"},
+		{"https://example.com/news/story?view=list", "https://example.com/news/story?view=list"},
+		{"https://example.com/news/story/print", "https://example.com/news/story"},
+		{"https://www.google.com/amp/s/example.com/news/story", "https://example.com/news/story"},
+		{"https://example-com.cdn.ampproject.org/c/s/example.com/news/story", "https://example.com/news/story"},
+		{"https://example.com/amp", "https://example.com/amp"},
+	}
+
+	for _, tt := range tests {
+		if got := pageURL(tt.url); got != tt.want {
+			t.Errorf("pageURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestFindSimilar(t *testing.T) {
+	root := &Folder{
+		Title: "Root",
+		Children: []Node{
+			&Bookmark{Title: "Big Story - Example News", URL: "https://example.com/news/big-story"},
+			&Bookmark{Title: "Unrelated", URL: "https://example.com/about"},
+			&Folder{
+				Title: "Mobile",
+				Children: []Node{
+					&Bookmark{Title: "Big Story", URL: "https://m.example.com/news/big-story"},
+					&Bookmark{Title: "Other Site", URL: "https://other.org/news/big-story"},
+				},
+			},
+			&Bookmark{Title: "Example News (AMP)", URL: "https://www.google.com/amp/s/example.com/news/big-story/amp"},
+			&Bookmark{Title: "Installing Go", URL: "https://go.dev/doc/install/guide?lang=en"},
+			&Bookmark{Title: "Installing Go", URL: "https://go.dev/doc/install/guide?lang=de"},
+		},
+	}
+
+	clusters := FindSimilar(root, SimilarityOptions{})
+	if len(clusters) != 2 {
+		t.Fatalf("Expected 2 clusters, got %d", len(clusters))
+	}
+
+	// The three versions of the story, in tree order
+	story := clusters[0]
+	expectedTitles := []string{"Big Story - Example News", "Big Story", "Example News (AMP)"}
+	if len(story.Bookmarks) != len(expectedTitles) {
+		t.Fatalf("Expected %d bookmarks in the first cluster, got %d", len(expectedTitles), len(story.Bookmarks))
+	}
+	for i, title := range expectedTitles {
+		if story.Bookmarks[i].Bookmark.Title != title {
+			t.Errorf("Expected bookmark %d to be '%s', got '%s'", i, title, story.Bookmarks[i].Bookmark.Title)
+		}
+	}
+	if len(story.Pairs) != 3 {
+		t.Errorf("Expected 3 pairs in the first cluster, got %d", len(story.Pairs))
+	}
+	for _, pair := range story.Pairs {
+		if pair.URLScore != 1 {
+			t.Errorf("Expected the same page URL for %d and %d, got URL score %.2f", pair.A, pair.B, pair.URLScore)
+		}
+	}
+	if story.Bookmarks[1].Path[0] != "Mobile" {
+		t.Errorf("Expected the folder path of the copies to be reported")
+	}
+
+	// Same title and similar URL
+	install := clusters[1]
+	if len(install.Bookmarks) != 2 || install.Pairs[0].TitleScore != 1 {
+		t.Errorf("Expected the two install guides to be in one cluster")
+	}
+
+	// A higher threshold leaves only pages with the same URL and similar titles
+	clusters = FindSimilar(root, SimilarityOptions{Threshold: 0.8})
+	if len(clusters) != 1 {
+		t.Errorf("Expected 1 cluster with threshold 0.8, got %d", len(clusters))
+	}
+
+	// Nothing is removed
+	if CountNodes(root) != 9 {
+		t.Errorf("Expected the tree to be unchanged")
+	}
+}
+
+func TestFindSimilarSkipsDuplicates(t *testing.T) {
+	root := &Folder{
+		Title: "Root",
+		Children: []Node{
+			&Bookmark{Title: "Big Story", URL: "https://example.com/news/big-story"},
+			&Bookmark{Title: "Big Story (saved again)", URL: "https://example.com/news/big-story/"},
+		},
+	}
+
+	// The URLs differ, so they are near-duplicates when compared exactly
+	if clusters := FindSimilar(root, SimilarityOptions{}); len(clusters) != 1 {
+		t.Errorf("Expected 1 cluster with exact URL matching, got %d", len(clusters))
+	}
+
+	// With the dedupe rules they are the same URL, so dedupe deals with them
+	if clusters := FindSimilar(root, SimilarityOptions{URLs: NormalizeStandard}); len(clusters) != 0 {
+		t.Errorf("Expected exact duplicates to be skipped, got %d clusters", len(clusters))
+	}
+	if clusters := FindSimilar(root, SimilarityOptions{URLs: NormalizeStandard, IncludeDuplicates: true}); len(clusters) != 1 {
+		t.Errorf("Expected exact duplicates to be included, got %d clusters", len(clusters))
+	}
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Duplicate is one copy of a bookmark whose URL appears more than once,
+// with the folder it is in
+type Duplicate struct {
+	Bookmark *Bookmark
+	Folder   *Folder  // The folder containing the bookmark
+	Path     []string // Titles of the folders from the root to Folder, not including the root
+}
+
+// DuplicateStrategy decides which copy of a duplicated bookmark is kept
+type DuplicateStrategy int
+
+const (
+	// KeepFirst keeps the copy that comes first in the tree
+	KeepFirst DuplicateStrategy = iota
+
+	// KeepNewest keeps the most recently modified copy (by LastModified)
+	KeepNewest
+
+	// KeepOldest keeps the copy that was added first (by AddDate)
+	KeepOldest
+
+	// KeepDeepest keeps the copy in the most deeply nested folder, which is
+	// usually the most carefully filed one
+	KeepDeepest
+
+	// MergeDuplicates keeps the first copy and merges the metadata of the
+	// others into it: the union of the tags, all descriptions, the earliest
+	// AddDate and the latest LastModified
+	MergeDuplicates
+)
+
+// duplicateStrategyNames are the names of the strategies, as used on the command line
+var duplicateStrategyNames = []string{"keep-first", "keep-newest", "keep-oldest", "keep-deepest-folder", "merge"}
+
+// String returns the name of the strategy
+func (s DuplicateStrategy) String() string {
+	if s < 0 || int(s) >= len(duplicateStrategyNames) {
+		return fmt.Sprintf("DuplicateStrategy(%d)", int(s))
+	}
+	return duplicateStrategyNames[s]
+}
+
+// ParseDuplicateStrategy returns the strategy with the given name
+func ParseDuplicateStrategy(name string) (DuplicateStrategy, error) {
+	if i := slices.Index(duplicateStrategyNames, strings.ToLower(strings.TrimSpace(name))); i >= 0 {
+		return DuplicateStrategy(i), nil
+	}
+	return KeepFirst, fmt.Errorf("unknown duplicate strategy %q (use %s)", name, strings.Join(duplicateStrategyNames, ", "))
+}
+
+// Resolve returns the index of the copy to keep. The copies are in tree
+// order; ties go to the earlier copy. With MergeDuplicates the kept copy is
+// updated with the metadata of the others.
+func (s DuplicateStrategy) Resolve(copies []Duplicate) int {
+	keep := s.Choose(copies)
+	if s == MergeDuplicates {
+		MergeCopies(copies, keep)
+	}
+	return keep
+}
+
+// Choose returns the index of the copy the strategy keeps, without changing
+// any of the copies
+func (s DuplicateStrategy) Choose(copies []Duplicate) int {
+	keep := 0
+	for i := 1; i < len(copies); i++ {
+		c, kept := copies[i], copies[keep].Bookmark
+		switch s {
+		case KeepNewest:
+			if c.Bookmark.LastModified.After(kept.LastModified) {
+				keep = i
+			}
+		case KeepOldest:
+			// Bookmarks without a date don't count as the oldest
+			if !c.Bookmark.AddDate.IsZero() && (kept.AddDate.IsZero() || c.Bookmark.AddDate.Before(kept.AddDate)) {
+				keep = i
+			}
+		case KeepDeepest:
+			if len(c.Path) > len(copies[keep].Path) {
+				keep = i
+			}
+		}
+	}
+
+	return keep
+}
+
+// MergeCopies merges the metadata of the other copies into the copy at index
+// keep, as the MergeDuplicates strategy does
+func MergeCopies(copies []Duplicate, keep int) {
+	for i, c := range copies {
+		if i != keep {
+			mergeBookmark(copies[keep].Bookmark, c.Bookmark)
+		}
+	}
+}
+
+// mergeBookmark adds the metadata of a duplicate to the bookmark that is kept.
+// Values the kept bookmark already has take precedence over the duplicate's.
+func mergeBookmark(kept, dup *Bookmark) {
+	for _, tag := range dup.Tags {
+		if !slices.Contains(kept.Tags, tag) {
+			kept.Tags = append(kept.Tags, tag)
+		}
+	}
+
+	if dup.Description != "" && !strings.Contains(kept.Description, dup.Description) {
+		if kept.Description == "" {
+			kept.Description = dup.Description
+		} else {
+			kept.Description += "\n\n" + dup.Description
+		}
+	}
+
+	if !dup.AddDate.IsZero() && (kept.AddDate.IsZero() || dup.AddDate.Before(kept.AddDate)) {
+		kept.AddDate = dup.AddDate
+	}
+	if dup.LastModified.After(kept.LastModified) {
+		kept.LastModified = dup.LastModified
+	}
+
+	if kept.ID == "" {
+		kept.ID = dup.ID
+	}
+	if kept.ShortcutURL == "" {
+		kept.ShortcutURL = dup.ShortcutURL
+	}
+	if kept.Icon == "" {
+		kept.Icon = dup.Icon
+		kept.IconURI = dup.IconURI
+	}
+
+	for key, value := range dup.Properties {
+		if _, ok := kept.Properties[key]; ok {
+			continue
+		}
+		if kept.Properties == nil {
+			kept.Properties = make(map[string]string)
+		}
+		kept.Properties[key] = value
+	}
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// duplicateTree returns a tree with three copies of the same URL
+func duplicateTree() *Folder {
+	return &Folder{
+		Title: "Root",
+		Children: []Node{
+			&Bookmark{
+				Title:        "First",
+				URL:          "https://example.com/",
+				Tags:         []string{"go", "docs"},
+				Description:  "First description",
+				AddDate:      time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+				LastModified: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+			},
+			&Folder{
+				Title: "Dev",
+				Children: []Node{
+					&Folder{
+						Title: "Go",
+						Children: []Node{
+							&Bookmark{
+								ID:           "Xq2c8GgU4TbN",
+								Title:        "Deepest",
+								URL:          "https://example.com/",
+								Tags:         []string{"go", "reference"},
+								ShortcutURL:  "ex",
+								AddDate:      time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+								LastModified: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+							},
+						},
+					},
+					&Bookmark{
+						Title:        "Newest",
+						URL:          "https://example.com/",
+						Description:  "Another description",
+						LastModified: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+	}
+}
+
+// remainingBookmarks returns the bookmarks left in a tree, in order
+func remainingBookmarks(root *Folder) []*Bookmark {
+	var bookmarks []*Bookmark
+	Walk(root, 0, func(node Node, depth int) {
+		if bookmark, ok := node.(*Bookmark); ok {
+			bookmarks = append(bookmarks, bookmark)
+		}
+	})
+	return bookmarks
+}
+
+func TestDuplicateStrategies(t *testing.T) {
+	tests := []struct {
+		strategy DuplicateStrategy
+		want     string
+	}{
+		{KeepFirst, "First"},
+		{KeepNewest, "Newest"},
+		{KeepOldest, "Deepest"},
+		{KeepDeepest, "Deepest"},
+		{MergeDuplicates, "First"},
+	}
+
+	for _, tt := range tests {
+		root := duplicateTree()
+		DeduplicateWithOptions(root, DeduplicateOptions{Strategy: tt.strategy})
+
+		bookmarks := remainingBookmarks(root)
+		if len(bookmarks) != 1 {
+			t.Errorf("%s: expected 1 bookmark, got %d", tt.strategy, len(bookmarks))
+			continue
+		}
+		if bookmarks[0].Title != tt.want {
+			t.Errorf("%s: expected '%s' to be kept, got '%s'", tt.strategy, tt.want, bookmarks[0].Title)
+		}
+	}
+}
+
+func TestMergeDuplicatesMetadata(t *testing.T) {
+	root := duplicateTree()
+	DeduplicateWithOptions(root, DeduplicateOptions{Strategy: MergeDuplicates})
+
+	kept := remainingBookmarks(root)[0]
+	if want := []string{"go", "docs", "reference"}; !slices.Equal(kept.Tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, kept.Tags)
+	}
+	if want := "First description\n\nAnother description"; kept.Description != want {
+		t.Errorf("Expected description %q, got %q", want, kept.Description)
+	}
+	if want := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC); !kept.AddDate.Equal(want) {
+		t.Errorf("Expected earliest AddDate %v, got %v", want, kept.AddDate)
+	}
+	if want := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC); !kept.LastModified.Equal(want) {
+		t.Errorf("Expected latest LastModified %v, got %v", want, kept.LastModified)
+	}
+	if kept.ShortcutURL != "ex" {
+		t.Errorf("Expected shortcut 'ex', got '%s'", kept.ShortcutURL)
+	}
+	if kept.ID != "Xq2c8GgU4TbN" {
+		t.Errorf("Expected the ID of a copy to be kept, got '%s'", kept.ID)
+	}
+}
+
+func TestDeduplicateCustomResolve(t *testing.T) {
+	root := duplicateTree()
+	var paths [][]string
+	DeduplicateWithOptions(root, DeduplicateOptions{
+		Resolve: func(copies []Duplicate) (int, error) {
+			for _, c := range copies {
+				paths = append(paths, c.Path)
+			}
+			return len(copies) - 1, nil
+		},
+	})
+
+	if len(paths) != 3 || len(paths[0]) != 0 || !slices.Equal(paths[1], []string{"Dev", "Go"}) || !slices.Equal(paths[2], []string{"Dev"}) {
+		t.Errorf("Unexpected folder paths of the copies: %v", paths)
+	}
+	if bookmarks := remainingBookmarks(root); len(bookmarks) != 1 || bookmarks[0].Title != "Newest" {
+		t.Errorf("Expected the last copy to be kept")
+	}
+}
+
+func TestParseDuplicateStrategy(t *testing.T) {
+	for _, strategy := range []DuplicateStrategy{KeepFirst, KeepNewest, KeepOldest, KeepDeepest, MergeDuplicates} {
+		parsed, err := ParseDuplicateStrategy(strategy.String())
+		if err != nil || parsed != strategy {
+			t.Errorf("ParseDuplicateStrategy(%q) = %v, %v; want %v", strategy.String(), parsed, err, strategy)
+		}
+	}
+	if _, err := ParseDuplicateStrategy("keep-last"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func TestDeduplicateReport(t *testing.T) {
+	root := duplicateTree()
+	root.AddChild(&Bookmark{Title: "Unique", URL: "https://unique.example.com/"})
+	root.AddChild(&Bookmark{Title: "Again", URL: "https://unique.example.com/"})
+
+	report, err := DeduplicateWithOptions(root, DeduplicateOptions{Strategy: KeepNewest})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Groups) != 2 {
+		t.Fatalf("Expected 2 duplicate groups, got %d", len(report.Groups))
+	}
+
+	group := report.Groups[0]
+	if group.URL != "https://example.com/" || len(group.Copies) != 3 {
+		t.Errorf("Unexpected first group: %s with %d copies", group.URL, len(group.Copies))
+	}
+	if group.Copies[group.Kept].Bookmark.Title != "Newest" {
+		t.Errorf("Expected 'Newest' to be reported as kept, got '%s'", group.Copies[group.Kept].Bookmark.Title)
+	}
+	if report.Removed() != 3 {
+		t.Errorf("Expected 3 removed bookmarks, got %d", report.Removed())
+	}
+}
+
+func TestDeduplicateKeepAll(t *testing.T) {
+	root := duplicateTree()
+	report, err := DeduplicateWithOptions(root, DeduplicateOptions{
+		Resolve: func(copies []Duplicate) (int, error) {
+			return KeepAll, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(remainingBookmarks(root)) != 3 {
+		t.Errorf("Expected all copies to be kept")
+	}
+	if report.Removed() != 0 || report.Groups[0].Kept != KeepAll {
+		t.Errorf("Expected the report to show all copies kept")
+	}
+}
@@ -0,0 +1,622 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MergeConflict is a change made on both sides of a three-way merge that
+// can't be combined. The result keeps our version; the conflict describes
+// what was lost.
+type MergeConflict struct {
+	Bookmark *Bookmark // The bookmark in the result (nil if it was deleted)
+	URL      string    // The URL in the base, or ours if the bookmark is new
+	Path     []string  // Folder path of the bookmark in the result, or in the base if deleted
+	Field    string    // What was changed: "title", "url", "description", "shortcut", "folder", a property name, or "" if one side deleted the bookmark
+	Ours     string    // Our value
+	Theirs   string    // Their value
+}
+
+// String describes the conflict in one line
+func (c MergeConflict) String() string {
+	switch {
+	case c.Field == "" && c.Bookmark == nil:
+		return fmt.Sprintf("%s: deleted in ours, changed in theirs; left deleted", c.URL)
+	case c.Field == "":
+		return fmt.Sprintf("%s: changed in ours, deleted in theirs; kept ours", c.URL)
+	default:
+		return fmt.Sprintf("%s: %s changed to %q in ours and %q in theirs; kept ours", c.URL, c.Field, c.Ours, c.Theirs)
+	}
+}
+
+// bookmarkEntry is a bookmark in one of the trees being merged or compared
+type bookmarkEntry struct {
+	bookmark *Bookmark
+	folder   *Folder  // The folder containing it
+	path     []string // Folder titles from the root, not including the root
+	key      string   // Identity of the bookmark in its tree
+}
+
+// mergeRecord is a bookmark as it appears in the base, ours and theirs
+// (nil where it doesn't exist)
+type mergeRecord struct {
+	base, ours, theirs *bookmarkEntry
+}
+
+// ThreeWayMerge merges two edited versions of a bookmark tree, ours and
+// theirs, that were both made from base, the state at the last merge.
+// Unlike MergeFolders, which adds everything from both trees, changes made on
+// either side since the base are applied: bookmarks deleted on one side stay
+// deleted, moves to another folder and edits of the title, URL, tags,
+// description, shortcut and properties are taken over, folders renamed in
+// theirs are renamed in place, keeping their position and metadata, and
+// folders deleted in theirs disappear once they are empty.
+//
+// Bookmarks are matched by ID, then by URL, and a bookmark whose URL changed
+// is matched by its title and folder. Folders are matched below their parent
+// by ID, then by title, then by their bookmarks. If both sides changed the
+// same thing differently, or one side deleted a bookmark the other changed,
+// our version is kept and the conflict is returned. Ours and theirs are left
+// unchanged.
+func ThreeWayMerge(base, ours, theirs *Folder) (*Folder, []MergeConflict) {
+	result := cloneFolder(ours)
+
+	// Match the folders and take over their renames, then give the folders of
+	// all three trees the paths they have in the result, so bookmarks in a
+	// renamed folder don't count as moved
+	ourFolders := map[*Folder]*Folder{base: result}
+	matchFolders(base, result, ourFolders)
+	theirFolders := map[*Folder]*Folder{base: theirs}
+	matchFolders(base, theirs, theirFolders)
+	theirsInResult := make(map[*Folder]*Folder)
+	for b, t := range theirFolders {
+		o := ourFolders[b]
+		if o == nil {
+			continue
+		}
+		theirsInResult[t] = o
+		if t.Title != b.Title && o.Title == b.Title {
+			o.Title = t.Title
+		}
+	}
+	resultPaths := mergePaths(result, nil, nil)
+	basePaths := mergePaths(base, ourFolders, resultPaths)
+	theirPaths := mergePaths(theirs, theirsInResult, resultPaths)
+
+	baseEntries := indexBookmarks(base, mergeKey)
+	ourEntries := indexBookmarks(result, mergeKey)
+	theirEntries := indexBookmarks(theirs, mergeKey)
+	usePaths(baseEntries, basePaths)
+	usePaths(theirEntries, theirPaths)
+	records := matchRecords(baseEntries, ourEntries, theirEntries)
+
+	var conflicts []MergeConflict
+	for _, r := range records {
+		conflicts = append(conflicts, mergeRecordInto(result, r)...)
+	}
+
+	// Folders deleted in theirs are removed once nothing is left in them;
+	// new folders in theirs are added even if they are empty
+	matchedTheirs := make(map[*Folder]bool)
+	for _, t := range theirFolders {
+		matchedTheirs[t] = true
+	}
+	for _, folder := range orderedFolders(base) {
+		if theirFolders[folder] == nil {
+			removeFolderIfEmpty(result, basePaths[folder])
+		}
+	}
+	for _, folder := range orderedFolders(theirs) {
+		if !matchedTheirs[folder] {
+			ensureFolder(result, theirPaths[folder])
+		}
+	}
+
+	return result, conflicts
+}
+
+// mergeKey returns the key bookmarks are matched by in a three-way merge
+func mergeKey(url string) string {
+	return NormalizeURL(url, NormalizeBasic)
+}
+
+// mergeRecordInto applies the changes to one bookmark to the result, which
+// starts out as a copy of ours
+func mergeRecordInto(result *Folder, r mergeRecord) []MergeConflict {
+	b, o, t := r.base, r.ours, r.theirs
+
+	switch {
+	case b == nil && o == nil:
+		// Added in theirs
+		bookmark := copyBookmark(t.bookmark)
+		ensureFolder(result, t.path).AddChild(bookmark)
+		return nil
+
+	case b == nil && t == nil, o == nil && t == nil:
+		// Added in ours, or deleted on both sides
+		return nil
+
+	case b == nil:
+		// Added on both sides: merge them as if the base was empty. With
+		// their folder as the base folder, the bookmark stays in ours, and
+		// URLs that only differ in their spelling are no conflict either.
+		empty := &Bookmark{}
+		if mergeKey(o.bookmark.URL) == mergeKey(t.bookmark.URL) {
+			empty.URL = t.bookmark.URL
+		}
+		return mergeBookmarkFields(&bookmarkEntry{bookmark: empty, path: t.path}, o, t, result)
+
+	case o == nil:
+		// Deleted in ours
+		if bookmarkChanged(b, t) {
+			return []MergeConflict{{URL: b.bookmark.URL, Path: b.path}}
+		}
+		return nil
+
+	case t == nil:
+		// Deleted in theirs
+		if bookmarkChanged(b, o) {
+			return []MergeConflict{{Bookmark: o.bookmark, URL: b.bookmark.URL, Path: o.path}}
+		}
+		removeChild(o.folder, o.bookmark)
+		return nil
+	}
+
+	return mergeBookmarkFields(b, o, t, result)
+}
+
+// mergeBookmarkFields merges the changes to a bookmark that exists on both
+// sides into our copy in the result
+func mergeBookmarkFields(b, o, t *bookmarkEntry, result *Folder) []MergeConflict {
+	var conflicts []MergeConflict
+	kept := o.bookmark
+	conflict := func(field, ours, theirs string) {
+		conflicts = append(conflicts, MergeConflict{Bookmark: kept, Field: field, Ours: ours, Theirs: theirs})
+	}
+	merge := func(field string, base, ours, theirs string) string {
+		value, ok := mergeValue(base, ours, theirs)
+		if !ok {
+			conflict(field, ours, theirs)
+		}
+		return value
+	}
+
+	url := b.bookmark.URL
+	if url == "" {
+		url = o.bookmark.URL
+	}
+	kept.URL = merge("url", b.bookmark.URL, o.bookmark.URL, t.bookmark.URL)
+	kept.Title = merge("title", b.bookmark.Title, o.bookmark.Title, t.bookmark.Title)
+	kept.Description = merge("description", b.bookmark.Description, o.bookmark.Description, t.bookmark.Description)
+	kept.ShortcutURL = merge("shortcut", b.bookmark.ShortcutURL, o.bookmark.ShortcutURL, t.bookmark.ShortcutURL)
+	kept.Tags = mergeTags(b.bookmark.Tags, o.bookmark.Tags, t.bookmark.Tags)
+
+	// Favicons are refreshed by the browser; take theirs unless ours changed
+	if o.bookmark.Icon == b.bookmark.Icon {
+		kept.Icon, kept.IconURI = t.bookmark.Icon, t.bookmark.IconURI
+	}
+	if t.bookmark.LastModified.After(kept.LastModified) {
+		kept.LastModified = t.bookmark.LastModified
+	}
+	if kept.AddDate.IsZero() {
+		kept.AddDate = t.bookmark.AddDate
+	}
+
+	keys := make(map[string]bool)
+	for _, props := range []map[string]string{b.bookmark.Properties, o.bookmark.Properties, t.bookmark.Properties} {
+		for key := range props {
+			keys[key] = true
+		}
+	}
+	properties := make(map[string]string)
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		if value := merge(key, b.bookmark.Properties[key], o.bookmark.Properties[key], t.bookmark.Properties[key]); value != "" {
+			properties[key] = value
+		}
+	}
+	kept.Properties = nil
+	if len(properties) > 0 {
+		kept.Properties = properties
+	}
+
+	// Moves: follow theirs if we didn't move the bookmark
+	if samePath(o.path, b.path) && !samePath(t.path, b.path) {
+		removeChild(o.folder, kept)
+		ensureFolder(result, t.path).AddChild(kept)
+		o.path = t.path
+	} else if !samePath(o.path, b.path) && !samePath(t.path, b.path) && !samePath(o.path, t.path) {
+		conflict("folder", strings.Join(o.path, "/"), strings.Join(t.path, "/"))
+	}
+
+	for i := range conflicts {
+		conflicts[i].URL = url
+		conflicts[i].Path = o.path
+	}
+	return conflicts
+}
+
+// mergeValue merges a value changed on either side. It returns false if both
+// sides changed it differently, with our value.
+func mergeValue(base, ours, theirs string) (string, bool) {
+	switch {
+	case ours == theirs, theirs == base:
+		return ours, true
+	case ours == base:
+		return theirs, true
+	default:
+		return ours, false
+	}
+}
+
+// mergeTags merges tag lists as sets: tags added on either side are added,
+// and tags removed on either side are removed
+func mergeTags(base, ours, theirs []string) []string {
+	var merged []string
+	for _, tag := range ours {
+		if slices.Contains(base, tag) && !slices.Contains(theirs, tag) {
+			continue // Removed in theirs
+		}
+		merged = append(merged, tag)
+	}
+	for _, tag := range theirs {
+		if !slices.Contains(base, tag) && !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// bookmarkChanged reports whether a bookmark was edited or moved since the base
+func bookmarkChanged(base, other *bookmarkEntry) bool {
+	b, o := base.bookmark, other.bookmark
+	return b.URL != o.URL || b.Title != o.Title || b.Description != o.Description ||
+		b.ShortcutURL != o.ShortcutURL || !sameTags(b.Tags, o.Tags) ||
+		!maps.Equal(b.Properties, o.Properties) || !samePath(base.path, other.path)
+}
+
+// sameTags reports whether two tag lists have the same tags, in any order
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, tag := range a {
+		if !slices.Contains(b, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// samePath reports whether two folder paths name the same folder. Folder
+// titles are compared like MergeFolders does, ignoring case and surrounding space.
+func samePath(a, b []string) bool {
+	return folderKey(a) == folderKey(b)
+}
+
+// folderKey returns the key of a folder path for comparisons
+func folderKey(path []string) string {
+	parts := make([]string, len(path))
+	for i, title := range path {
+		parts[i] = strings.ToLower(strings.TrimSpace(title))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// indexBookmarks lists the bookmarks of a tree in order. The key of a
+// bookmark is the key of its URL, with a count appended for repeated URLs,
+// so that copies of a URL are matched in order.
+func indexBookmarks(root *Folder, urlKey func(url string) string) []*bookmarkEntry {
+	var entries []*bookmarkEntry
+	count := make(map[string]int)
+	var walk func(folder *Folder, path []string)
+	walk = func(folder *Folder, path []string) {
+		for _, child := range folder.Children {
+			if child.IsFolder() {
+				subfolder := child.(*Folder)
+				walk(subfolder, append(path[:len(path):len(path)], subfolder.Title))
+				continue
+			}
+			bookmark, ok := child.(*Bookmark)
+			if !ok {
+				continue
+			}
+			key := urlKey(bookmark.URL)
+			if n := count[key]; n > 0 {
+				count[key]++
+				key = fmt.Sprintf("%s\x00%d", key, n)
+			} else {
+				count[key] = 1
+			}
+			entries = append(entries, &bookmarkEntry{bookmark: bookmark, folder: folder, path: path, key: key})
+		}
+	}
+	walk(root, nil)
+	return entries
+}
+
+// matchRecords matches the bookmarks of the three trees: by ID first, then
+// by key, then bookmarks missing on one side by title and folder (their URL
+// was changed)
+func matchRecords(base, ours, theirs []*bookmarkEntry) []mergeRecord {
+	ourKeys := entryMap(ours)
+	theirKeys := entryMap(theirs)
+	ourIDs := entryIDMap(ours)
+	theirIDs := entryIDMap(theirs)
+	usedOurs := make(map[*bookmarkEntry]bool)
+	usedTheirs := make(map[*bookmarkEntry]bool)
+
+	// claim returns the unused entry with the given key in m and marks it used
+	claim := func(m map[string]*bookmarkEntry, key string, used map[*bookmarkEntry]bool) *bookmarkEntry {
+		if e := m[key]; key != "" && e != nil && !used[e] {
+			used[e] = true
+			return e
+		}
+		return nil
+	}
+
+	records := make([]mergeRecord, 0, len(base))
+	for _, b := range base {
+		records = append(records, mergeRecord{
+			base:   b,
+			ours:   claim(ourIDs, b.bookmark.ID, usedOurs),
+			theirs: claim(theirIDs, b.bookmark.ID, usedTheirs),
+		})
+	}
+	for i, r := range records {
+		if r.ours == nil {
+			records[i].ours = claim(ourKeys, r.base.key, usedOurs)
+		}
+		if r.theirs == nil {
+			records[i].theirs = claim(theirKeys, r.base.key, usedTheirs)
+		}
+	}
+
+	// A bookmark missing on one side may have a new URL there
+	baseKeys := entryMap(base)
+	renamed := func(b *bookmarkEntry, entries []*bookmarkEntry, used map[*bookmarkEntry]bool) *bookmarkEntry {
+		for _, e := range entries {
+			if !used[e] && baseKeys[e.key] == nil && e.bookmark.Title == b.bookmark.Title && samePath(e.path, b.path) {
+				used[e] = true
+				return e
+			}
+		}
+		return nil
+	}
+	for i := range records {
+		if records[i].ours == nil {
+			records[i].ours = renamed(records[i].base, ours, usedOurs)
+		}
+		if records[i].theirs == nil {
+			records[i].theirs = renamed(records[i].base, theirs, usedTheirs)
+		}
+	}
+
+	// New bookmarks, matched by ID or key if both sides added them
+	for _, o := range ours {
+		if usedOurs[o] {
+			continue
+		}
+		r := mergeRecord{ours: o, theirs: claim(theirIDs, o.bookmark.ID, usedTheirs)}
+		if r.theirs == nil {
+			r.theirs = claim(theirKeys, o.key, usedTheirs)
+		}
+		records = append(records, r)
+	}
+	for _, t := range theirs {
+		if !usedTheirs[t] {
+			records = append(records, mergeRecord{theirs: t})
+		}
+	}
+	return records
+}
+
+// entryMap maps the keys of entries to the entries
+func entryMap(entries []*bookmarkEntry) map[string]*bookmarkEntry {
+	m := make(map[string]*bookmarkEntry, len(entries))
+	for _, e := range entries {
+		m[e.key] = e
+	}
+	return m
+}
+
+// entryIDMap maps the IDs of entries to the entries, for those that have one
+func entryIDMap(entries []*bookmarkEntry) map[string]*bookmarkEntry {
+	m := make(map[string]*bookmarkEntry, len(entries))
+	for _, e := range entries {
+		if e.bookmark.ID != "" {
+			m[e.bookmark.ID] = e
+		}
+	}
+	return m
+}
+
+// usePaths replaces the folder paths of entries with the given ones
+func usePaths(entries []*bookmarkEntry, paths map[*Folder][]string) {
+	for _, e := range entries {
+		e.path = paths[e.folder]
+	}
+}
+
+// matchFolders matches the subfolders of a and b, and theirs recursively: by
+// ID, then by title, then a renamed folder by its bookmarks. Folders are only
+// matched below matching parents, so a moved folder doesn't match.
+func matchFolders(a, b *Folder, matches map[*Folder]*Folder) {
+	as, bs := subfolders(a), subfolders(b)
+	used := make(map[*Folder]bool)
+	match := func(same func(x, y *Folder) bool) {
+		for _, x := range as {
+			if matches[x] != nil {
+				continue
+			}
+			for _, y := range bs {
+				if !used[y] && same(x, y) {
+					matches[x] = y
+					used[y] = true
+					break
+				}
+			}
+		}
+	}
+	match(func(x, y *Folder) bool { return x.ID != "" && x.ID == y.ID })
+	match(func(x, y *Folder) bool { return titleKey(x.Title) == titleKey(y.Title) })
+	match(sameBookmarks)
+
+	for _, x := range as {
+		if y := matches[x]; y != nil {
+			matchFolders(x, y, matches)
+		}
+	}
+}
+
+// subfolders returns the folders directly below a folder
+func subfolders(folder *Folder) []*Folder {
+	var folders []*Folder
+	for _, child := range folder.Children {
+		if sub, ok := child.(*Folder); ok {
+			folders = append(folders, sub)
+		}
+	}
+	return folders
+}
+
+// sameBookmarks reports whether folder b holds at least half of the
+// bookmarks directly in folder a
+func sameBookmarks(a, b *Folder) bool {
+	keys := make(map[string]bool)
+	for _, child := range b.Children {
+		if bookmark, ok := child.(*Bookmark); ok {
+			keys[mergeKey(bookmark.URL)] = true
+		}
+	}
+	total, shared := 0, 0
+	for _, child := range a.Children {
+		if bookmark, ok := child.(*Bookmark); ok {
+			total++
+			if keys[mergeKey(bookmark.URL)] {
+				shared++
+			}
+		}
+	}
+	return total > 0 && 2*shared >= total
+}
+
+// mergePaths returns the paths of the folders below root as they are in the
+// result: a folder matched to a result folder gets its path, any other folder
+// its own title below its parent's path
+func mergePaths(root *Folder, inResult map[*Folder]*Folder, resultPaths map[*Folder][]string) map[*Folder][]string {
+	paths := map[*Folder][]string{root: nil}
+	var walk func(folder *Folder, path []string)
+	walk = func(folder *Folder, path []string) {
+		for _, sub := range subfolders(folder) {
+			subpath := append(path[:len(path):len(path)], sub.Title)
+			if o, ok := inResult[sub]; ok {
+				subpath = resultPaths[o]
+			}
+			paths[sub] = subpath
+			walk(sub, subpath)
+		}
+	}
+	walk(root, nil)
+	return paths
+}
+
+// orderedFolders returns all folders below root, in tree order
+func orderedFolders(root *Folder) []*Folder {
+	var folders []*Folder
+	for _, sub := range subfolders(root) {
+		folders = append(folders, sub)
+		folders = append(folders, orderedFolders(sub)...)
+	}
+	return folders
+}
+
+// findFolder returns the folder at path below root, or nil
+func findFolder(root *Folder, path []string) *Folder {
+	folder := root
+	for _, title := range path {
+		var next *Folder
+		for _, child := range folder.Children {
+			if sub, ok := child.(*Folder); ok && samePath([]string{sub.Title}, []string{title}) {
+				next = sub
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		folder = next
+	}
+	return folder
+}
+
+// ensureFolder returns the folder at path below root, creating missing folders
+func ensureFolder(root *Folder, path []string) *Folder {
+	folder := root
+	for i := range path {
+		next := findFolder(folder, path[i:i+1])
+		if next == nil {
+			next = &Folder{Title: path[i]}
+			folder.AddChild(next)
+		}
+		folder = next
+	}
+	return folder
+}
+
+// removeFolderIfEmpty removes the folder at path below root if it has no
+// bookmarks left in it (empty subfolders don't count)
+func removeFolderIfEmpty(root *Folder, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	parent := findFolder(root, path[:len(path)-1])
+	folder := findFolder(root, path)
+	if parent == nil || folder == nil {
+		return
+	}
+	hasBookmarks := false
+	Walk(folder, 0, func(node Node, _ int) {
+		if _, ok := node.(*Bookmark); ok {
+			hasBookmarks = true
+		}
+	})
+	if !hasBookmarks {
+		removeChild(parent, folder)
+	}
+}
+
+// removeChild removes a node from a folder's children
+func removeChild(folder *Folder, node Node) {
+	folder.Children = slices.DeleteFunc(folder.Children, func(child Node) bool {
+		return child == node
+	})
+}
+
+// cloneFolder returns a deep copy of a folder tree
+func cloneFolder(folder *Folder) *Folder {
+	clone := *folder
+	clone.Properties = maps.Clone(folder.Properties)
+	clone.Children = make([]Node, 0, len(folder.Children))
+	for _, child := range folder.Children {
+		switch node := child.(type) {
+		case *Folder:
+			clone.Children = append(clone.Children, cloneFolder(node))
+		case *Bookmark:
+			clone.Children = append(clone.Children, copyBookmark(node))
+		case *Separator:
+			separator := *node
+			clone.Children = append(clone.Children, &separator)
+		}
+	}
+	return &clone
+}
+
+// copyBookmark returns a copy of a bookmark
+func copyBookmark(bookmark *Bookmark) *Bookmark {
+	clone := *bookmark
+	clone.Tags = slices.Clone(bookmark.Tags)
+	clone.Properties = maps.Clone(bookmark.Properties)
+	return &clone
+}
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// syncBase returns the tree at the last sync, for the three-way merge tests
+func syncBase() *Folder {
+	return &Folder{
+		Title: "Bookmarks",
+		Children: []Node{
+			&Folder{
+				Title: "Dev",
+				Children: []Node{
+					&Bookmark{Title: "Go", URL: "https://go.dev/", Tags: []string{"go"}},
+					&Bookmark{Title: "Rust", URL: "https://rust-lang.org/"},
+					&Bookmark{Title: "Zig", URL: "https://ziglang.org/"},
+				},
+			},
+			&Folder{
+				Title: "News",
+				Children: []Node{
+					&Bookmark{Title: "HN", URL: "https://news.ycombinator.com/"},
+					&Bookmark{Title: "LWN", URL: "https://lwn.net/"},
+				},
+			},
+			&Folder{
+				Title: "Old",
+				Children: []Node{
+					&Bookmark{Title: "Gone", URL: "https://gone.example.com/"},
+				},
+			},
+		},
+	}
+}
+
+// findBookmark returns the bookmark with the given URL in a tree and its folder path
+func findBookmark(root *Folder, url string) (*Bookmark, []string) {
+	var found *Bookmark
+	var foundPath []string
+	var walk func(folder *Folder, path []string)
+	walk = func(folder *Folder, path []string) {
+		for _, child := range folder.Children {
+			if sub, ok := child.(*Folder); ok {
+				walk(sub, append(path[:len(path):len(path)], sub.Title))
+			} else if b := child.(*Bookmark); b.URL == url && found == nil {
+				found, foundPath = b, path
+			}
+		}
+	}
+	walk(root, nil)
+	return found, foundPath
+}
+
+func TestThreeWayMergeCombinesChanges(t *testing.T) {
+	base := syncBase()
+
+	// Ours: retitle Go and tag it, delete Rust, add a bookmark
+	ours := syncBase()
+	dev := ours.Children[0].(*Folder)
+	dev.Children[0].(*Bookmark).Title = "The Go Programming Language"
+	dev.Children[0].(*Bookmark).Tags = []string{"go", "lang"}
+	dev.Children = slices.Delete(dev.Children, 1, 2)
+	dev.AddChild(&Bookmark{Title: "Odin", URL: "https://odin-lang.org/"})
+
+	// Theirs: delete LWN, move Zig to News, change HN's URL, remove the go
+	// tag, add a folder, rename Old to Archive
+	theirs := syncBase()
+	dev = theirs.Children[0].(*Folder)
+	news := theirs.Children[1].(*Folder)
+	zig := dev.Children[2]
+	dev.Children = dev.Children[:2]
+	dev.Children[0].(*Bookmark).Tags = nil
+	news.Children = []Node{
+		&Bookmark{Title: "HN", URL: "https://news.ycombinator.com/news"},
+		zig,
+	}
+	theirs.Children[2].(*Folder).Title = "Archive"
+	theirs.AddChild(&Folder{Title: "Reading", Children: []Node{
+		&Bookmark{Title: "Book", URL: "https://book.example.com/"},
+	}})
+
+	result, conflicts := ThreeWayMerge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+
+	goBookmark, _ := findBookmark(result, "https://go.dev/")
+	if goBookmark == nil || goBookmark.Title != "The Go Programming Language" {
+		t.Errorf("Expected our title change to be kept")
+	} else if !slices.Equal(goBookmark.Tags, []string{"lang"}) {
+		t.Errorf("Expected tags [lang], got %v", goBookmark.Tags)
+	}
+
+	for _, url := range []string{"https://rust-lang.org/", "https://lwn.net/", "https://news.ycombinator.com/"} {
+		if b, _ := findBookmark(result, url); b != nil {
+			t.Errorf("Expected %s to be gone", url)
+		}
+	}
+	if b, _ := findBookmark(result, "https://news.ycombinator.com/news"); b == nil {
+		t.Errorf("Expected their URL change to be applied")
+	}
+	if _, path := findBookmark(result, "https://ziglang.org/"); !slices.Equal(path, []string{"News"}) {
+		t.Errorf("Expected Zig to be moved to News, got %v", path)
+	}
+	if _, path := findBookmark(result, "https://odin-lang.org/"); !slices.Equal(path, []string{"Dev"}) {
+		t.Errorf("Expected our new bookmark in Dev, got %v", path)
+	}
+	if _, path := findBookmark(result, "https://book.example.com/"); !slices.Equal(path, []string{"Reading"}) {
+		t.Errorf("Expected their new bookmark in Reading, got %v", path)
+	}
+	if _, path := findBookmark(result, "https://gone.example.com/"); !slices.Equal(path, []string{"Archive"}) {
+		t.Errorf("Expected the renamed folder to be followed, got %v", path)
+	}
+
+	// The old folder is gone
+	var titles []string
+	for _, child := range result.Children {
+		titles = append(titles, child.GetTitle())
+	}
+	if want := []string{"Dev", "News", "Archive", "Reading"}; !slices.Equal(titles, want) {
+		t.Errorf("Expected top-level folders %v, got %v", want, titles)
+	}
+
+	// The inputs are unchanged
+	if b, _ := findBookmark(ours, "https://go.dev/"); !slices.Equal(b.Tags, []string{"go", "lang"}) {
+		t.Errorf("Expected ours to be left unchanged")
+	}
+}
+
+func TestThreeWayMergeConflicts(t *testing.T) {
+	base := syncBase()
+
+	ours := syncBase()
+	ourDev := ours.Children[0].(*Folder)
+	ourDev.Children[0].(*Bookmark).Title = "Go (ours)"
+	ourDev.Children[2].(*Bookmark).Description = "Changed here"
+	ours.Children[1].(*Folder).Children = ours.Children[1].(*Folder).Children[1:] // Delete HN
+
+	theirs := syncBase()
+	theirDev := theirs.Children[0].(*Folder)
+	theirDev.Children[0].(*Bookmark).Title = "Go (theirs)"
+	theirDev.Children = theirDev.Children[:2] // Delete Zig
+	theirs.Children[1].(*Folder).Children[0].(*Bookmark).Title = "Hacker News"
+
+	result, conflicts := ThreeWayMerge(base, ours, theirs)
+	if len(conflicts) != 3 {
+		t.Fatalf("Expected 3 conflicts, got %d: %v", len(conflicts), conflicts)
+	}
+
+	// Both changed the title: ours wins
+	if conflicts[0].Field != "title" || conflicts[0].Ours != "Go (ours)" || conflicts[0].Theirs != "Go (theirs)" {
+		t.Errorf("Unexpected title conflict: %+v", conflicts[0])
+	}
+	if b, _ := findBookmark(result, "https://go.dev/"); b.Title != "Go (ours)" {
+		t.Errorf("Expected our title to be kept, got '%s'", b.Title)
+	}
+
+	// Changed in ours, deleted in theirs: kept
+	if conflicts[1].Field != "" || conflicts[1].Bookmark == nil || conflicts[1].URL != "https://ziglang.org/" {
+		t.Errorf("Unexpected deletion conflict: %+v", conflicts[1])
+	}
+	if b, _ := findBookmark(result, "https://ziglang.org/"); b == nil {
+		t.Errorf("Expected the bookmark changed in ours to be kept")
+	}
+
+	// Deleted in ours, changed in theirs: stays deleted
+	if conflicts[2].Field != "" || conflicts[2].Bookmark != nil || conflicts[2].URL != "https://news.ycombinator.com/" {
+		t.Errorf("Unexpected deletion conflict: %+v", conflicts[2])
+	}
+	if b, _ := findBookmark(result, "https://news.ycombinator.com/"); b != nil {
+		t.Errorf("Expected the bookmark deleted in ours to stay deleted")
+	}
+}
+
+func TestThreeWayMergeMatchesIDs(t *testing.T) {
+	withIDs := func() *Folder {
+		tree := syncBase()
+		dev := tree.Children[0].(*Folder)
+		dev.Children[0].(*Bookmark).ID = "go"
+		dev.Children[1].(*Bookmark).ID = "rust"
+		return tree
+	}
+	base := withIDs()
+	ours := withIDs()
+
+	// Theirs changed both the URL and the title of Go, and gave Rust's URL
+	// to a new bookmark
+	theirs := withIDs()
+	dev := theirs.Children[0].(*Folder)
+	goBookmark := dev.Children[0].(*Bookmark)
+	goBookmark.Title, goBookmark.URL = "Golang", "https://golang.org/"
+	rust := dev.Children[1].(*Bookmark)
+	rust.URL = "https://www.rust-lang.org/"
+	dev.AddChild(&Bookmark{ID: "other", Title: "Rust mirror", URL: "https://rust-lang.org/"})
+
+	result, conflicts := ThreeWayMerge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+	if b, _ := findBookmark(result, "https://golang.org/"); b == nil || b.Title != "Golang" || b.ID != "go" {
+		t.Errorf("Expected Go to be matched by ID and updated, got %+v", b)
+	}
+	if b, _ := findBookmark(result, "https://go.dev/"); b != nil {
+		t.Errorf("Expected no copy of Go under its old URL")
+	}
+	if b, _ := findBookmark(result, "https://www.rust-lang.org/"); b == nil || b.ID != "rust" {
+		t.Errorf("Expected Rust to follow its ID to the new URL, got %+v", b)
+	}
+	if b, _ := findBookmark(result, "https://rust-lang.org/"); b == nil || b.ID != "other" {
+		t.Errorf("Expected the new bookmark with Rust's old URL to be added, got %+v", b)
+	}
+}
+
+func TestThreeWayMergeRenamedFolders(t *testing.T) {
+	added := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tree := func() *Folder {
+		tree := syncBase()
+		dev := tree.Children[0].(*Folder)
+		dev.ID, dev.AddDate, dev.Role = "dev", added, RootToolbar
+		return tree
+	}
+	base := tree()
+
+	// Ours adds a bookmark to Dev, and both sides add the same new bookmark,
+	// spelled differently
+	ours := tree()
+	ours.Children[0].(*Folder).AddChild(&Bookmark{Title: "Odin", URL: "https://odin-lang.org/"})
+	ours.Children[1].(*Folder).AddChild(&Bookmark{Title: "Lobsters", URL: "https://Lobste.rs/"})
+
+	// Theirs renames Dev (with the same ID) and News (only its case), and
+	// moves Zig to the renamed News
+	theirs := tree()
+	dev := theirs.Children[0].(*Folder)
+	dev.Title = "Development"
+	zig := dev.Children[2]
+	dev.Children = dev.Children[:2]
+	news := theirs.Children[1].(*Folder)
+	news.Title = "news"
+	news.AddChild(zig)
+	news.AddChild(&Bookmark{Title: "Lobsters", URL: "https://lobste.rs/"})
+
+	result, conflicts := ThreeWayMerge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+
+	var titles []string
+	for _, child := range result.Children {
+		titles = append(titles, child.GetTitle())
+	}
+	if want := []string{"Development", "news", "Old"}; !slices.Equal(titles, want) {
+		t.Fatalf("Expected the folders renamed in place %v, got %v", want, titles)
+	}
+	renamed := result.Children[0].(*Folder)
+	if renamed.ID != "dev" || !renamed.AddDate.Equal(added) || renamed.Role != RootToolbar {
+		t.Errorf("Expected the renamed folder to keep its metadata, got %+v", renamed)
+	}
+	if _, path := findBookmark(result, "https://odin-lang.org/"); !slices.Equal(path, []string{"Development"}) {
+		t.Errorf("Expected our new bookmark in the renamed folder, got %v", path)
+	}
+	if _, path := findBookmark(result, "https://ziglang.org/"); !slices.Equal(path, []string{"news"}) {
+		t.Errorf("Expected Zig to be moved to news, got %v", path)
+	}
+	if len(result.Children[1].(*Folder).Children) != 4 {
+		t.Errorf("Expected the bookmark added on both sides once, got %v", result.Children[1].(*Folder).Children)
+	}
+}
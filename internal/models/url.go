@@ -0,0 +1,283 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// URLNormalization is how strictly bookmark URLs are compared when looking
+// for duplicates. Each level includes the rules of the levels before it.
+type URLNormalization int
+
+const (
+	// NormalizeNone compares URLs byte-for-byte
+	NormalizeNone URLNormalization = iota
+
+	// NormalizeBasic only rewrites what can't change the page: the case of
+	// the scheme and host, IDN hosts (to punycode), default ports, an empty
+	// path and percent-encoding of unreserved characters
+	NormalizeBasic
+
+	// NormalizeStandard also ignores fragments, trailing slashes, the order
+	// of query parameters and tracking parameters such as utm_source
+	NormalizeStandard
+
+	// NormalizeAggressive also treats http and https, and hosts with and
+	// without a www. prefix, as the same
+	NormalizeAggressive
+)
+
+// urlNormalizationNames are the names of the levels, as used on the command line
+var urlNormalizationNames = []string{"exact", "basic", "standard", "aggressive"}
+
+// String returns the name of the normalization level
+func (n URLNormalization) String() string {
+	if n < 0 || int(n) >= len(urlNormalizationNames) {
+		return fmt.Sprintf("URLNormalization(%d)", int(n))
+	}
+	return urlNormalizationNames[n]
+}
+
+// ParseURLNormalization returns the normalization level with the given name
+func ParseURLNormalization(name string) (URLNormalization, error) {
+	if i := slices.Index(urlNormalizationNames, strings.ToLower(strings.TrimSpace(name))); i >= 0 {
+		return URLNormalization(i), nil
+	}
+	return NormalizeNone, fmt.Errorf("unknown URL normalization %q (use %s)", name, strings.Join(urlNormalizationNames, ", "))
+}
+
+// defaultPorts are the ports that can be left out of URLs with these schemes
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
+// trackingParams are query parameters added for analytics, which don't
+// change the page. Parameters starting with trackingParamPrefixes are
+// removed as well.
+var trackingParams = map[string]bool{
+	"fbclid":      true,
+	"gclid":       true,
+	"gclsrc":      true,
+	"dclid":       true,
+	"msclkid":     true,
+	"yclid":       true,
+	"twclid":      true,
+	"igshid":      true,
+	"mc_cid":      true,
+	"mc_eid":      true,
+	"_ga":         true,
+	"_gl":         true,
+	"_hsenc":      true,
+	"_hsmi":       true,
+	"mkt_tok":     true,
+	"ref_src":     true,
+	"ref_url":     true,
+	"vero_id":     true,
+	"wickedid":    true,
+	"__s":         true,
+	"spm":         true,
+	"trk":         true,
+	"trkcampaign": true,
+}
+
+// trackingParamPrefixes are prefixes of tracking query parameter names
+var trackingParamPrefixes = []string{"utm_", "pk_", "hsa_", "oly_"}
+
+// NormalizeURL returns the canonical form of a URL at the given level, for
+// comparing bookmarks. URLs that can't be parsed, and URLs without a host
+// (like mailto: or javascript: links), are only trimmed of surrounding space.
+func NormalizeURL(rawURL string, level URLNormalization) string {
+	if level <= NormalizeNone {
+		return rawURL
+	}
+
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return rawURL
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := normalizeHost(u.Hostname())
+	port := u.Port()
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+	path := normalizePercentEncoding(u.EscapedPath())
+	query := u.RawQuery
+	fragment := u.EscapedFragment()
+
+	if level >= NormalizeStandard {
+		fragment = ""
+		query = normalizeQuery(query)
+		path = strings.TrimRight(path, "/")
+	} else {
+		query = normalizePercentEncoding(query)
+	}
+
+	if level >= NormalizeAggressive {
+		if scheme == "http" {
+			scheme = "https"
+			if port == defaultPorts["https"] {
+				port = ""
+			}
+		}
+		host = strings.TrimPrefix(host, "www.")
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	var b strings.Builder
+	if scheme != "" {
+		b.WriteString(scheme)
+		b.WriteString(":")
+	}
+	b.WriteString("//")
+	if u.User != nil {
+		b.WriteString(u.User.String())
+		b.WriteString("@")
+	}
+	if strings.Contains(host, ":") {
+		// IPv6 address
+		b.WriteString("[" + host + "]")
+	} else {
+		b.WriteString(host)
+	}
+	if port != "" {
+		b.WriteString(":" + port)
+	}
+	b.WriteString(path)
+	if query != "" {
+		b.WriteString("?" + query)
+	}
+	if fragment != "" {
+		b.WriteString("#" + normalizePercentEncoding(fragment))
+	}
+	return b.String()
+}
+
+// normalizeHost lowercases a host name, drops a trailing dot and converts
+// internationalized labels to punycode. Hosts that aren't valid IDNs are
+// only lowercased.
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if isASCII(host) {
+		return host
+	}
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// normalizeQuery removes tracking parameters and empty parameters from a raw
+// query string and sorts the rest by name. Values with the same name keep
+// their order.
+func normalizeQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+
+	type param struct{ name, raw string }
+	var params []param
+	for _, raw := range strings.Split(query, "&") {
+		if raw == "" {
+			continue
+		}
+		raw = normalizePercentEncoding(raw)
+		name, _, _ := strings.Cut(raw, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if isTrackingParam(strings.ToLower(name)) {
+			continue
+		}
+		params = append(params, param{name, raw})
+	}
+
+	slices.SortStableFunc(params, func(a, b param) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p.raw
+	}
+	return strings.Join(parts, "&")
+}
+
+// isTrackingParam reports whether a lowercase query parameter name is used
+// for tracking
+func isTrackingParam(name string) bool {
+	if trackingParams[name] {
+		return true
+	}
+	for _, prefix := range trackingParamPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePercentEncoding decodes percent-encoded unreserved characters
+// (letters, digits, "-", ".", "_" and "~") and uppercases the hex digits of
+// the remaining escapes
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(s[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
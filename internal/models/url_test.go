@@ -0,0 +1,110 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		level URLNormalization
+		want  string
+	}{
+		// Exact comparison leaves URLs alone
+		{"HTTP://Example.com", NormalizeNone, "HTTP://Example.com"},
+
+		// Basic
+		{"HTTP://Example.COM", NormalizeBasic, "http://example.com/"},
+		{"https://example.com:443/a", NormalizeBasic, "https://example.com/a"},
+		{"http://example.com:8080/a", NormalizeBasic, "http://example.com:8080/a"},
+		{"https://example.com/%7euser/%2fdocs%3f", NormalizeBasic, "https://example.com/~user/%2Fdocs%3F"},
+		{"https://münchen.de/", NormalizeBasic, "https://xn--mnchen-3ya.de/"},
+		{"https://bücher.example./", NormalizeBasic, "https://xn--bcher-kva.example/"},
+		{"https://MÜNCHEN.de/", NormalizeBasic, "https://xn--mnchen-3ya.de/"},
+		{"https://example.com/a/#top", NormalizeBasic, "https://example.com/a/#top"},
+		{"https://example.com/?b=2&a=1", NormalizeBasic, "https://example.com/?b=2&a=1"},
+		{"https://[::1]:443/", NormalizeBasic, "https://[::1]/"},
+		{"mailto:someone@example.com", NormalizeBasic, "mailto:someone@example.com"},
+
+		// Standard
+		{"https://example.com/a/#top", NormalizeStandard, "https://example.com/a"},
+		{"https://example.com/#top", NormalizeStandard, "https://example.com/"},
+		{"https://example.com/?b=2&a=1&b=1", NormalizeStandard, "https://example.com/?a=1&b=2&b=1"},
+		{"https://example.com/?utm_source=foo&utm_medium=x&fbclid=1", NormalizeStandard, "https://example.com/"},
+		{"https://example.com/p?id=7&UTM_CAMPAIGN=z&&", NormalizeStandard, "https://example.com/p?id=7"},
+		{"http://www.example.com/", NormalizeStandard, "http://www.example.com/"},
+
+		// Aggressive
+		{"http://www.example.com/", NormalizeAggressive, "https://example.com/"},
+		{"http://example.com:443/", NormalizeAggressive, "https://example.com/"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.url, tt.level); got != tt.want {
+			t.Errorf("NormalizeURL(%q, %s) = %q, want %q", tt.url, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestParseURLNormalization(t *testing.T) {
+	for _, level := range []URLNormalization{NormalizeNone, NormalizeBasic, NormalizeStandard, NormalizeAggressive} {
+		parsed, err := ParseURLNormalization(level.String())
+		if err != nil || parsed != level {
+			t.Errorf("ParseURLNormalization(%q) = %v, %v; want %v", level.String(), parsed, err, level)
+		}
+	}
+	if _, err := ParseURLNormalization("loose"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestDeduplicateWithURLNormalization(t *testing.T) {
+	newTree := func() *Folder {
+		return &Folder{
+			Title: "Root",
+			Children: []Node{
+				&Bookmark{Title: "Plain", URL: "http://x.com/"},
+				&Folder{
+					Title: "Sub",
+					Children: []Node{
+						&Bookmark{Title: "WWW", URL: "https://www.x.com"},
+						&Bookmark{Title: "Fragment", URL: "https://x.com/#top"},
+						&Bookmark{Title: "Tracking", URL: "https://x.com/?utm_source=foo"},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		level URLNormalization
+		want  int
+	}{
+		{NormalizeNone, 4},
+		{NormalizeBasic, 4},
+		{NormalizeStandard, 3},
+		{NormalizeAggressive, 1},
+	}
+
+	for _, tt := range tests {
+		root := newTree()
+		DeduplicateWithOptions(root, DeduplicateOptions{URLs: tt.level})
+
+		count := 0
+		Walk(root, 0, func(node Node, depth int) {
+			if !node.IsFolder() {
+				count++
+			}
+		})
+		if count != tt.want {
+			t.Errorf("With %s URLs, expected %d bookmarks, got %d", tt.level, tt.want, count)
+		}
+	}
+
+	// The first occurrence is kept
+	root := newTree()
+	DeduplicateWithOptions(root, DeduplicateOptions{URLs: NormalizeAggressive})
+	if root.Children[0].GetTitle() != "Plain" {
+		t.Errorf("Expected first bookmark to be kept, got '%s'", root.Children[0].GetTitle())
+	}
+}
@@ -0,0 +1,217 @@
+// Package mozlz4 reads and writes Mozilla's "mozlz4" container format,
+// used by Firefox for compressed bookmark backups (*.jsonlz4).
+//
+// A mozlz4 file is the magic "mozLz40\0", the uncompressed size as a
+// little-endian uint32, and a single raw LZ4 block (without LZ4 frame headers).
+package mozlz4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Magic is the header that starts every mozlz4 file
+var Magic = []byte("mozLz40\x00")
+
+// headerSize is the size of the magic plus the uncompressed size field
+const headerSize = 12
+
+// maxExpansion is how much larger than its compressed size an LZ4 block can
+// get: every byte of a match length adds at most 255 bytes of output
+const maxExpansion = 255
+
+// ErrInvalidMagic is returned when data doesn't start with the mozlz4 magic
+var ErrInvalidMagic = errors.New("mozlz4: invalid magic")
+
+// IsMozLz4 reports whether data starts with the mozlz4 magic
+func IsMozLz4(data []byte) bool {
+	return bytes.HasPrefix(data, Magic)
+}
+
+// Decode decompresses a complete mozlz4 file
+func Decode(data []byte) ([]byte, error) {
+	if !IsMozLz4(data) {
+		return nil, ErrInvalidMagic
+	}
+	if len(data) < headerSize {
+		return nil, errors.New("mozlz4: truncated header")
+	}
+
+	// Check the size before allocating for it, so a corrupt header can't
+	// make us reserve gigabytes
+	size := binary.LittleEndian.Uint32(data[len(Magic):headerSize])
+	if uint64(size) > maxExpansion*uint64(len(data)-headerSize) {
+		return nil, fmt.Errorf("mozlz4: uncompressed size %d is too large for %d bytes of data", size, len(data)-headerSize)
+	}
+	return decompressBlock(data[headerSize:], int(size))
+}
+
+// Encode compresses data into a complete mozlz4 file
+func Encode(data []byte) []byte {
+	out := make([]byte, headerSize, headerSize+len(data)/2+16)
+	copy(out, Magic)
+	binary.LittleEndian.PutUint32(out[len(Magic):], uint32(len(data)))
+	return compressBlock(out, data)
+}
+
+// decompressBlock decodes a raw LZ4 block whose uncompressed size is known
+func decompressBlock(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	i := 0
+
+	for i < len(src) {
+		token := src[i]
+		i++
+
+		// Literal length, with 255-byte continuation
+		litLen := int(token >> 4)
+		if litLen == 15 {
+			for {
+				if i >= len(src) {
+					return nil, errors.New("mozlz4: truncated literal length")
+				}
+				b := src[i]
+				i++
+				litLen += int(b)
+				if b != 255 {
+					break
+				}
+			}
+		}
+
+		if i+litLen > len(src) {
+			return nil, errors.New("mozlz4: truncated literals")
+		}
+		if len(dst)+litLen > size {
+			return nil, fmt.Errorf("mozlz4: data decompresses to more than %d bytes", size)
+		}
+		dst = append(dst, src[i:i+litLen]...)
+		i += litLen
+
+		// The last sequence only contains literals
+		if i == len(src) {
+			break
+		}
+
+		if i+2 > len(src) {
+			return nil, errors.New("mozlz4: truncated match offset")
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, fmt.Errorf("mozlz4: invalid match offset %d", offset)
+		}
+
+		// Match length, with 255-byte continuation
+		matchLen := int(token & 0x0f)
+		if matchLen == 15 {
+			for {
+				if i >= len(src) {
+					return nil, errors.New("mozlz4: truncated match length")
+				}
+				b := src[i]
+				i++
+				matchLen += int(b)
+				if b != 255 {
+					break
+				}
+			}
+		}
+		matchLen += minMatch
+		if len(dst)+matchLen > size {
+			return nil, fmt.Errorf("mozlz4: data decompresses to more than %d bytes", size)
+		}
+
+		// Copy byte by byte, since matches may overlap their own output
+		start := len(dst) - offset
+		for j := 0; j < matchLen; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, fmt.Errorf("mozlz4: decompressed %d bytes, expected %d", len(dst), size)
+	}
+	return dst, nil
+}
+
+const (
+	minMatch   = 4
+	hashLog    = 16
+	maxOffset  = 65535
+	lastLits   = 5  // The last 5 bytes of a block are always literals
+	matchLimit = 12 // No match may start within the last 12 bytes
+)
+
+// compressBlock appends src to dst as a raw LZ4 block using a simple greedy
+// hash-chain-free matcher. It favours simplicity over compression ratio.
+func compressBlock(dst, src []byte) []byte {
+	var table [1 << hashLog]int32
+	anchor := 0
+
+	if len(src) > matchLimit {
+		limit := len(src) - matchLimit
+		for i := 0; i < limit; {
+			seq := binary.LittleEndian.Uint32(src[i:])
+			h := (seq * 2654435761) >> (32 - hashLog)
+			ref := int(table[h]) - 1
+			table[h] = int32(i + 1)
+
+			if ref < 0 || i-ref > maxOffset || binary.LittleEndian.Uint32(src[ref:]) != seq {
+				i++
+				continue
+			}
+
+			// Extend the match as far as the end-of-block rules allow
+			matchLen := minMatch
+			for i+matchLen < len(src)-lastLits && src[ref+matchLen] == src[i+matchLen] {
+				matchLen++
+			}
+
+			dst = appendSequence(dst, src[anchor:i], i-ref, matchLen)
+			i += matchLen
+			anchor = i
+		}
+	}
+
+	// Remaining bytes are emitted as a literal-only sequence
+	return appendSequence(dst, src[anchor:], 0, 0)
+}
+
+// appendSequence writes one LZ4 sequence. A matchLen of 0 writes the final
+// literal-only sequence.
+func appendSequence(dst, literals []byte, offset, matchLen int) []byte {
+	litLen := len(literals)
+
+	token := byte(min(litLen, 15)) << 4
+	if matchLen > 0 {
+		token |= byte(min(matchLen-minMatch, 15))
+	}
+	dst = append(dst, token)
+
+	if litLen >= 15 {
+		dst = appendLength(dst, litLen-15)
+	}
+	dst = append(dst, literals...)
+
+	if matchLen == 0 {
+		return dst
+	}
+
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(offset))
+	if matchLen-minMatch >= 15 {
+		dst = appendLength(dst, matchLen-minMatch-15)
+	}
+	return dst
+}
+
+// appendLength writes the 255-byte continuation of a literal or match length
+func appendLength(dst []byte, n int) []byte {
+	for n >= 255 {
+		dst = append(dst, 255)
+		n -= 255
+	}
+	return append(dst, byte(n))
+}
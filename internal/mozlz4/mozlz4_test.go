@@ -0,0 +1,96 @@
+package mozlz4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDecodeKnownBlock(t *testing.T) {
+	// "hello " as literals, a 24 byte overlapping match at offset 6, then "world"
+	block := []byte{0x6f}
+	block = append(block, "hello "...)
+	block = append(block, 0x06, 0x00, 0x05)
+	block = append(block, 0x50)
+	block = append(block, "world"...)
+
+	data := append([]byte{}, Magic...)
+	data = append(data, 35, 0, 0, 0)
+	data = append(data, block...)
+
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	expected := "hello hello hello hello hello world"
+	if string(decoded) != expected {
+		t.Errorf("Expected %q, got %q", expected, decoded)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	inputs := map[string][]byte{
+		"empty":      {},
+		"short":      []byte("abc"),
+		"repetitive": []byte(strings.Repeat(`{"guid":"abcdefghijkl","title":"Bookmark","type":"text/x-moz-place"},`, 2000)),
+		"random":     random,
+		"long run":   bytes.Repeat([]byte{'x'}, 70000),
+	}
+
+	for name, input := range inputs {
+		encoded := Encode(input)
+		if !IsMozLz4(encoded) {
+			t.Errorf("%s: encoded data is missing the magic", name)
+		}
+
+		decoded, err := Decode(encoded)
+		if err != nil {
+			t.Errorf("%s: failed to decode: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(decoded, input) {
+			t.Errorf("%s: round-trip mismatch (got %d bytes, want %d)", name, len(decoded), len(input))
+		}
+	}
+
+	// Repetitive input should actually compress
+	repetitive := inputs["repetitive"]
+	if encoded := Encode(repetitive); len(encoded) > len(repetitive)/10 {
+		t.Errorf("Expected repetitive input to compress well, got %d of %d bytes", len(encoded), len(repetitive))
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	if _, err := Decode([]byte("not mozlz4 data")); err != ErrInvalidMagic {
+		t.Errorf("Expected ErrInvalidMagic, got %v", err)
+	}
+
+	// Match offset pointing before the start of the output
+	data := append([]byte{}, Magic...)
+	data = append(data, 8, 0, 0, 0, 0x10, 'a', 0x09, 0x00)
+	if _, err := Decode(data); err == nil {
+		t.Error("Expected error for invalid match offset")
+	}
+}
+
+func TestDecodeSizeLimit(t *testing.T) {
+	// A header claiming 4 GiB for a few bytes of data is rejected up front
+	data := append([]byte{}, Magic...)
+	data = append(data, 0xff, 0xff, 0xff, 0xff, 0x10, 'a')
+	if _, err := Decode(data); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("Expected error for oversized header, got %v", err)
+	}
+
+	// Output beyond the size in the header is rejected too
+	encoded := Encode(bytes.Repeat([]byte("abcd"), 100))
+	binary.LittleEndian.PutUint32(encoded[len(Magic):], 10)
+	if _, err := Decode(encoded); err == nil {
+		t.Error("Expected error for output larger than the header size")
+	}
+}
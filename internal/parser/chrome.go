@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// webkitEpochOffset is the number of microseconds between the WebKit epoch
+// (1601-01-01 UTC), which Chromium uses for timestamps, and the Unix epoch
+const webkitEpochOffset = 11644473600000000
+
+// chromeFile is the top level of a Chromium "Bookmarks" profile file
+type chromeFile struct {
+	Roots struct {
+		BookmarkBar *chromeNode `json:"bookmark_bar"`
+		Other       *chromeNode `json:"other"`
+		Synced      *chromeNode `json:"synced"`
+	} `json:"roots"`
+}
+
+// chromeNode is a bookmark or folder in a Chromium bookmarks file
+type chromeNode struct {
+	GUID         string        `json:"guid"`
+	Name         string        `json:"name"`
+	Type         string        `json:"type"`
+	URL          string        `json:"url"`
+	DateAdded    string        `json:"date_added"`
+	DateModified string        `json:"date_modified"`
+	Children     []*chromeNode `json:"children"`
+}
+
+// ChromeParser parses Chrome/Chromium "Bookmarks" profile files
+type ChromeParser struct {
+	reader io.Reader
+}
+
+// NewChromeParser creates a new Chromium bookmarks parser from a reader
+func NewChromeParser(r io.Reader) *ChromeParser {
+	return &ChromeParser{
+		reader: r,
+	}
+}
+
+// Parse reads the Chromium bookmarks file and returns the root folder.
+// Like Chrome's HTML export, the bookmarks bar becomes a folder and the
+// contents of "Other bookmarks" become the top level of the tree.
+// Mobile bookmarks, if any, are kept in their own folder.
+func (p *ChromeParser) Parse() (*models.Folder, error) {
+	var file chromeFile
+	if err := json.NewDecoder(p.reader).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid Chrome bookmarks JSON: %w", err)
+	}
+
+	if file.Roots.BookmarkBar == nil && file.Roots.Other == nil {
+		return nil, fmt.Errorf("invalid Chrome bookmarks JSON: no bookmark roots")
+	}
+
+	root := &models.Folder{
+		Title: "Bookmarks",
+	}
+
+	if bar := file.Roots.BookmarkBar; bar != nil {
+		folder := chromeFolder(bar)
+		folder.Role = models.RootToolbar
+		// Chromium's permanent folders have the same GUID in every profile,
+		// which the writer sets again from the role
+		folder.ID = ""
+		root.AddChild(folder)
+	}
+
+	if other := file.Roots.Other; other != nil {
+		root.AddDate = chromeTime(other.DateAdded)
+		root.LastModified = chromeTime(other.DateModified)
+		for _, child := range other.Children {
+			addChromeNode(root, child)
+		}
+	}
+
+	if synced := file.Roots.Synced; synced != nil && len(synced.Children) > 0 {
+		folder := chromeFolder(synced)
+		folder.Role = models.RootMobile
+		folder.ID = ""
+		root.AddChild(folder)
+	}
+
+	return root, nil
+}
+
+// addChromeNode converts a node and adds it to the parent folder
+func addChromeNode(parent *models.Folder, node *chromeNode) {
+	switch node.Type {
+	case "folder":
+		parent.AddChild(chromeFolder(node))
+	case "url":
+		if node.URL == "" {
+			return
+		}
+		parent.AddChild(&models.Bookmark{
+			ID:           node.GUID,
+			URL:          node.URL,
+			Title:        node.Name,
+			AddDate:      chromeTime(node.DateAdded),
+			LastModified: chromeTime(node.DateModified),
+		})
+	}
+}
+
+// chromeFolder converts a folder node and its children
+func chromeFolder(node *chromeNode) *models.Folder {
+	folder := &models.Folder{
+		ID:           node.GUID,
+		Title:        node.Name,
+		AddDate:      chromeTime(node.DateAdded),
+		LastModified: chromeTime(node.DateModified),
+	}
+
+	for _, child := range node.Children {
+		addChromeNode(folder, child)
+	}
+
+	return folder
+}
+
+// chromeTime converts a Chromium timestamp (a string holding microseconds
+// since 1601-01-01 UTC)
+func chromeTime(value string) time.Time {
+	us, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || us == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(us - webkitEpochOffset)
+}
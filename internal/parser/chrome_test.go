@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParseChrome(t *testing.T) {
+	file, err := os.Open("../../test/testdata/Bookmarks")
+	if err != nil {
+		t.Fatalf("Failed to open Chrome bookmarks file: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewChromeParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Chrome bookmarks: %v", err)
+	}
+
+	// Bookmarks bar as a folder, then the contents of "Other bookmarks".
+	// The empty mobile root is skipped.
+	expectedTitles := []string{"Bookmarks bar", "GNU", "Empty"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+
+	bar := root.Children[0].(*models.Folder)
+	if bar.ID != "" {
+		t.Errorf("Root folders should not keep their GUIDs, got %q", bar.ID)
+	}
+	if bar.Role != models.RootToolbar {
+		t.Errorf("Expected the bookmarks bar to have the toolbar role, got %q", bar.Role)
+	}
+
+	docs := bar.Children[0].(*models.Bookmark)
+	if docs.URL != "https://go.dev/doc/" {
+		t.Errorf("Unexpected URL: %s", docs.URL)
+	}
+	// 13347158791000000 µs since 1601 is 1702685191 s since 1970
+	if docs.AddDate.Unix() != 1702685191 {
+		t.Errorf("Unexpected add date: %v", docs.AddDate)
+	}
+	if docs.ID != "5b1f3a0c-6d2e-4c8b-9f7a-1e2d3c4b5a69" {
+		t.Errorf("Expected GUID to be kept as the ID, got %q", docs.ID)
+	}
+
+	projects := bar.Children[1].(*models.Folder)
+	if projects.LastModified.Unix() != 1702685199 {
+		t.Errorf("Unexpected folder modification date: %v", projects.LastModified)
+	}
+	unicode := projects.Children[1].(*models.Bookmark)
+	if unicode.Title != "Ünïcödé & <Tags>" || unicode.URL != "https://example.com/?a=1&b=2" {
+		t.Errorf("Unexpected bookmark: %q %q", unicode.Title, unicode.URL)
+	}
+}
+
+func TestParseChromeInvalid(t *testing.T) {
+	for _, input := range []string{"{not json", `{"roots": {}}`} {
+		parser := NewChromeParser(strings.NewReader(input))
+		if _, err := parser.Parse(); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// CSV columns, in the order orgmarks writes them
+const (
+	csvFolder = iota
+	csvTitle
+	csvURL
+	csvTags
+	csvShortcut
+	csvDescription
+	csvAddDate
+	csvLastModified
+	csvID
+	csvColumnCount
+)
+
+// csvHeaderNames maps (lowercase) header names to columns. Besides the names
+// orgmarks writes, a few common alternatives are accepted for hand-made sheets.
+var csvHeaderNames = map[string]int{
+	"folder":        csvFolder,
+	"path":          csvFolder,
+	"title":         csvTitle,
+	"name":          csvTitle,
+	"url":           csvURL,
+	"link":          csvURL,
+	"tags":          csvTags,
+	"shortcut":      csvShortcut,
+	"keyword":       csvShortcut,
+	"description":   csvDescription,
+	"note":          csvDescription,
+	"notes":         csvDescription,
+	"add_date":      csvAddDate,
+	"added":         csvAddDate,
+	"created":       csvAddDate,
+	"last_modified": csvLastModified,
+	"modified":      csvLastModified,
+	"id":            csvID,
+}
+
+// csvDateLayouts are the date formats accepted besides RFC 3339 and Unix
+// timestamps, since spreadsheets like to reformat dates
+var csvDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// csvPathEscaper escapes folder titles for use in a folder path
+var csvPathEscaper = strings.NewReplacer(`\`, `\\`, "/", `\/`)
+
+// CSVParser parses bookmark spreadsheets with one bookmark per row
+type CSVParser struct {
+	reader io.Reader
+}
+
+// NewCSVParser creates a new CSV parser from a reader
+func NewCSVParser(r io.Reader) *CSVParser {
+	return &CSVParser{
+		reader: r,
+	}
+}
+
+// Parse reads the CSV rows and returns the root folder.
+// The columns are folder path, title, url, tags, shortcut, description, add
+// date, last modified and ID. If the first row is a header, columns are matched by
+// name instead. Folder paths like "Linux/News" are rebuilt into nested folders,
+// and rows with a folder path but no URL create (possibly empty) folders.
+func (p *CSVParser) Parse() (*models.Folder, error) {
+	br := bufio.NewReader(p.reader)
+	// Spreadsheet programs often start UTF-8 files with a byte order mark
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	root := &models.Folder{
+		Title: "Bookmarks",
+	}
+
+	columns := []int{csvFolder, csvTitle, csvURL, csvTags, csvShortcut, csvDescription, csvAddDate, csvLastModified, csvID}
+	if len(records) > 0 {
+		if header, ok := csvHeader(records[0]); ok {
+			columns = header
+			records = records[1:]
+		}
+	}
+
+	folders := map[string]*models.Folder{"": root}
+
+	for _, record := range records {
+		var fields [csvColumnCount]string
+		for i, value := range record {
+			if i < len(columns) && columns[i] >= 0 {
+				fields[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+
+		folder := csvFolderForPath(folders, fields[csvFolder])
+		if fields[csvURL] == "" {
+			// A folder row can carry the folder's ID
+			if folder != root && folder.ID == "" {
+				folder.ID = fields[csvID]
+			}
+			continue
+		}
+
+		bookmark := &models.Bookmark{
+			ID:           fields[csvID],
+			URL:          fields[csvURL],
+			Title:        fields[csvTitle],
+			ShortcutURL:  fields[csvShortcut],
+			AddDate:      csvTime(fields[csvAddDate]),
+			LastModified: csvTime(fields[csvLastModified]),
+			Description:  fields[csvDescription],
+		}
+		if bookmark.Title == "" {
+			bookmark.Title = bookmark.URL
+		}
+		for _, tag := range strings.Split(fields[csvTags], ",") {
+			if trimmed := strings.TrimSpace(tag); trimmed != "" {
+				bookmark.Tags = append(bookmark.Tags, trimmed)
+			}
+		}
+
+		folder.AddChild(bookmark)
+	}
+
+	return root, nil
+}
+
+// csvHeader maps the columns of a header row, or returns false if the row
+// isn't a header. Unknown columns are mapped to -1 and ignored.
+func csvHeader(record []string) ([]int, bool) {
+	columns := make([]int, len(record))
+	hasURL := false
+	for i, name := range record {
+		column, ok := csvHeaderNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			columns[i] = -1
+			continue
+		}
+		columns[i] = column
+		hasURL = hasURL || column == csvURL
+	}
+	return columns, hasURL
+}
+
+// csvFolderForPath returns the folder for a path, creating it and any missing
+// parent folders. Folders are separated by "/"; a "\/" is a slash in a title.
+func csvFolderForPath(folders map[string]*models.Folder, path string) *models.Folder {
+	if folder, ok := folders[path]; ok {
+		return folder
+	}
+
+	parent := folders[""]
+	key := ""
+	for i, title := range splitCSVPath(path) {
+		if i > 0 {
+			key += "/"
+		}
+		key += csvPathEscaper.Replace(title)
+
+		folder, ok := folders[key]
+		if !ok {
+			folder = &models.Folder{Title: title}
+			parent.AddChild(folder)
+			folders[key] = folder
+		}
+		parent = folder
+	}
+
+	folders[path] = parent
+	return parent
+}
+
+// splitCSVPath splits a folder path into folder titles, unescaping "\/" and "\\"
+func splitCSVPath(path string) []string {
+	var titles []string
+	var title strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && (path[i+1] == '/' || path[i+1] == '\\'):
+			i++
+			title.WriteByte(path[i])
+		case path[i] == '/':
+			titles = append(titles, title.String())
+			title.Reset()
+		default:
+			title.WriteByte(path[i])
+		}
+	}
+	titles = append(titles, title.String())
+
+	// Ignore empty segments from leading, trailing or doubled slashes
+	filtered := titles[:0]
+	for _, t := range titles {
+		if trimmed := strings.TrimSpace(t); trimmed != "" {
+			filtered = append(filtered, trimmed)
+		}
+	}
+	return filtered
+}
+
+// csvTime parses a date column
+func csvTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	if t, ok := parseTimestamp(value); ok {
+		return t
+	}
+	for _, layout := range csvDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
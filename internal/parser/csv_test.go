@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParseCSV(t *testing.T) {
+	file, err := os.Open("../../test/testdata/bookmarks.csv")
+	if err != nil {
+		t.Fatalf("Failed to open CSV file: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewCSVParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	expectedTitles := []string{"Org Mode", "Linux", "Music"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+
+	// Columns are matched by header name, in any order
+	org := root.Children[0].(*models.Bookmark)
+	if org.URL != "https://orgmode.org/" || org.Description != "Your life in plain text" {
+		t.Errorf("Unexpected bookmark: %+v", org)
+	}
+	if strings.Join(org.Tags, ",") != "emacs,org" {
+		t.Errorf("Expected tags [emacs org], got %v", org.Tags)
+	}
+
+	// Equivalent paths end up in the same folder
+	linux := root.Children[1].(*models.Folder)
+	if len(linux.Children) != 2 {
+		t.Fatalf("Expected 2 children in Linux, got %d", len(linux.Children))
+	}
+	news := linux.Children[0].(*models.Folder)
+	if news.Title != "News" || len(news.Children) != 2 {
+		t.Fatalf("Expected News folder with 2 bookmarks, got %q with %d", news.Title, len(news.Children))
+	}
+	magazine := news.Children[0].(*models.Bookmark)
+	if magazine.Description != "Fedora news\nand tips" {
+		t.Errorf("Unexpected description: %q", magazine.Description)
+	}
+
+	// Rows without a URL create empty folders
+	empty := linux.Children[1].(*models.Folder)
+	if empty.Title != "Empty" || len(empty.Children) != 0 {
+		t.Errorf("Expected empty folder, got %q with %d children", empty.Title, len(empty.Children))
+	}
+
+	// Escaped slashes are part of the folder title
+	music := root.Children[2].(*models.Folder)
+	if acdc := music.Children[0].(*models.Folder); acdc.Title != "AC/DC" {
+		t.Errorf("Expected folder 'AC/DC', got %q", acdc.Title)
+	}
+}
+
+func TestParseCSVWithoutHeader(t *testing.T) {
+	input := "Tools,Go,https://go.dev/,golang,go,The Go website,2024-01-02T03:04:05Z,1704164645\n"
+	root, err := NewCSVParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	tools := root.Children[0].(*models.Folder)
+	bookmark := tools.Children[0].(*models.Bookmark)
+	if bookmark.Title != "Go" || bookmark.ShortcutURL != "go" || bookmark.Description != "The Go website" {
+		t.Errorf("Unexpected bookmark: %+v", bookmark)
+	}
+	if bookmark.AddDate.Unix() != 1704164645 || bookmark.LastModified.Unix() != 1704164645 {
+		t.Errorf("Unexpected dates: %v, %v", bookmark.AddDate, bookmark.LastModified)
+	}
+}
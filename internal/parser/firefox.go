@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+	"github.com/drewherron/orgmarks/internal/mozlz4"
+)
+
+// Firefox bookmark node types (the typeCode field)
+const (
+	firefoxTypeBookmark  = 1
+	firefoxTypeFolder    = 2
+	firefoxTypeSeparator = 3
+)
+
+// Firefox root folder GUIDs
+const (
+	firefoxMenuGUID    = "menu________"
+	firefoxToolbarGUID = "toolbar_____"
+	firefoxTagsGUID    = "tags________"
+	firefoxUnfiledGUID = "unfiled_____"
+	firefoxMobileGUID  = "mobile______"
+)
+
+// firefoxRootRoles maps Firefox root GUIDs to the roles of the folders they become
+var firefoxRootRoles = map[string]models.RootRole{
+	firefoxToolbarGUID: models.RootToolbar,
+	firefoxUnfiledGUID: models.RootOther,
+	firefoxMobileGUID:  models.RootMobile,
+}
+
+// firefoxRootTitles maps Firefox root GUIDs to the folder titles Firefox uses
+// in its HTML export, so JSON and HTML imports produce the same tree
+var firefoxRootTitles = map[string]string{
+	firefoxToolbarGUID: "Bookmarks Toolbar",
+	firefoxUnfiledGUID: "Other Bookmarks",
+	firefoxMobileGUID:  "Mobile Bookmarks",
+}
+
+// firefoxNode is a node in a Firefox JSON bookmark backup
+type firefoxNode struct {
+	GUID         string         `json:"guid"`
+	Title        string         `json:"title"`
+	DateAdded    int64          `json:"dateAdded"`
+	LastModified int64          `json:"lastModified"`
+	TypeCode     int            `json:"typeCode"`
+	URI          string         `json:"uri"`
+	IconURI      string         `json:"iconUri"`
+	Keyword      string         `json:"keyword"`
+	Tags         string         `json:"tags"`
+	Annos        []firefoxAnno  `json:"annos"`
+	Children     []*firefoxNode `json:"children"`
+}
+
+// firefoxAnno is an item annotation (older backups store descriptions here)
+type firefoxAnno struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// FirefoxParser parses Firefox bookmark backups (.json and .jsonlz4)
+type FirefoxParser struct {
+	reader io.Reader
+}
+
+// NewFirefoxParser creates a new Firefox backup parser from a reader.
+// Both plain JSON and mozlz4-compressed backups are accepted.
+func NewFirefoxParser(r io.Reader) *FirefoxParser {
+	return &FirefoxParser{
+		reader: r,
+	}
+}
+
+// Parse reads the Firefox backup and returns the root folder
+func (p *FirefoxParser) Parse() (*models.Folder, error) {
+	data, err := io.ReadAll(p.reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decompress .jsonlz4 backups
+	if mozlz4.IsMozLz4(data) {
+		data, err = mozlz4.Decode(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var placesRoot firefoxNode
+	if err := json.Unmarshal(data, &placesRoot); err != nil {
+		return nil, fmt.Errorf("invalid Firefox bookmarks JSON: %w", err)
+	}
+
+	return firefoxTree(&placesRoot), nil
+}
+
+// firefoxTree converts the places root into a bookmark tree.
+// The bookmarks menu becomes the top level of the tree, and the toolbar,
+// other and mobile roots become folders, matching Firefox's HTML export.
+func firefoxTree(placesRoot *firefoxNode) *models.Folder {
+	root := &models.Folder{
+		Title:        "Bookmarks",
+		AddDate:      firefoxTime(placesRoot.DateAdded),
+		LastModified: firefoxTime(placesRoot.LastModified),
+	}
+
+	// Menu contents first, like in the HTML export
+	for _, child := range placesRoot.Children {
+		if child.GUID == firefoxMenuGUID {
+			for _, node := range child.Children {
+				addFirefoxNode(root, node)
+			}
+		}
+	}
+
+	for _, child := range placesRoot.Children {
+		switch child.GUID {
+		case firefoxMenuGUID, firefoxTagsGUID:
+			// Menu was handled above, tags are stored on each bookmark
+			continue
+		case firefoxMobileGUID:
+			// Only include the mobile root if it's actually used
+			if len(child.Children) == 0 {
+				continue
+			}
+		}
+
+		folder := firefoxFolder(child)
+		if title, ok := firefoxRootTitles[child.GUID]; ok {
+			folder.Title = title
+			folder.Role = firefoxRootRoles[child.GUID]
+			// toolbar_____, menu________ and the other root GUIDs are the same
+			// in every profile; the role is enough to write them back
+			folder.ID = ""
+		}
+		root.AddChild(folder)
+	}
+
+	return root
+}
+
+// addFirefoxNode converts a node and adds it to the parent folder
+func addFirefoxNode(parent *models.Folder, node *firefoxNode) {
+	switch node.TypeCode {
+	case firefoxTypeFolder:
+		parent.AddChild(firefoxFolder(node))
+	case firefoxTypeBookmark:
+		// Skip Firefox place: URLs (dynamic queries), like the HTML parser
+		if node.URI == "" || strings.HasPrefix(node.URI, "place:") {
+			return
+		}
+		parent.AddChild(firefoxBookmark(node))
+	case firefoxTypeSeparator:
+		parent.AddChild(&models.Separator{ID: node.GUID})
+	}
+}
+
+// firefoxFolder converts a folder node and its children
+func firefoxFolder(node *firefoxNode) *models.Folder {
+	folder := &models.Folder{
+		ID:           node.GUID,
+		Title:        node.Title,
+		AddDate:      firefoxTime(node.DateAdded),
+		LastModified: firefoxTime(node.LastModified),
+	}
+
+	for _, child := range node.Children {
+		addFirefoxNode(folder, child)
+	}
+
+	return folder
+}
+
+// firefoxBookmark converts a bookmark node
+func firefoxBookmark(node *firefoxNode) *models.Bookmark {
+	bookmark := &models.Bookmark{
+		ID:           node.GUID,
+		URL:          node.URI,
+		Title:        node.Title,
+		ShortcutURL:  node.Keyword,
+		AddDate:      firefoxTime(node.DateAdded),
+		LastModified: firefoxTime(node.LastModified),
+		IconURI:      node.IconURI,
+	}
+
+	// Parse comma-separated tags
+	for _, tag := range strings.Split(node.Tags, ",") {
+		if trimmed := strings.TrimSpace(tag); trimmed != "" {
+			bookmark.Tags = append(bookmark.Tags, trimmed)
+		}
+	}
+
+	// Older backups store descriptions as annotations
+	for _, anno := range node.Annos {
+		if anno.Name == "bookmarkProperties/description" {
+			if description, ok := anno.Value.(string); ok {
+				bookmark.Description = description
+			}
+		}
+	}
+
+	return bookmark
+}
+
+// firefoxTime converts a Firefox timestamp (microseconds since the epoch)
+func firefoxTime(us int64) time.Time {
+	if us == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(us)
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+	"github.com/drewherron/orgmarks/internal/mozlz4"
+)
+
+func TestParseFirefoxJSON(t *testing.T) {
+	file, err := os.Open("../../test/testdata/bookmarks.json")
+	if err != nil {
+		t.Fatalf("Failed to open Firefox JSON file: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewFirefoxParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Firefox JSON: %v", err)
+	}
+
+	// Menu contents at the top level, then toolbar and other bookmarks.
+	// The empty mobile root, tags root and place: query are skipped.
+	expectedTitles := []string{"Email", "", "Bookmarks Toolbar", "Other Bookmarks"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+
+	if separator, ok := root.Children[1].(*models.Separator); !ok || separator.ID != "sEp4r4t0r___" {
+		t.Errorf("Expected the separator with its GUID, got %#v", root.Children[1])
+	}
+
+	toolbar := root.Children[2].(*models.Folder)
+	if toolbar.ID != "" {
+		t.Errorf("Root folders should not keep their GUIDs, got %q", toolbar.ID)
+	}
+	if toolbar.Role != models.RootToolbar {
+		t.Errorf("Expected the toolbar role, got %q", toolbar.Role)
+	}
+	if other := root.Children[3].(*models.Folder); other.Role != models.RootOther {
+		t.Errorf("Expected the other bookmarks role, got %q", other.Role)
+	}
+
+	magazine := toolbar.Children[1].(*models.Bookmark)
+	if magazine.URL != "https://fedoramagazine.org/" {
+		t.Errorf("Unexpected URL: %s", magazine.URL)
+	}
+	if magazine.ShortcutURL != "magazine" {
+		t.Errorf("Expected keyword 'magazine', got '%s'", magazine.ShortcutURL)
+	}
+	if len(magazine.Tags) != 1 || magazine.Tags[0] != "news" {
+		t.Errorf("Expected tags [news], got %v", magazine.Tags)
+	}
+	if magazine.AddDate.Unix() != 1367341224 || magazine.LastModified.Unix() != 1503757786 {
+		t.Errorf("Unexpected timestamps: %v, %v", magazine.AddDate, magazine.LastModified)
+	}
+	if magazine.ID != "V2n0aJ7kP1sD" {
+		t.Errorf("Expected GUID to be kept as the ID, got %q", magazine.ID)
+	}
+
+	gmail := root.Children[0].(*models.Folder).Children[0].(*models.Bookmark)
+	if gmail.Description != "Personal email account" {
+		t.Errorf("Expected description from annotation, got '%s'", gmail.Description)
+	}
+	if gmail.IconURI != "https://mail.google.com/favicon.ico" {
+		t.Errorf("Expected icon URI, got '%s'", gmail.IconURI)
+	}
+}
+
+func TestParseFirefoxJSONLZ4(t *testing.T) {
+	data, err := os.ReadFile("../../test/testdata/bookmarks.json")
+	if err != nil {
+		t.Fatalf("Failed to read Firefox JSON file: %v", err)
+	}
+
+	parser := NewFirefoxParser(bytes.NewReader(mozlz4.Encode(data)))
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse compressed Firefox backup: %v", err)
+	}
+
+	if count := models.CountNodes(root); count != 11 {
+		t.Errorf("Expected 11 nodes, got %d", count)
+	}
+}
+
+func TestParseFirefoxInvalidJSON(t *testing.T) {
+	parser := NewFirefoxParser(strings.NewReader("{not json"))
+	if _, err := parser.Parse(); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
@@ -1,8 +1,17 @@
-// Package parser provides parsers for converting HTML and Org-mode files
+// Package parser provides parsers for converting bookmark files
 // into the internal bookmark tree representation.
 //
 // HTMLParser handles Netscape Bookmark format HTML files (used by Firefox and Chrome).
 // OrgParser handles Org-mode formatted bookmark files.
+// FirefoxParser handles Firefox JSON bookmark backups (.json and .jsonlz4).
+// ChromeParser handles Chrome/Chromium "Bookmarks" profile files.
+// XBELParser handles XBEL files (used by Floccus).
+// PlacesParser reads bookmarks from a Firefox places.sqlite database.
+// SafariParser handles Safari Bookmarks.plist files.
+// MarkdownParser handles Markdown link lists.
+// OPMLParser handles OPML outlines (used by RSS readers).
+// CSVParser handles bookmark spreadsheets in CSV format.
+// PinboardParser, RaindropParser and PocketParser handle exports from those services.
 package parser
 
 import (
@@ -114,6 +123,9 @@ func (p *HTMLParser) Parse() (*models.Folder, error) {
 				}
 				currentFolder := folderStack[len(folderStack)-1]
 				currentFolder.AddChild(bookmark)
+			case "hr":
+				// HR is a separator, as exported by Firefox
+				folderStack[len(folderStack)-1].AddChild(&models.Separator{})
 			}
 		case html.EndTagToken:
 			switch token.Data {
@@ -144,6 +156,26 @@ func parseFolder(token html.Token) *models.Folder {
 			if ts, err := strconv.ParseInt(attr.Val, 10, 64); err == nil {
 				folder.LastModified = time.Unix(ts, 0)
 			}
+		case "personal_toolbar_folder":
+			// Firefox, Chrome and Edge mark their toolbar
+			if attr.Val == "true" {
+				folder.Role = models.RootToolbar
+			}
+		case "unfiled_bookmarks_folder":
+			// Firefox marks its "Other Bookmarks"
+			if attr.Val == "true" {
+				folder.Role = models.RootOther
+			}
+		case "id":
+			// Safari marks its special lists by id, orgmarks writes IDs
+			switch attr.Val {
+			case "favorites_bar":
+				folder.Role = models.RootToolbar
+			case "com.apple.ReadingList":
+				folder.Role = models.RootReading
+			default:
+				folder.ID = attr.Val
+			}
 		}
 	}
 
@@ -180,8 +212,12 @@ func parseBookmark(token html.Token) *models.Bookmark {
 			}
 		case "shortcuturl":
 			bookmark.ShortcutURL = attr.Val
-		case "icon", "icon_uri":
-			// Skip icon data as per requirements
+		case "icon":
+			bookmark.Icon = attr.Val
+		case "icon_uri":
+			bookmark.IconURI = attr.Val
+		case "id":
+			bookmark.ID = attr.Val
 		}
 	}
 
@@ -308,3 +308,42 @@ func TestParseHTMLDeeplyNested(t *testing.T) {
 		t.Errorf("Expected max depth of at least 5, got %d", maxDepth)
 	}
 }
+
+func TestParseHTMLRootFolders(t *testing.T) {
+	html := `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><H3 PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><H3>Toolbar subfolder</H3>
+        <DL><p>
+        </DL><p>
+    </DL><p>
+    <DT><H3 UNFILED_BOOKMARKS_FOLDER="true">Other Bookmarks</H3>
+    <DL><p>
+    </DL><p>
+    <DT><H3 id="com.apple.ReadingList">Reading List</H3>
+    <DL><p>
+    </DL><p>
+    <DT><H3>Regular</H3>
+    <DL><p>
+    </DL><p>
+</DL><p>`
+
+	root, err := NewHTMLParser(strings.NewReader(html)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	expected := []models.RootRole{models.RootToolbar, models.RootOther, models.RootReading, models.RootNone}
+	if len(root.Children) != len(expected) {
+		t.Fatalf("Expected %d top-level folders, got %d", len(expected), len(root.Children))
+	}
+	for i, role := range expected {
+		if folder := root.Children[i].(*models.Folder); folder.Role != role {
+			t.Errorf("Expected '%s' to have role %q, got %q", folder.Title, role, folder.Role)
+		}
+	}
+	if sub := root.Children[0].(*models.Folder).Children[0].(*models.Folder); sub.Role != models.RootNone {
+		t.Errorf("Expected a regular subfolder to have no role, got %q", sub.Role)
+	}
+}
@@ -0,0 +1,351 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+var (
+	// markdownHeadingPattern matches ATX headings ("## Title")
+	markdownHeadingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+
+	// markdownListPattern matches bullet and numbered list items
+	markdownListPattern = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+(.*)$`)
+
+	// markdownFencePattern matches the start or end of a fenced code block
+	markdownFencePattern = regexp.MustCompile("^\\s*(```|~~~)")
+
+	// markdownTagPattern matches inline #tags, which must start with a letter
+	markdownTagPattern = regexp.MustCompile(`(?:^|\s)#(\p{L}[\p{L}\p{N}_\-]*)`)
+
+	// markdownImagePattern matches images inside link text, like badges
+	markdownImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+)
+
+// markdownSeparators are the separators commonly used between a link and its description
+var markdownSeparators = []string{"-", "–", "—", ":", "|"}
+
+// MarkdownParser parses Markdown link lists, like READMEs and "awesome" lists
+type MarkdownParser struct {
+	scanner *bufio.Scanner
+
+	// Tags extracts inline #tags from bookmark descriptions into Bookmark.Tags
+	Tags bool
+}
+
+// markdownItem is an open list item, which can hold nested items
+type markdownItem struct {
+	indent   int
+	folder   *models.Folder // Set for items without a link
+	parent   *models.Folder
+	attached bool
+}
+
+// NewMarkdownParser creates a new Markdown parser from a reader
+func NewMarkdownParser(r io.Reader) *MarkdownParser {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &MarkdownParser{
+		scanner: scanner,
+	}
+}
+
+// Parse reads the Markdown document and returns the root folder.
+// Headings become folders and list items with a link become bookmarks, with
+// the text after the link as description. List items without a link become
+// folders if they have nested items. A single level 1 heading at the top is
+// taken as the document title, not a folder.
+func (p *MarkdownParser) Parse() (*models.Folder, error) {
+	var lines []string
+	for p.scanner.Scan() {
+		lines = append(lines, p.scanner.Text())
+	}
+	if err := p.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	root := &models.Folder{
+		Title: "Bookmarks",
+	}
+
+	skipTitle := markdownHasTitle(lines)
+
+	// Headings nest by level, list items by indentation
+	type section struct {
+		level  int
+		folder *models.Folder
+	}
+	sections := []section{{0, root}}
+	var items []*markdownItem
+	var last *models.Bookmark
+	inFence := false
+
+	for _, line := range lines {
+		if markdownFencePattern.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			last = nil
+			continue
+		}
+
+		if match := markdownHeadingPattern.FindStringSubmatch(line); match != nil {
+			items, last = nil, nil
+			level := len(match[1])
+			if skipTitle && level == 1 {
+				skipTitle = false
+				continue
+			}
+
+			for len(sections) > 1 && sections[len(sections)-1].level >= level {
+				sections = sections[:len(sections)-1]
+			}
+			folder := &models.Folder{Title: markdownText(match[2])}
+			sections[len(sections)-1].folder.AddChild(folder)
+			sections = append(sections, section{level, folder})
+			continue
+		}
+
+		if match := markdownListPattern.FindStringSubmatch(line); match != nil {
+			indent := markdownIndent(match[1])
+			for len(items) > 0 && items[len(items)-1].indent >= indent {
+				items = items[:len(items)-1]
+			}
+
+			// Items nest inside the closest item without a link
+			parent := sections[len(sections)-1].folder
+			for i := len(items) - 1; i >= 0; i-- {
+				if items[i].folder != nil {
+					attachMarkdownItems(items[:i+1])
+					parent = items[i].folder
+					break
+				}
+			}
+
+			item := &markdownItem{indent: indent, parent: parent}
+			last = nil
+			if bookmark := p.parseBookmark(match[2]); bookmark != nil {
+				parent.AddChild(bookmark)
+				last = bookmark
+			} else if _, _, _, isLink := parseMarkdownLink(match[2]); !isLink {
+				// Only added once something is nested inside it. Items linking
+				// elsewhere (like table of contents entries) aren't folders.
+				item.folder = &models.Folder{Title: markdownFolderTitle(match[2])}
+			}
+			items = append(items, item)
+			continue
+		}
+
+		// Indented lines right after a bookmark continue its description
+		if last != nil && (line[0] == ' ' || line[0] == '\t') {
+			text := strings.TrimSpace(line)
+			if p.Tags {
+				text, last.Tags = extractMarkdownTags(text, last.Tags)
+			}
+			if text != "" {
+				if last.Description != "" {
+					last.Description += "\n"
+				}
+				last.Description += text
+			}
+			continue
+		}
+
+		// Other paragraphs aren't bookmarks
+		last = nil
+	}
+
+	return root, nil
+}
+
+// parseBookmark parses a list item's text as a bookmark, or returns nil if
+// it doesn't start with (or consist of) a link to a web page
+func (p *MarkdownParser) parseBookmark(text string) *models.Bookmark {
+	title, link, rest, ok := parseMarkdownLink(text)
+	if !ok {
+		return nil
+	}
+
+	// Skip anchors like table of contents entries and relative links
+	if parsed, err := url.Parse(link); err != nil || parsed.Scheme == "" {
+		return nil
+	}
+
+	bookmark := &models.Bookmark{
+		URL:   link,
+		Title: markdownText(title),
+	}
+	if bookmark.Title == "" {
+		bookmark.Title = link
+	}
+
+	description := strings.TrimSpace(rest)
+	for _, separator := range markdownSeparators {
+		if strings.HasPrefix(description, separator+" ") || description == separator {
+			description = strings.TrimSpace(description[len(separator):])
+			break
+		}
+	}
+	if p.Tags {
+		description, bookmark.Tags = extractMarkdownTags(description, nil)
+	}
+	bookmark.Description = description
+
+	return bookmark
+}
+
+// parseMarkdownLink finds the first [title](url) link in a list item. Only
+// bold or emphasis markers may come before it; text after it is returned as rest.
+func parseMarkdownLink(text string) (title, link, rest string, ok bool) {
+	start := strings.Index(text, "[")
+	if start < 0 || strings.Trim(text[:start], "*_ ") != "" {
+		return "", "", "", false
+	}
+
+	// Find the closing bracket, allowing nested brackets (badges)
+	depth := 0
+	end := -1
+	for i := start; i < len(text) && end < 0; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(text) || text[end+1] != '(' {
+		return "", "", "", false
+	}
+
+	// Find the closing parenthesis, allowing <url> and balanced parentheses
+	target := text[end+2:]
+	var closeParen int
+	if strings.HasPrefix(target, "<") {
+		gt := strings.Index(target, ">")
+		if gt < 0 || !strings.HasPrefix(target[gt+1:], ")") {
+			return "", "", "", false
+		}
+		link, closeParen = target[1:gt], gt+1
+	} else {
+		depth = 1
+		closeParen = -1
+		for i := 0; i < len(target) && closeParen < 0; i++ {
+			switch target[i] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					closeParen = i
+				}
+			}
+		}
+		if closeParen < 0 {
+			return "", "", "", false
+		}
+		link = target[:closeParen]
+		// Drop an optional link title: [text](url "title")
+		if space := strings.IndexAny(link, " \t"); space >= 0 {
+			link = link[:space]
+		}
+	}
+
+	rest = strings.TrimLeft(target[closeParen+1:], "*_")
+	return text[start+1 : end], strings.TrimSpace(link), rest, true
+}
+
+// markdownHasTitle reports whether the document has exactly one level 1
+// heading and it comes before all other headings
+func markdownHasTitle(lines []string) bool {
+	count := 0
+	first := true
+	titleFirst := false
+	inFence := false
+	for _, line := range lines {
+		if markdownFencePattern.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if match := markdownHeadingPattern.FindStringSubmatch(line); match != nil && !inFence {
+			if len(match[1]) == 1 {
+				count++
+				titleFirst = titleFirst || first
+			}
+			first = false
+		}
+	}
+	return count == 1 && titleFirst
+}
+
+// attachMarkdownItems adds folder items to their parents, now that they have content
+func attachMarkdownItems(items []*markdownItem) {
+	for _, item := range items {
+		if item.folder != nil && !item.attached {
+			item.parent.AddChild(item.folder)
+			item.attached = true
+		}
+	}
+}
+
+// extractMarkdownTags removes inline #tags from text and appends them to tags
+func extractMarkdownTags(text string, tags []string) (string, []string) {
+	for _, match := range markdownTagPattern.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, match[1])
+	}
+	text = markdownTagPattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(text), tags
+}
+
+// markdownFolderTitle returns the title of a list item used as a folder
+func markdownFolderTitle(text string) string {
+	return strings.TrimSuffix(markdownText(text), ":")
+}
+
+// markdownText strips emphasis, images and escapes from inline Markdown text
+func markdownText(text string) string {
+	text = markdownImagePattern.ReplaceAllString(text, "$1")
+	text = strings.TrimSpace(text)
+	for _, marker := range []string{"**", "__", "*", "_", "`"} {
+		if len(text) > 2*len(marker) && strings.HasPrefix(text, marker) && strings.HasSuffix(text, marker) {
+			text = text[len(marker) : len(text)-len(marker)]
+		}
+	}
+
+	// Remove backslash escapes
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_{}[]()#+-.!|<>", text[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(text[i])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// markdownIndent returns the width of a list item's indentation, counting tabs as four spaces
+func markdownIndent(whitespace string) int {
+	width := 0
+	for _, r := range whitespace {
+		if r == '\t' {
+			width += 4
+		} else {
+			width++
+		}
+	}
+	return width
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	file, err := os.Open("../../test/testdata/awesome.md")
+	if err != nil {
+		t.Fatalf("Failed to open Markdown file: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewMarkdownParser(file)
+	parser.Tags = true
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Markdown: %v", err)
+	}
+
+	// The title heading isn't a folder; the table of contents has no bookmarks
+	expectedTitles := []string{"Contents", "Editors", "Tools"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+	if contents := root.Children[0].(*models.Folder); len(contents.Children) != 0 {
+		t.Errorf("Table of contents links should be skipped, got %d children", len(contents.Children))
+	}
+
+	editors := root.Children[1].(*models.Folder)
+	if len(editors.Children) != 4 {
+		t.Fatalf("Expected 4 children in Editors, got %d", len(editors.Children))
+	}
+
+	emacs := editors.Children[0].(*models.Bookmark)
+	if emacs.URL != "https://www.gnu.org/software/emacs/" || emacs.Title != "GNU Emacs" {
+		t.Errorf("Unexpected bookmark: %+v", emacs)
+	}
+	if emacs.Description != "The extensible editor." {
+		t.Errorf("Unexpected description: %q", emacs.Description)
+	}
+	if strings.Join(emacs.Tags, ",") != "editor,gnu" {
+		t.Errorf("Expected tags [editor gnu], got %v", emacs.Tags)
+	}
+
+	vim := editors.Children[1].(*models.Bookmark)
+	if vim.Description != "Org for Neovim.\nSupports agenda and capture." {
+		t.Errorf("Unexpected multi-line description: %q", vim.Description)
+	}
+	logseq := editors.Children[2].(*models.Bookmark)
+	if logseq.Title != "Logseq" || logseq.Description != "Outliner with Org support." {
+		t.Errorf("Unexpected bold bookmark: %+v", logseq)
+	}
+
+	plugins := editors.Children[3].(*models.Folder)
+	roam := plugins.Children[0].(*models.Bookmark)
+	if roam.URL != "https://www.orgroam.com/" || len(roam.Tags) != 1 {
+		t.Errorf("Unexpected numbered item: %+v", roam)
+	}
+	parens := plugins.Children[1].(*models.Bookmark)
+	if parens.URL != "https://en.wikipedia.org/wiki/Org_(mode)" {
+		t.Errorf("Unexpected URL: %s", parens.URL)
+	}
+
+	// List items without links become folders only if they have nested items;
+	// relative links and code blocks are skipped
+	tools := root.Children[2].(*models.Folder)
+	if len(tools.Children) != 1 {
+		t.Fatalf("Expected 1 child in Tools, got %d", len(tools.Children))
+	}
+	converters := tools.Children[0].(*models.Folder)
+	if converters.Title != "Converters" || len(converters.Children) != 2 {
+		t.Errorf("Unexpected list folder: %q with %d children", converters.Title, len(converters.Children))
+	}
+}
+
+func TestParseMarkdownWithoutTags(t *testing.T) {
+	parser := NewMarkdownParser(strings.NewReader("- [Go](https://go.dev/) - Docs #golang\n"))
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Markdown: %v", err)
+	}
+
+	bookmark := root.Children[0].(*models.Bookmark)
+	if len(bookmark.Tags) != 0 || bookmark.Description != "Docs #golang" {
+		t.Errorf("Tags should only be extracted when enabled: %+v", bookmark)
+	}
+}
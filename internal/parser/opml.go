@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// opmlDocument is the <opml> root element
+type opmlDocument struct {
+	XMLName  xml.Name      `xml:"opml"`
+	Title    string        `xml:"head>title"`
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+// opmlOutline is an <outline> element: a folder, a link or a feed
+type opmlOutline struct {
+	Text        string        `xml:"text,attr"`
+	Title       string        `xml:"title,attr"`
+	Type        string        `xml:"type,attr"`
+	URL         string        `xml:"url,attr"`
+	XMLURL      string        `xml:"xmlUrl,attr"`
+	HTMLURL     string        `xml:"htmlUrl,attr"`
+	Description string        `xml:"description,attr"`
+	Category    string        `xml:"category,attr"`
+	Created     string        `xml:"created,attr"`
+	Shortcut    string        `xml:"shortcut,attr"`
+	ID          string        `xml:"id,attr"`
+	Children    []opmlOutline `xml:"outline"`
+}
+
+// opmlDateLayouts are the date formats accepted in the created attribute.
+// OPML uses RFC 822 dates, but not every tool follows that.
+var opmlDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+}
+
+// OPMLParser parses OPML outlines, as exported by RSS readers and outliners
+type OPMLParser struct {
+	reader io.Reader
+}
+
+// NewOPMLParser creates a new OPML parser from a reader
+func NewOPMLParser(r io.Reader) *OPMLParser {
+	return &OPMLParser{
+		reader: r,
+	}
+}
+
+// Parse reads the OPML document and returns the root folder.
+// Outlines with a url, xmlUrl or htmlUrl attribute become bookmarks, all
+// other outlines become folders. For feeds the feed URL is used, and the
+// site URL is kept as an HTML_URL property.
+func (p *OPMLParser) Parse() (*models.Folder, error) {
+	var doc opmlDocument
+	if err := xml.NewDecoder(p.reader).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid OPML: %w", err)
+	}
+
+	root := &models.Folder{
+		Title: strings.TrimSpace(doc.Title),
+	}
+	if root.Title == "" {
+		root.Title = "Bookmarks"
+	}
+
+	for i := range doc.Outlines {
+		addOPMLOutline(root, &doc.Outlines[i])
+	}
+
+	return root, nil
+}
+
+// addOPMLOutline converts an outline and adds it to the parent folder
+func addOPMLOutline(parent *models.Folder, outline *opmlOutline) {
+	title := strings.TrimSpace(outline.Text)
+	if title == "" {
+		title = strings.TrimSpace(outline.Title)
+	}
+	description := strings.TrimSpace(outline.Description)
+
+	url := outline.URL
+	if url == "" {
+		url = outline.XMLURL
+	}
+	if url == "" {
+		url = outline.HTMLURL
+	}
+
+	if url == "" {
+		folder := &models.Folder{
+			ID:      outline.ID,
+			Title:   title,
+			AddDate: opmlTime(outline.Created),
+		}
+		if description != "" {
+			folder.Properties = map[string]string{"DESCRIPTION": description}
+		}
+		for i := range outline.Children {
+			addOPMLOutline(folder, &outline.Children[i])
+		}
+		parent.AddChild(folder)
+		return
+	}
+
+	bookmark := &models.Bookmark{
+		ID:          outline.ID,
+		URL:         url,
+		Title:       title,
+		ShortcutURL: outline.Shortcut,
+		AddDate:     opmlTime(outline.Created),
+		Description: description,
+	}
+	if bookmark.Title == "" {
+		bookmark.Title = url
+	}
+
+	// Categories are comma-separated; those without slashes are tags
+	for _, category := range strings.Split(outline.Category, ",") {
+		if tag := strings.TrimSpace(category); tag != "" && !strings.Contains(tag, "/") {
+			bookmark.Tags = append(bookmark.Tags, tag)
+		}
+	}
+
+	// Keep the site of a feed so it can be written back
+	if url == outline.XMLURL && outline.HTMLURL != "" && outline.HTMLURL != url {
+		bookmark.Properties = map[string]string{"HTML_URL": outline.HTMLURL}
+	}
+
+	parent.AddChild(bookmark)
+}
+
+// opmlTime parses an OPML date attribute
+func opmlTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range opmlDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	if t, ok := parseTimestamp(value); ok {
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParseOPML(t *testing.T) {
+	file, err := os.Open("../../test/testdata/feeds.opml")
+	if err != nil {
+		t.Fatalf("Failed to open OPML file: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewOPMLParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse OPML: %v", err)
+	}
+
+	if root.Title != "Reading & Links" {
+		t.Errorf("Expected root title from <head>, got %q", root.Title)
+	}
+
+	expectedTitles := []string{"Org Mode", "Linux", "Just a note about things"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+
+	org := root.Children[0].(*models.Bookmark)
+	if org.URL != "https://orgmode.org/" || org.Description != "Your life in plain text" {
+		t.Errorf("Unexpected bookmark: %+v", org)
+	}
+	if strings.Join(org.Tags, ",") != "emacs,org" {
+		t.Errorf("Expected tags [emacs org], got %v", org.Tags)
+	}
+	if org.AddDate.Unix() != 1702685191 {
+		t.Errorf("Unexpected add date: %v", org.AddDate)
+	}
+
+	linux := root.Children[1].(*models.Folder)
+	if linux.Properties["DESCRIPTION"] != "Distributions and news" {
+		t.Errorf("Expected folder description property, got %v", linux.Properties)
+	}
+	if len(linux.Children) != 3 {
+		t.Fatalf("Expected 3 children in Linux, got %d", len(linux.Children))
+	}
+
+	// Feeds use the feed URL and keep the site URL
+	feed := linux.Children[0].(*models.Bookmark)
+	if feed.URL != "https://fedoramagazine.org/feed/" || feed.Properties["HTML_URL"] != "https://fedoramagazine.org/" {
+		t.Errorf("Unexpected feed: %+v", feed)
+	}
+	if strings.Join(feed.Tags, ",") != "news" {
+		t.Errorf("Category paths should not become tags, got %v", feed.Tags)
+	}
+
+	lwn := linux.Children[1].(*models.Bookmark)
+	if lwn.Title != "LWN" || lwn.URL != "https://lwn.net/?a=1&b=2" {
+		t.Errorf("Unexpected bookmark: %+v", lwn)
+	}
+	if !linux.Children[2].IsFolder() {
+		t.Error("Outlines without a URL should become folders")
+	}
+}
+
+func TestParseOPMLInvalid(t *testing.T) {
+	parser := NewOPMLParser(strings.NewReader(`<xbel version="1.0"></xbel>`))
+	if _, err := parser.Parse(); err == nil {
+		t.Error("Expected an error for a non-OPML document")
+	}
+}
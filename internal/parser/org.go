@@ -3,7 +3,9 @@ package parser
 import (
 	"bufio"
 	"io"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/drewherron/orgmarks/internal/models"
 )
@@ -13,10 +15,17 @@ type OrgParser struct {
 	scanner *bufio.Scanner
 }
 
+// maxLineSize is the longest line the org parser accepts. Lines can get long,
+// since favicons are stored inline as data: URIs of several kilobytes.
+const maxLineSize = 64 * 1024 * 1024
+
 // NewOrgParser creates a new org-mode parser from a reader
 func NewOrgParser(r io.Reader) *OrgParser {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
 	return &OrgParser{
-		scanner: bufio.NewScanner(r),
+		scanner: scanner,
 	}
 }
 
@@ -103,6 +112,174 @@ func parseProperty(line string) (key, value string, ok bool) {
 	return key, value, true
 }
 
+// parseDrawerStart checks if a line opens a drawer like :PROPERTIES: and returns its name
+func parseDrawerStart(line string) (name string, ok bool) {
+	line = strings.TrimSpace(line)
+
+	if len(line) < 3 || line[0] != ':' || line[len(line)-1] != ':' {
+		return "", false
+	}
+
+	name = line[1 : len(line)-1]
+	if name == "" {
+		return "", false
+	}
+
+	// Drawer names are single words (letters, digits, - and _)
+	for _, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", false
+		}
+	}
+
+	return strings.ToUpper(name), true
+}
+
+// isDrawerEnd returns true if the line closes a drawer (:END:)
+func isDrawerEnd(line string) bool {
+	return strings.EqualFold(strings.TrimSpace(line), ":END:")
+}
+
+// lastDrawerEnd returns the index of the last line that closes a drawer, or -1.
+// Like Org itself, a drawer without a matching :END: is treated as plain text,
+// so only drawers that start before this line are drawers.
+func lastDrawerEnd(lines []string) int {
+	last := -1
+	for i, line := range lines {
+		if isDrawerEnd(line) {
+			last = i
+		}
+	}
+	return last
+}
+
+// parseDrawerProperty parses property drawer lines like :SHORTCUTURL: value
+func parseDrawerProperty(line string) (key, value string, ok bool) {
+	line = strings.TrimSpace(line)
+
+	if !strings.HasPrefix(line, ":") {
+		return "", "", false
+	}
+
+	// Find the closing colon of the key
+	colonIdx := strings.Index(line[1:], ":")
+	if colonIdx <= 0 {
+		return "", "", false
+	}
+	colonIdx++ // Make it absolute position
+
+	key = strings.ToUpper(line[1:colonIdx])
+	value = strings.TrimSpace(line[colonIdx+1:])
+
+	return key, value, true
+}
+
+// orgTimestampLayouts are the accepted layouts for timestamp property values,
+// with and without the day name and seconds. Timestamps without a UTC offset
+// are in local time.
+var orgTimestampLayouts = []string{
+	"2006-01-02 Mon 15:04:05 -0700",
+	"2006-01-02 Mon 15:04 -0700",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04 -0700",
+	"2006-01-02 Mon 15:04:05",
+	"2006-01-02 Mon 15:04",
+	"2006-01-02 Mon",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTimestamp parses a timestamp property value. It accepts Org timestamps
+// (active <...> or inactive [...]) with a UTC offset or in local time, and
+// plain Unix timestamps
+// as used by the HTML format's ADD_DATE and LAST_MODIFIED attributes.
+func parseTimestamp(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+
+	// Plain Unix timestamp
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(ts, 0), true
+	}
+
+	// Strip the timestamp brackets
+	if len(value) >= 2 && (value[0] == '[' && value[len(value)-1] == ']' ||
+		value[0] == '<' && value[len(value)-1] == '>') {
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+
+	for _, layout := range orgTimestampLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// metadata collects the properties found in a headline's content section
+type metadata struct {
+	id           string
+	role         models.RootRole
+	shortcutURL  string
+	addDate      time.Time
+	lastModified time.Time
+	icon         string
+	iconURI      string
+	separator    bool
+	properties   map[string]string
+}
+
+// set stores a property, mapping well-known keys onto model fields
+func (m *metadata) set(key, value string) {
+	switch key {
+	case "ID":
+		m.id = value
+	case "GUID":
+		// Browser GUIDs were kept under this name before IDs were a field
+		if m.id == "" {
+			m.id = value
+		}
+	case "ROOT":
+		if role, err := models.ParseRootRole(value); err == nil {
+			m.role = role
+			return
+		}
+		// Keep unknown roles as they are
+		m.setProperty(key, value)
+	case "SHORTCUTURL":
+		m.shortcutURL = value
+	case "ADD_DATE":
+		if t, ok := parseTimestamp(value); ok {
+			m.addDate = t
+		}
+	case "LAST_MODIFIED":
+		if t, ok := parseTimestamp(value); ok {
+			m.lastModified = t
+		}
+	case "ICON":
+		m.icon = value
+	case "ICON_URI":
+		m.iconURI = value
+	case "SEPARATOR":
+		if value == "t" {
+			m.separator = true
+			return
+		}
+		m.setProperty(key, value)
+	default:
+		m.setProperty(key, value)
+	}
+}
+
+// setProperty stores a property that has no model field
+func (m *metadata) setProperty(key, value string) {
+	if m.properties == nil {
+		m.properties = make(map[string]string)
+	}
+	m.properties[key] = value
+}
+
 // parseLink parses org-mode links like [[URL]] or [[URL][title]]
 // Returns url, title (if present), and ok bool
 func parseLink(line string) (url, title string, ok bool) {
@@ -178,9 +355,17 @@ func hasLink(line string) bool {
 	return strings.Contains(line, "[[") && strings.Contains(line, "]]")
 }
 
+// isSeparatorTitle checks if a headline title is a separator: five or more
+// dashes, like an Org horizontal rule
+func isSeparatorTitle(title string) bool {
+	return len(title) >= 5 && strings.Trim(title, "-") == ""
+}
+
 // Note: Folders vs Bookmarks distinction:
 // - A headline WITH a link (anywhere in its content section) = Bookmark
-// - A headline WITHOUT a link = Folder
+// - A headline WITHOUT a link or child headlines that has a SEPARATOR: t
+//   property, or whose title is all dashes = Separator
+// - Any other headline WITHOUT a link = Folder
 // This will be determined during the tree building phase (step 4.9)
 // by looking ahead at the content lines after each headline
 
@@ -204,7 +389,8 @@ func (p *OrgParser) Parse() (*models.Folder, error) {
 		if h := parseHeadline(line); h != nil {
 			// Process previous headline if exists
 			if currentHeadline != nil {
-				p.processHeadline(currentHeadline, contentLines, &folderStack, &levelStack)
+				hasChildren := h.level > currentHeadline.level
+				p.processHeadline(currentHeadline, contentLines, hasChildren, &folderStack, &levelStack)
 			}
 
 			// Start new headline
@@ -218,20 +404,42 @@ func (p *OrgParser) Parse() (*models.Folder, error) {
 
 	// Process final headline
 	if currentHeadline != nil {
-		p.processHeadline(currentHeadline, contentLines, &folderStack, &levelStack)
+		p.processHeadline(currentHeadline, contentLines, false, &folderStack, &levelStack)
 	}
 
 	return root, p.scanner.Err()
 }
 
-// processHeadline processes a headline and its content, creating either a folder or bookmark
-func (p *OrgParser) processHeadline(h *headline, contentLines []string, folderStack *[]*models.Folder, levelStack *[]int) {
+// processHeadline processes a headline and its content, creating either a folder or bookmark.
+// hasChildren tells whether the next headline is below this one.
+func (p *OrgParser) processHeadline(h *headline, contentLines []string, hasChildren bool, folderStack *[]*models.Folder, levelStack *[]int) {
 	// Check if content has a link (determines if it's a bookmark or folder)
 	var linkURL string
-	var shortcutURL string
+	var meta metadata
 	var description strings.Builder
 
-	for _, line := range contentLines {
+	// Name of the drawer we are currently inside, if any
+	drawer := ""
+	lastEnd := lastDrawerEnd(contentLines)
+
+	for i, line := range contentLines {
+		// Handle drawer contents (:PROPERTIES: ... :END:)
+		if drawer != "" {
+			if isDrawerEnd(line) {
+				drawer = ""
+			} else if drawer == "PROPERTIES" {
+				if key, value, ok := parseDrawerProperty(line); ok {
+					meta.set(key, value)
+				}
+			}
+			// Other drawers (e.g. :LOGBOOK:) are skipped entirely
+			continue
+		}
+		if name, ok := parseDrawerStart(line); ok && name != "END" && i < lastEnd {
+			drawer = name
+			continue
+		}
+
 		// Check for link
 		if url, _, ok := parseLink(line); ok && linkURL == "" {
 			linkURL = url
@@ -239,9 +447,7 @@ func (p *OrgParser) processHeadline(h *headline, contentLines []string, folderSt
 
 		// Check for properties
 		if key, value, ok := parseProperty(line); ok {
-			if key == "SHORTCUTURL" {
-				shortcutURL = value
-			}
+			meta.set(key, value)
 		}
 
 		// Collect description text
@@ -273,11 +479,17 @@ func (p *OrgParser) processHeadline(h *headline, contentLines []string, folderSt
 	if linkURL != "" {
 		// This is a bookmark
 		bookmark := &models.Bookmark{
-			Title:       h.title,
-			URL:         linkURL,
-			Tags:        h.tags,
-			ShortcutURL: shortcutURL,
-			Description: description.String(),
+			ID:           meta.id,
+			Title:        h.title,
+			URL:          linkURL,
+			Tags:         h.tags,
+			ShortcutURL:  meta.shortcutURL,
+			AddDate:      meta.addDate,
+			LastModified: meta.lastModified,
+			Description:  description.String(),
+			Icon:         meta.icon,
+			IconURI:      meta.iconURI,
+			Properties:   meta.properties,
 		}
 
 		// Skip bookmarks with empty titles (malformed)
@@ -286,10 +498,19 @@ func (p *OrgParser) processHeadline(h *headline, contentLines []string, folderSt
 		}
 
 		parent.AddChild(bookmark)
+	} else if !hasChildren && (meta.separator || isSeparatorTitle(h.title)) {
+		// This is a separator. A dash-titled headline with children is a
+		// folder, so they aren't lost.
+		parent.AddChild(&models.Separator{ID: meta.id})
 	} else {
 		// This is a folder
 		folder := &models.Folder{
-			Title: h.title,
+			ID:           meta.id,
+			Title:        h.title,
+			Role:         meta.role,
+			AddDate:      meta.addDate,
+			LastModified: meta.lastModified,
+			Properties:   meta.properties,
 		}
 
 		// Skip folders with empty titles (malformed)
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/drewherron/orgmarks/internal/models"
 )
@@ -397,3 +398,201 @@ func TestParseOrgMixedContent(t *testing.T) {
 		t.Error("Third child should be a folder")
 	}
 }
+
+// TestParseOrgWithPropertiesDrawer tests parsing metadata from a :PROPERTIES: drawer
+func TestParseOrgWithPropertiesDrawer(t *testing.T) {
+	org := `* Bookmark with drawer
+:PROPERTIES:
+:SHORTCUTURL: myshortcut
+:ADD_DATE: 1503757590
+:LAST_MODIFIED: [2024-10-01 Tue 12:30]
+:ID: 1234-abcd
+:Custom_Key: some value
+:END:
+[[https://example.com]]
+This is a description`
+
+	parser := NewOrgParser(strings.NewReader(org))
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org with properties drawer: %v", err)
+	}
+
+	if len(root.Children) != 1 {
+		t.Fatalf("Expected 1 child, got %d", len(root.Children))
+	}
+
+	bookmark := root.Children[0].(*models.Bookmark)
+	if bookmark.ShortcutURL != "myshortcut" {
+		t.Errorf("Expected shortcut 'myshortcut', got: %s", bookmark.ShortcutURL)
+	}
+	if bookmark.AddDate.Unix() != 1503757590 {
+		t.Errorf("Expected ADD_DATE 1503757590, got: %d", bookmark.AddDate.Unix())
+	}
+	expectedModified := time.Date(2024, 10, 1, 12, 30, 0, 0, time.Local)
+	if !bookmark.LastModified.Equal(expectedModified) {
+		t.Errorf("Expected LAST_MODIFIED %v, got: %v", expectedModified, bookmark.LastModified)
+	}
+	if bookmark.ID != "1234-abcd" {
+		t.Errorf("Expected ID '1234-abcd', got: %q", bookmark.ID)
+	}
+	if _, ok := bookmark.Properties["ID"]; ok {
+		t.Errorf("ID should not also be kept as a property")
+	}
+	if bookmark.Properties["CUSTOM_KEY"] != "some value" {
+		t.Errorf("Expected CUSTOM_KEY property 'some value', got: %q", bookmark.Properties["CUSTOM_KEY"])
+	}
+	// Drawer lines must not leak into the description
+	if bookmark.Description != "This is a description" {
+		t.Errorf("Expected description 'This is a description', got: %q", bookmark.Description)
+	}
+}
+
+// TestParseOrgFolderWithDrawers tests that folders keep drawer metadata and skip other drawers
+func TestParseOrgFolderWithDrawers(t *testing.T) {
+	org := `* Folder
+:PROPERTIES:
+:ADD_DATE: 1544490673
+:END:
+:LOGBOOK:
+- Note taken on [2024-10-01 Tue 12:30]
+:END:
+** Bookmark
+[[https://example.com]]
+:not-a-drawer:
+Trailing text`
+
+	parser := NewOrgParser(strings.NewReader(org))
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org with folder drawers: %v", err)
+	}
+
+	folder := root.Children[0].(*models.Folder)
+	if folder.AddDate.Unix() != 1544490673 {
+		t.Errorf("Expected folder ADD_DATE 1544490673, got: %d", folder.AddDate.Unix())
+	}
+	if len(folder.Properties) != 0 {
+		t.Errorf("Expected no extra folder properties, got: %v", folder.Properties)
+	}
+
+	// A drawer without :END: is plain text
+	bookmark := folder.Children[0].(*models.Bookmark)
+	if bookmark.Description != ":not-a-drawer:\nTrailing text" {
+		t.Errorf("Expected unterminated drawer to be description, got: %q", bookmark.Description)
+	}
+}
+
+// TestParseOrgIDs tests reading IDs, including GUID properties from older files
+func TestParseOrgIDs(t *testing.T) {
+	org := `* Folder
+#+ID: 6f1c2a8e-4b3d-4e55-9a3c-0d8e2b7f1a90
+** Converted from Firefox
+#+GUID: V2n0aJ7kP1sD
+[[https://example.com]]
+** Both
+#+ID: both-id
+#+GUID: V2n0aJ7kP1sD
+[[https://example.org]]`
+
+	root, err := NewOrgParser(strings.NewReader(org)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org with IDs: %v", err)
+	}
+
+	folder := root.Children[0].(*models.Folder)
+	if folder.ID != "6f1c2a8e-4b3d-4e55-9a3c-0d8e2b7f1a90" {
+		t.Errorf("Expected folder ID, got: %q", folder.ID)
+	}
+	if id := folder.Children[0].(*models.Bookmark).ID; id != "V2n0aJ7kP1sD" {
+		t.Errorf("Expected GUID to be read as the ID, got: %q", id)
+	}
+	both := folder.Children[1].(*models.Bookmark)
+	if both.ID != "both-id" || len(both.Properties) != 0 {
+		t.Errorf("Expected ID to take precedence over GUID, got: %q %v", both.ID, both.Properties)
+	}
+}
+
+// TestParseOrgWithTimestampKeywords tests parsing timestamps from #+KEY: lines
+func TestParseOrgWithTimestampKeywords(t *testing.T) {
+	org := `* Bookmark
+#+ADD_DATE: [2017-08-26 Sat 14:26:30]
+#+LAST_MODIFIED: <2024-10-01 Tue>
+[[https://example.com]]`
+
+	parser := NewOrgParser(strings.NewReader(org))
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org with timestamps: %v", err)
+	}
+
+	bookmark := root.Children[0].(*models.Bookmark)
+	expectedAdded := time.Date(2017, 8, 26, 14, 26, 30, 0, time.Local)
+	if !bookmark.AddDate.Equal(expectedAdded) {
+		t.Errorf("Expected ADD_DATE %v, got: %v", expectedAdded, bookmark.AddDate)
+	}
+	expectedModified := time.Date(2024, 10, 1, 0, 0, 0, 0, time.Local)
+	if !bookmark.LastModified.Equal(expectedModified) {
+		t.Errorf("Expected LAST_MODIFIED %v, got: %v", expectedModified, bookmark.LastModified)
+	}
+	if len(bookmark.Properties) != 0 {
+		t.Errorf("Expected no extra properties, got: %v", bookmark.Properties)
+	}
+}
+
+// TestParseOrgTimestampOffsets tests that timestamps with a UTC offset are
+// read as written, also in the hour that repeats when DST ends
+func TestParseOrgTimestampOffsets(t *testing.T) {
+	org := `* First
+#+ADD_DATE: [2024-11-03 Sun 01:30:00 -0400]
+[[https://first.example.com]]
+* Second
+#+ADD_DATE: [2024-11-03 Sun 01:30:00 -0500]
+[[https://second.example.com]]`
+
+	root, err := NewOrgParser(strings.NewReader(org)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org with timestamps: %v", err)
+	}
+
+	first := root.Children[0].(*models.Bookmark).AddDate
+	second := root.Children[1].(*models.Bookmark).AddDate
+	if first.Unix() != 1730611800 || second.Unix() != 1730615400 {
+		t.Errorf("Expected 1730611800 and 1730615400, got %d and %d", first.Unix(), second.Unix())
+	}
+}
+
+// TestParseOrgSeparators tests that separators are marked by a property or
+// a dashed title, and that a dash-titled headline with children is a folder
+func TestParseOrgSeparators(t *testing.T) {
+	org := `* Menu
+** Gmail
+[[https://mail.google.com]]
+** Break
+#+SEPARATOR: t
+** -----
+* -----
+** Child
+[[https://x.com]]`
+
+	root, err := NewOrgParser(strings.NewReader(org)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse org with separators: %v", err)
+	}
+
+	if len(root.Children) != 2 {
+		t.Fatalf("Expected 2 top-level children, got %d", len(root.Children))
+	}
+	menu := root.Children[0].(*models.Folder)
+	if len(menu.Children) != 3 || !models.IsSeparator(menu.Children[1]) || !models.IsSeparator(menu.Children[2]) {
+		t.Errorf("Expected a bookmark and two separators, got %#v", menu.Children)
+	}
+
+	folder, ok := root.Children[1].(*models.Folder)
+	if !ok || folder.Title != "-----" {
+		t.Fatalf("Expected a folder titled -----, got %#v", root.Children[1])
+	}
+	if len(folder.Children) != 1 || folder.Children[0].(*models.Bookmark).Title != "Child" {
+		t.Errorf("Expected the child to stay in the folder, got %#v", folder.Children)
+	}
+}
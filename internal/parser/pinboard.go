@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// pinboardPost is a bookmark in a Pinboard JSON export (posts/all)
+type pinboardPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"` // The title
+	Extended    string `json:"extended"`    // The notes
+	Time        string `json:"time"`
+	Shared      string `json:"shared"`
+	ToRead      string `json:"toread"`
+	Tags        string `json:"tags"`
+}
+
+// PinboardParser parses Pinboard JSON exports
+type PinboardParser struct {
+	reader io.Reader
+}
+
+// NewPinboardParser creates a new Pinboard export parser from a reader
+func NewPinboardParser(r io.Reader) *PinboardParser {
+	return &PinboardParser{
+		reader: r,
+	}
+}
+
+// Parse reads the Pinboard export and returns the root folder.
+// Pinboard has no folders, so all bookmarks are at the top level. Unread and
+// private bookmarks get TOREAD and PRIVATE properties.
+func (p *PinboardParser) Parse() (*models.Folder, error) {
+	var posts []pinboardPost
+	if err := json.NewDecoder(p.reader).Decode(&posts); err != nil {
+		return nil, fmt.Errorf("invalid Pinboard export: %w", err)
+	}
+
+	root := &models.Folder{
+		Title: "Bookmarks",
+	}
+
+	for _, post := range posts {
+		if post.Href == "" {
+			continue
+		}
+
+		bookmark := &models.Bookmark{
+			URL:         post.Href,
+			Title:       strings.TrimSpace(post.Description),
+			Tags:        strings.Fields(post.Tags),
+			Description: strings.TrimSpace(post.Extended),
+		}
+		if bookmark.Title == "" {
+			bookmark.Title = post.Href
+		}
+		if t, err := time.Parse(time.RFC3339, post.Time); err == nil {
+			bookmark.AddDate = t
+		}
+
+		if post.ToRead == "yes" || post.Shared == "no" {
+			bookmark.Properties = make(map[string]string)
+			if post.ToRead == "yes" {
+				bookmark.Properties["TOREAD"] = "yes"
+			}
+			if post.Shared == "no" {
+				bookmark.Properties["PRIVATE"] = "yes"
+			}
+		}
+
+		root.AddChild(bookmark)
+	}
+
+	return root, nil
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParsePinboard(t *testing.T) {
+	file, err := os.Open("../../test/testdata/pinboard.json")
+	if err != nil {
+		t.Fatalf("Failed to open Pinboard export: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewPinboardParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Pinboard export: %v", err)
+	}
+
+	if len(root.Children) != 3 {
+		t.Fatalf("Expected 3 bookmarks, got %d", len(root.Children))
+	}
+
+	org := root.Children[0].(*models.Bookmark)
+	if org.Title != "Org Mode" || org.URL != "https://orgmode.org/" {
+		t.Errorf("Unexpected bookmark: %+v", org)
+	}
+	if org.Description != "Your life in plain text.\nOutlines, notes and agendas." {
+		t.Errorf("Expected extended description, got %q", org.Description)
+	}
+	if strings.Join(org.Tags, ",") != "emacs,org" {
+		t.Errorf("Expected tags [emacs org], got %v", org.Tags)
+	}
+	if org.AddDate.Unix() != 1702685191 {
+		t.Errorf("Unexpected add date: %v", org.AddDate)
+	}
+	if org.Properties != nil {
+		t.Errorf("Shared, read bookmarks should have no properties, got %v", org.Properties)
+	}
+
+	lwn := root.Children[1].(*models.Bookmark)
+	if lwn.Properties["TOREAD"] != "yes" || lwn.Properties["PRIVATE"] != "yes" {
+		t.Errorf("Expected TOREAD and PRIVATE properties, got %v", lwn.Properties)
+	}
+
+	untitled := root.Children[2].(*models.Bookmark)
+	if untitled.Title != "https://example.com/" || !untitled.AddDate.IsZero() {
+		t.Errorf("Unexpected bookmark: %+v", untitled)
+	}
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/models"
+	"github.com/drewherron/orgmarks/internal/sqlite"
+)
+
+// firefoxRootGUID is the GUID of the places root, the parent of all other roots
+const firefoxRootGUID = "root________"
+
+// PlacesParser reads bookmarks from a Firefox places.sqlite database
+type PlacesParser struct {
+	reader io.Reader
+}
+
+// NewPlacesParser creates a new places.sqlite parser from a reader.
+// The database is read into memory and never modified.
+func NewPlacesParser(r io.Reader) *PlacesParser {
+	return &PlacesParser{
+		reader: r,
+	}
+}
+
+// Parse reads the bookmarks from the database and returns the root folder.
+// The tree has the same shape as for Firefox JSON backups and HTML exports:
+// tags come from the folders under the tags root and keywords from moz_keywords.
+func (p *PlacesParser) Parse() (*models.Folder, error) {
+	data, err := io.ReadAll(p.reader)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sqlite.New(data)
+	if err != nil {
+		return nil, err
+	}
+	if !db.HasTable("moz_bookmarks") || !db.HasTable("moz_places") {
+		return nil, fmt.Errorf("not a Firefox places database: moz_bookmarks or moz_places missing")
+	}
+
+	bookmarkRows, err := db.Rows("moz_bookmarks")
+	if err != nil {
+		return nil, err
+	}
+	placeRows, err := db.Rows("moz_places")
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[int64]string)
+	for _, row := range placeRows {
+		urls[rowInt(row, "id")] = rowString(row, "url")
+	}
+
+	keywords := make(map[int64]string)
+	if db.HasTable("moz_keywords") {
+		keywordRows, err := db.Rows("moz_keywords")
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range keywordRows {
+			keywords[rowInt(row, "place_id")] = rowString(row, "keyword")
+		}
+	}
+
+	// Build the nodes, remembering each node's parent and position
+	nodes := make(map[int64]*firefoxNode)
+	parents := make(map[int64]int64)
+	positions := make(map[*firefoxNode]int64)
+	var placesRoot *firefoxNode
+	for _, row := range bookmarkRows {
+		node := &firefoxNode{
+			GUID:         rowString(row, "guid"),
+			Title:        rowString(row, "title"),
+			DateAdded:    rowInt(row, "dateAdded"),
+			LastModified: rowInt(row, "lastModified"),
+			TypeCode:     int(rowInt(row, "type")),
+		}
+		if fk := rowInt(row, "fk"); fk != 0 {
+			node.URI = urls[fk]
+			node.Keyword = keywords[fk]
+		}
+
+		id := rowInt(row, "id")
+		nodes[id] = node
+		parents[id] = rowInt(row, "parent")
+		positions[node] = rowInt(row, "position")
+		if node.GUID == firefoxRootGUID {
+			placesRoot = node
+		}
+	}
+	if placesRoot == nil {
+		return nil, fmt.Errorf("not a Firefox places database: no places root")
+	}
+
+	// Link children to their parents in id order, then sort them by position
+	ids := make([]int64, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if parent, ok := nodes[parents[id]]; ok && parent != nodes[id] {
+			parent.Children = append(parent.Children, nodes[id])
+		}
+	}
+	for _, node := range nodes {
+		sort.SliceStable(node.Children, func(i, j int) bool {
+			return positions[node.Children[i]] < positions[node.Children[j]]
+		})
+	}
+
+	// Tags are folders under the tags root, holding one bookmark per tagged URL
+	tags := make(map[string][]string)
+	for _, child := range placesRoot.Children {
+		if child.GUID != firefoxTagsGUID {
+			continue
+		}
+		for _, tag := range child.Children {
+			for _, tagged := range tag.Children {
+				tags[tagged.URI] = append(tags[tagged.URI], tag.Title)
+			}
+		}
+	}
+	for _, node := range nodes {
+		if node.TypeCode == firefoxTypeBookmark {
+			node.Tags = strings.Join(tags[node.URI], ",")
+		}
+	}
+
+	return firefoxTree(placesRoot), nil
+}
+
+// rowInt returns an integer column, or 0 if it is NULL
+func rowInt(row sqlite.Row, column string) int64 {
+	value, _ := row[column].(int64)
+	return value
+}
+
+// rowString returns a text column, or "" if it is NULL
+func rowString(row sqlite.Row, column string) string {
+	value, _ := row[column].(string)
+	return value
+}
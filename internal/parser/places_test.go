@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParsePlaces(t *testing.T) {
+	file, err := os.Open("../../test/testdata/places.sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open places database: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewPlacesParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse places database: %v", err)
+	}
+
+	// Same shape as the Firefox JSON backup: menu contents at the top level,
+	// a separator, toolbar and other bookmarks as folders; tags and place:
+	// queries are skipped
+	expectedTitles := []string{"Email", "", "Bookmarks Toolbar", "Other Bookmarks"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+
+	if !models.IsSeparator(root.Children[1]) {
+		t.Errorf("Expected a separator, got %#v", root.Children[1])
+	}
+
+	toolbar := root.Children[2].(*models.Folder)
+	var titles []string
+	for _, child := range toolbar.Children {
+		titles = append(titles, child.GetTitle())
+	}
+	if strings.Join(titles, ",") != "Docs,Fedora Magazine,Project" {
+		t.Errorf("Unexpected toolbar contents: %v", titles)
+	}
+
+	magazine := toolbar.Children[1].(*models.Bookmark)
+	if magazine.URL != "https://fedoramagazine.org/" {
+		t.Errorf("Unexpected URL: %s", magazine.URL)
+	}
+	if magazine.ShortcutURL != "magazine" {
+		t.Errorf("Expected keyword 'magazine', got '%s'", magazine.ShortcutURL)
+	}
+	if len(magazine.Tags) != 1 || magazine.Tags[0] != "news" {
+		t.Errorf("Expected tags [news], got %v", magazine.Tags)
+	}
+	if magazine.AddDate.Unix() != 1367341224 || magazine.LastModified.Unix() != 1503757786 {
+		t.Errorf("Unexpected timestamps: %v, %v", magazine.AddDate, magazine.LastModified)
+	}
+	if magazine.ID != "V2n0aJ7kP1sD" {
+		t.Errorf("Expected GUID to be kept as the ID, got %q", magazine.ID)
+	}
+
+	other := root.Children[3].(*models.Folder)
+	archive := other.Children[1].(*models.Folder)
+	if len(archive.Children) != 201 {
+		t.Fatalf("Expected 201 bookmarks in Archive, got %d", len(archive.Children))
+	}
+	if archive.Children[10].GetTitle() != "Page 10" || archive.Children[200].GetTitle() != "Long URL" {
+		t.Errorf("Archive is not in position order")
+	}
+}
+
+func TestParsePlacesInvalid(t *testing.T) {
+	parser := NewPlacesParser(strings.NewReader("not a database"))
+	if _, err := parser.Parse(); err == nil {
+		t.Error("Expected an error for invalid input")
+	}
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+	"golang.org/x/net/html"
+)
+
+// pocketUnreadTitle is the heading of the unread list in Pocket exports
+const pocketUnreadTitle = "unread"
+
+// PocketParser parses Pocket HTML exports
+type PocketParser struct {
+	tokenizer *html.Tokenizer
+}
+
+// NewPocketParser creates a new Pocket export parser from a reader
+func NewPocketParser(r io.Reader) *PocketParser {
+	return &PocketParser{
+		tokenizer: html.NewTokenizer(r),
+	}
+}
+
+// Parse reads the Pocket export and returns the root folder.
+// Pocket has no folders: the export lists links under an "Unread" and a
+// "Read Archive" heading. All bookmarks go to the top level, and those
+// from the unread list get a TOREAD property.
+func (p *PocketParser) Parse() (*models.Folder, error) {
+	root := &models.Folder{
+		Title: "Bookmarks",
+	}
+
+	unread := false
+	for {
+		tt := p.tokenizer.Next()
+		if tt == html.ErrorToken {
+			if p.tokenizer.Err() == io.EOF {
+				break
+			}
+			return nil, p.tokenizer.Err()
+		}
+		if tt != html.StartTagToken {
+			continue
+		}
+
+		token := p.tokenizer.Token()
+		switch token.Data {
+		case "h1":
+			unread = strings.EqualFold(strings.TrimSpace(p.textContent()), pocketUnreadTitle)
+		case "a":
+			bookmark := &models.Bookmark{}
+			for _, attr := range token.Attr {
+				switch attr.Key {
+				case "href":
+					bookmark.URL = attr.Val
+				case "time_added":
+					if ts, err := strconv.ParseInt(attr.Val, 10, 64); err == nil {
+						bookmark.AddDate = time.Unix(ts, 0)
+					}
+				case "tags":
+					for _, tag := range strings.Split(attr.Val, ",") {
+						if trimmed := strings.TrimSpace(tag); trimmed != "" {
+							bookmark.Tags = append(bookmark.Tags, trimmed)
+						}
+					}
+				}
+			}
+
+			bookmark.Title = strings.TrimSpace(p.textContent())
+			if bookmark.URL == "" {
+				continue
+			}
+			if bookmark.Title == "" {
+				bookmark.Title = bookmark.URL
+			}
+			if unread {
+				bookmark.Properties = map[string]string{"TOREAD": "yes"}
+			}
+
+			root.AddChild(bookmark)
+		}
+	}
+
+	return root, nil
+}
+
+// textContent reads text content until the closing tag
+func (p *PocketParser) textContent() string {
+	var content strings.Builder
+	for {
+		tt := p.tokenizer.Next()
+		if tt == html.TextToken {
+			content.WriteString(p.tokenizer.Token().Data)
+		} else if tt == html.EndTagToken || tt == html.ErrorToken {
+			break
+		}
+	}
+	return content.String()
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParsePocket(t *testing.T) {
+	file, err := os.Open("../../test/testdata/pocket.html")
+	if err != nil {
+		t.Fatalf("Failed to open Pocket export: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewPocketParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Pocket export: %v", err)
+	}
+
+	// No folders, unread and archived items all at the top level
+	if len(root.Children) != 3 {
+		t.Fatalf("Expected 3 bookmarks, got %d", len(root.Children))
+	}
+
+	lwn := root.Children[0].(*models.Bookmark)
+	if lwn.Title != "LWN & kernel news" || lwn.URL != "https://lwn.net/Articles/1/" {
+		t.Errorf("Unexpected bookmark: %+v", lwn)
+	}
+	if strings.Join(lwn.Tags, ",") != "linux,kernel" {
+		t.Errorf("Expected tags [linux kernel], got %v", lwn.Tags)
+	}
+	if lwn.AddDate.Unix() != 1709294400 {
+		t.Errorf("Unexpected add date: %v", lwn.AddDate)
+	}
+	if lwn.Properties["TOREAD"] != "yes" {
+		t.Errorf("Unread items should have a TOREAD property, got %v", lwn.Properties)
+	}
+
+	untitled := root.Children[1].(*models.Bookmark)
+	if untitled.Title != untitled.URL || len(untitled.Tags) != 0 {
+		t.Errorf("Unexpected bookmark: %+v", untitled)
+	}
+
+	org := root.Children[2].(*models.Bookmark)
+	if org.Title != "Org Mode" || org.Properties != nil {
+		t.Errorf("Archived items should not be marked unread: %+v", org)
+	}
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// raindropUnsorted is the collection of bookmarks not in any collection
+const raindropUnsorted = "Unsorted"
+
+// RaindropParser parses Raindrop.io CSV exports
+type RaindropParser struct {
+	reader io.Reader
+}
+
+// NewRaindropParser creates a new Raindrop.io export parser from a reader
+func NewRaindropParser(r io.Reader) *RaindropParser {
+	return &RaindropParser{
+		reader: r,
+	}
+}
+
+// Parse reads the Raindrop.io export and returns the root folder.
+// Collections become folders (nested collections are exported as paths like
+// "Design/Icons"), and unsorted bookmarks go to the top level. The note and
+// the page excerpt together make up the description. Favorites get a
+// FAVORITE property.
+func (p *RaindropParser) Parse() (*models.Folder, error) {
+	br := bufio.NewReader(p.reader)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Raindrop.io export: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("invalid Raindrop.io export: missing header")
+	}
+
+	// Columns are looked up by name, as Raindrop.io has added columns over time
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, fmt.Errorf("invalid Raindrop.io export: no url column")
+	}
+
+	root := &models.Folder{
+		Title: "Bookmarks",
+	}
+	folders := map[string]*models.Folder{"": root, raindropUnsorted: root}
+
+	for _, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		url := field("url")
+		if url == "" {
+			continue
+		}
+
+		bookmark := &models.Bookmark{
+			URL:     url,
+			Title:   field("title"),
+			AddDate: csvTime(field("created")),
+		}
+		if bookmark.Title == "" {
+			bookmark.Title = url
+		}
+
+		var description []string
+		for _, text := range []string{field("note"), field("excerpt")} {
+			if text != "" {
+				description = append(description, text)
+			}
+		}
+		bookmark.Description = strings.Join(description, "\n\n")
+
+		for _, tag := range strings.Split(field("tags"), ",") {
+			if trimmed := strings.TrimSpace(tag); trimmed != "" {
+				bookmark.Tags = append(bookmark.Tags, trimmed)
+			}
+		}
+
+		if field("favorite") == "true" {
+			bookmark.Properties = map[string]string{"FAVORITE": "yes"}
+		}
+
+		csvFolderForPath(folders, field("folder")).AddChild(bookmark)
+	}
+
+	return root, nil
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParseRaindrop(t *testing.T) {
+	file, err := os.Open("../../test/testdata/raindrop.csv")
+	if err != nil {
+		t.Fatalf("Failed to open Raindrop.io export: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewRaindropParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Raindrop.io export: %v", err)
+	}
+
+	// Unsorted bookmarks at the top level, collections as folders
+	expectedTitles := []string{"Org Mode", "Linux"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+
+	org := root.Children[0].(*models.Bookmark)
+	if org.Description != "My favourite outliner\n\nYour life in plain text" {
+		t.Errorf("Expected note and excerpt as description, got %q", org.Description)
+	}
+	if strings.Join(org.Tags, ",") != "emacs,org" {
+		t.Errorf("Expected tags [emacs org], got %v", org.Tags)
+	}
+	if org.AddDate.Unix() != 1702685191 {
+		t.Errorf("Unexpected add date: %v", org.AddDate)
+	}
+	if org.Properties["FAVORITE"] != "yes" {
+		t.Errorf("Expected FAVORITE property, got %v", org.Properties)
+	}
+
+	// Nested collections, the row without a URL is skipped
+	linux := root.Children[1].(*models.Folder)
+	if len(linux.Children) != 2 {
+		t.Fatalf("Expected 2 children in Linux, got %d", len(linux.Children))
+	}
+	news := linux.Children[0].(*models.Folder)
+	if news.Title != "News" || news.Children[0].(*models.Bookmark).Description != "News for Fedora users" {
+		t.Errorf("Unexpected nested collection: %+v", news)
+	}
+	if lwn := linux.Children[1].(*models.Bookmark); lwn.Properties != nil {
+		t.Errorf("Expected no properties, got %v", lwn.Properties)
+	}
+}
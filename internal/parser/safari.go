@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+	"github.com/drewherron/orgmarks/internal/plist"
+)
+
+// Safari bookmark node types (the WebBookmarkType key)
+const (
+	safariTypeList  = "WebBookmarkTypeList"
+	safariTypeLeaf  = "WebBookmarkTypeLeaf"
+	safariTypeProxy = "WebBookmarkTypeProxy"
+)
+
+// Titles of Safari's special top-level lists
+const (
+	safariBarTitle     = "BookmarksBar"
+	safariMenuTitle    = "BookmarksMenu"
+	safariReadingTitle = "com.apple.ReadingList"
+)
+
+// safariRootTitles maps Safari's special lists to the folder titles Safari
+// uses in its HTML export
+var safariRootTitles = map[string]string{
+	safariBarTitle:     "Favorites",
+	safariReadingTitle: "Reading List",
+}
+
+// safariRootRoles maps Safari's special lists to their root roles
+var safariRootRoles = map[string]models.RootRole{
+	safariBarTitle:     models.RootToolbar,
+	safariReadingTitle: models.RootReading,
+}
+
+// SafariParser parses Safari Bookmarks.plist files (binary or XML)
+type SafariParser struct {
+	reader io.Reader
+}
+
+// NewSafariParser creates a new Safari bookmarks parser from a reader
+func NewSafariParser(r io.Reader) *SafariParser {
+	return &SafariParser{
+		reader: r,
+	}
+}
+
+// Parse reads the Safari bookmarks and returns the root folder.
+// Like Safari's HTML export, the bookmarks menu becomes the top level of the
+// tree and the favorites bar becomes a folder. The Reading List, if used,
+// becomes a folder too, with preview texts as descriptions.
+func (p *SafariParser) Parse() (*models.Folder, error) {
+	data, err := io.ReadAll(p.reader)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := plist.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rootDict, ok := value.(map[string]any)
+	if !ok || plistString(rootDict, "WebBookmarkType") != safariTypeList {
+		return nil, fmt.Errorf("invalid Safari bookmarks: root is not a bookmark list")
+	}
+
+	root := &models.Folder{
+		Title: "Bookmarks",
+	}
+
+	lists := plistChildren(rootDict)
+
+	// Menu contents first, like in the HTML export
+	for _, list := range lists {
+		if plistString(list, "Title") == safariMenuTitle {
+			for _, child := range plistChildren(list) {
+				addSafariNode(root, child)
+			}
+		}
+	}
+
+	for _, list := range lists {
+		title := plistString(list, "Title")
+		if title == safariMenuTitle || plistString(list, "WebBookmarkType") != safariTypeList {
+			// Menu was handled above, the History proxy has no bookmarks
+			continue
+		}
+		if title == safariReadingTitle && len(plistChildren(list)) == 0 {
+			continue
+		}
+
+		folder := safariFolder(list)
+		if rootTitle, ok := safariRootTitles[title]; ok {
+			folder.Title = rootTitle
+			folder.Role = safariRootRoles[title]
+		}
+		root.AddChild(folder)
+	}
+
+	return root, nil
+}
+
+// addSafariNode converts a node and adds it to the parent folder
+func addSafariNode(parent *models.Folder, node map[string]any) {
+	switch plistString(node, "WebBookmarkType") {
+	case safariTypeList:
+		parent.AddChild(safariFolder(node))
+	case safariTypeLeaf:
+		url := plistString(node, "URLString")
+		if url == "" {
+			return
+		}
+
+		bookmark := &models.Bookmark{
+			ID:  plistString(node, "WebBookmarkUUID"),
+			URL: url,
+		}
+		if uriDictionary, ok := node["URIDictionary"].(map[string]any); ok {
+			bookmark.Title = plistString(uriDictionary, "title")
+		}
+
+		// Reading List items have the date they were added and a preview
+		if readingList, ok := node["ReadingList"].(map[string]any); ok {
+			if added, ok := readingList["DateAdded"].(time.Time); ok {
+				bookmark.AddDate = added
+			}
+			bookmark.Description = plistString(readingList, "PreviewText")
+		}
+
+		parent.AddChild(bookmark)
+	case safariTypeProxy:
+		// Proxies (History) have no equivalent in the bookmark model
+	}
+}
+
+// safariFolder converts a list node and its children
+func safariFolder(node map[string]any) *models.Folder {
+	folder := &models.Folder{
+		ID:    plistString(node, "WebBookmarkUUID"),
+		Title: plistString(node, "Title"),
+	}
+
+	for _, child := range plistChildren(node) {
+		addSafariNode(folder, child)
+	}
+
+	return folder
+}
+
+// plistChildren returns the child nodes of a list node
+func plistChildren(node map[string]any) []map[string]any {
+	values, _ := node["Children"].([]any)
+	children := make([]map[string]any, 0, len(values))
+	for _, value := range values {
+		if child, ok := value.(map[string]any); ok {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// plistString returns a string value from a dictionary, or "" if missing
+func plistString(dict map[string]any, key string) string {
+	value, _ := dict[key].(string)
+	return value
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParseSafari(t *testing.T) {
+	file, err := os.Open("../../test/testdata/Bookmarks.plist")
+	if err != nil {
+		t.Fatalf("Failed to open Safari bookmarks: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewSafariParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse Safari bookmarks: %v", err)
+	}
+
+	// Menu contents at the top level, then the favorites bar and Reading List.
+	// The History proxy is skipped.
+	expectedTitles := []string{"Org Mode – Your life in plain text", "Linux", "Favorites", "Reading List"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+
+	favorites := root.Children[2].(*models.Folder)
+	if len(favorites.Properties) != 0 || len(favorites.Children) != 2 {
+		t.Errorf("Unexpected favorites folder: %+v", favorites)
+	}
+	webkit := favorites.Children[1].(*models.Folder).Children[0].(*models.Bookmark)
+	if webkit.URL != "https://webkit.org/" || webkit.ID != "B1E2A3C4-0000-4000-8000-000000000013" {
+		t.Errorf("Unexpected bookmark: %+v", webkit)
+	}
+
+	article := root.Children[3].(*models.Folder).Children[0].(*models.Bookmark)
+	if article.Description != "An article worth reading later" {
+		t.Errorf("Unexpected preview text: %q", article.Description)
+	}
+	if !article.AddDate.Equal(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date added: %v", article.AddDate)
+	}
+}
+
+func TestParseSafariInvalid(t *testing.T) {
+	for _, input := range []string{"not a plist", `<plist><array/></plist>`} {
+		parser := NewSafariParser(strings.NewReader(input))
+		if _, err := parser.Parse(); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
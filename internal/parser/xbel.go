@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// xbelMetadataOwner identifies the <metadata> blocks orgmarks uses to store
+// fields XBEL has no elements for (tags, keywords, favicons, properties)
+const xbelMetadataOwner = "https://github.com/drewherron/orgmarks"
+
+// xbelNode is an element of an XBEL document: the <xbel> root, a folder,
+// a bookmark, a separator or an alias
+type xbelNode struct {
+	XMLName  xml.Name
+	ID       string     `xml:"id,attr"`
+	Href     string     `xml:"href,attr"`
+	Ref      string     `xml:"ref,attr"`
+	Added    string     `xml:"added,attr"`
+	Modified string     `xml:"modified,attr"`
+	Title    string     `xml:"title"`
+	Desc     string     `xml:"desc"`
+	Metadata []xbelMeta `xml:"info>metadata"`
+	Children []xbelNode `xml:",any"`
+}
+
+// xbelMeta is a <metadata> block inside <info>
+type xbelMeta struct {
+	Owner      string         `xml:"owner,attr"`
+	Properties []xbelProperty `xml:"property"`
+}
+
+// xbelProperty is a single orgmarks property inside a <metadata> block
+type xbelProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// XBELParser parses XBEL (XML Bookmark Exchange Language) files,
+// as used by Floccus and several other bookmark tools
+type XBELParser struct {
+	reader io.Reader
+}
+
+// NewXBELParser creates a new XBEL parser from a reader
+func NewXBELParser(r io.Reader) *XBELParser {
+	return &XBELParser{
+		reader: r,
+	}
+}
+
+// Parse reads the XBEL document and returns the root folder.
+// Aliases are replaced by copies of the bookmarks they refer to.
+func (p *XBELParser) Parse() (*models.Folder, error) {
+	var doc xbelNode
+	if err := xml.NewDecoder(p.reader).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid XBEL: %w", err)
+	}
+	if doc.XMLName.Local != "xbel" {
+		return nil, fmt.Errorf("invalid XBEL: unexpected root element <%s>", doc.XMLName.Local)
+	}
+
+	root := &models.Folder{
+		Title: "Bookmarks",
+	}
+
+	// Index bookmarks by id so aliases can be resolved
+	bookmarks := make(map[string]*xbelNode)
+	indexXBELBookmarks(&doc, bookmarks)
+
+	for i := range doc.Children {
+		addXBELNode(root, &doc.Children[i], bookmarks)
+	}
+
+	return root, nil
+}
+
+// indexXBELBookmarks collects all bookmarks with an id
+func indexXBELBookmarks(node *xbelNode, bookmarks map[string]*xbelNode) {
+	for i := range node.Children {
+		child := &node.Children[i]
+		switch child.XMLName.Local {
+		case "bookmark":
+			if child.ID != "" {
+				bookmarks[child.ID] = child
+			}
+		case "folder":
+			indexXBELBookmarks(child, bookmarks)
+		}
+	}
+}
+
+// addXBELNode converts an element and adds it to the parent folder
+func addXBELNode(parent *models.Folder, node *xbelNode, bookmarks map[string]*xbelNode) {
+	switch node.XMLName.Local {
+	case "folder":
+		folder := &models.Folder{
+			Title:   strings.TrimSpace(node.Title),
+			AddDate: xbelTime(node.Added),
+		}
+		meta := xbelMetadata(node)
+		folder.ID = meta.id
+		folder.Role = meta.role
+		folder.LastModified = meta.lastModified
+		folder.Properties = meta.properties
+		if desc := strings.TrimSpace(node.Desc); desc != "" {
+			// Folders have no description field, keep it as a property
+			if folder.Properties == nil {
+				folder.Properties = make(map[string]string)
+			}
+			folder.Properties["DESCRIPTION"] = desc
+		}
+
+		for i := range node.Children {
+			addXBELNode(folder, &node.Children[i], bookmarks)
+		}
+		parent.AddChild(folder)
+	case "bookmark":
+		if node.Href == "" {
+			return
+		}
+		parent.AddChild(xbelBookmark(node))
+	case "alias":
+		if target, ok := bookmarks[node.Ref]; ok && target.Href != "" {
+			bookmark := xbelBookmark(target)
+			// The alias isn't the original bookmark, don't duplicate its ids
+			bookmark.ID = ""
+			delete(bookmark.Properties, "XBEL_ID")
+			parent.AddChild(bookmark)
+		}
+	case "separator":
+		parent.AddChild(&models.Separator{})
+	}
+}
+
+// xbelBookmark converts a bookmark element
+func xbelBookmark(node *xbelNode) *models.Bookmark {
+	meta := xbelMetadata(node)
+	bookmark := &models.Bookmark{
+		ID:           meta.id,
+		URL:          node.Href,
+		Title:        strings.TrimSpace(node.Title),
+		ShortcutURL:  meta.shortcutURL,
+		AddDate:      xbelTime(node.Added),
+		LastModified: xbelTime(node.Modified),
+		Description:  strings.TrimSpace(node.Desc),
+		Icon:         meta.icon,
+		IconURI:      meta.iconURI,
+		Properties:   meta.properties,
+	}
+
+	if bookmark.LastModified.IsZero() {
+		bookmark.LastModified = meta.lastModified
+	}
+
+	// Tags are stored as a comma-separated property
+	if tags, ok := bookmark.Properties["TAGS"]; ok {
+		delete(bookmark.Properties, "TAGS")
+		for _, tag := range strings.Split(tags, ",") {
+			if trimmed := strings.TrimSpace(tag); trimmed != "" {
+				bookmark.Tags = append(bookmark.Tags, trimmed)
+			}
+		}
+	}
+
+	if len(bookmark.Properties) == 0 {
+		bookmark.Properties = nil
+	}
+
+	return bookmark
+}
+
+// xbelMetadata collects the orgmarks properties and the element's XBEL id.
+// Metadata from other applications is ignored.
+func xbelMetadata(node *xbelNode) metadata {
+	var meta metadata
+	for _, block := range node.Metadata {
+		if block.Owner != xbelMetadataOwner {
+			continue
+		}
+		for _, prop := range block.Properties {
+			if prop.Name != "" {
+				meta.set(strings.ToUpper(prop.Name), prop.Value)
+			}
+		}
+	}
+
+	// Keep the id so sync tools see the same items when the file is written back
+	if node.ID != "" {
+		meta.set("XBEL_ID", node.ID)
+	}
+
+	return meta
+}
+
+// xbelTime parses an XBEL date attribute. XBEL uses ISO 8601 dates,
+// but some tools write Unix timestamps instead.
+func xbelTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	if t, ok := parseTimestamp(value); ok {
+		return t
+	}
+	return time.Time{}
+}
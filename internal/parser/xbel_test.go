@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+func TestParseXBEL(t *testing.T) {
+	file, err := os.Open("../../test/testdata/bookmarks.xbel")
+	if err != nil {
+		t.Fatalf("Failed to open XBEL file: %v", err)
+	}
+	defer file.Close()
+
+	parser := NewXBELParser(file)
+	root, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse XBEL: %v", err)
+	}
+
+	// The bookmark without a URL is skipped
+	expectedTitles := []string{"Org Mode", "Linux", "GNU <Project>"}
+	if len(root.Children) != len(expectedTitles) {
+		t.Fatalf("Expected %d top-level children, got %d", len(expectedTitles), len(root.Children))
+	}
+	for i, child := range root.Children {
+		if child.GetTitle() != expectedTitles[i] {
+			t.Errorf("Expected child %d to be '%s', got '%s'", i, expectedTitles[i], child.GetTitle())
+		}
+	}
+
+	org := root.Children[0].(*models.Bookmark)
+	if org.Description != "Your life in plain text" {
+		t.Errorf("Unexpected description: %q", org.Description)
+	}
+	if org.AddDate.Unix() != 1702685191 {
+		t.Errorf("Unexpected add date: %v", org.AddDate)
+	}
+	if org.Properties["XBEL_ID"] != "1" {
+		t.Errorf("Expected XBEL id to be kept, got %v", org.Properties)
+	}
+
+	// Separator kept, alias resolved to a copy of the bookmark
+	linux := root.Children[1].(*models.Folder)
+	if len(linux.Children) != 4 {
+		t.Fatalf("Expected 4 children in Linux, got %d", len(linux.Children))
+	}
+	if !models.IsSeparator(linux.Children[1]) {
+		t.Errorf("Expected a separator, got %#v", linux.Children[1])
+	}
+	if linux.Properties["DESCRIPTION"] != "Distributions and news" {
+		t.Errorf("Expected folder description property, got %v", linux.Properties)
+	}
+
+	magazine := linux.Children[0].(*models.Bookmark)
+	if magazine.URL != "https://fedoramagazine.org/?a=1&b=2" {
+		t.Errorf("Unexpected URL: %s", magazine.URL)
+	}
+	if len(magazine.Tags) != 2 || magazine.Tags[0] != "news" || magazine.Tags[1] != "linux" {
+		t.Errorf("Expected tags [news linux], got %v", magazine.Tags)
+	}
+	if magazine.ShortcutURL != "mag" || magazine.ID != "V2n0aJ7kP1sD" {
+		t.Errorf("Metadata not parsed: %+v", magazine)
+	}
+	if magazine.LastModified.Unix() != 1503757786 {
+		t.Errorf("Unexpected modification date: %v", magazine.LastModified)
+	}
+
+	alias := linux.Children[3].(*models.Bookmark)
+	if alias.URL != "https://orgmode.org/" || alias.Properties["XBEL_ID"] != "" || alias.ID != "" {
+		t.Errorf("Alias not resolved correctly: %+v", alias)
+	}
+}
+
+func TestParseXBELInvalid(t *testing.T) {
+	for _, input := range []string{"<xbel><folder>", "<html></html>"} {
+		parser := NewXBELParser(strings.NewReader(input))
+		if _, err := parser.Parse(); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
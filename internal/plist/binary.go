@@ -0,0 +1,466 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+	"unicode/utf16"
+)
+
+// trailerSize is the size of the trailer at the end of a binary plist
+const trailerSize = 32
+
+// Binary object markers (high nibble)
+const (
+	markerSimple = 0x00
+	markerInt    = 0x10
+	markerReal   = 0x20
+	markerDate   = 0x30
+	markerData   = 0x40
+	markerASCII  = 0x50
+	markerUTF16  = 0x60
+	markerUID    = 0x80
+	markerArray  = 0xa0
+	markerDict   = 0xd0
+)
+
+// binaryDecoder reads objects from a binary plist
+type binaryDecoder struct {
+	data       []byte
+	offsets    []uint64
+	objRefSize int
+}
+
+// decodeBinary parses a binary plist
+func decodeBinary(data []byte) (any, error) {
+	if len(data) < len(binaryMagic)+trailerSize {
+		return nil, errors.New("plist: truncated binary plist")
+	}
+
+	trailer := data[len(data)-trailerSize:]
+	offsetSize := int(trailer[6])
+	objRefSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetSize < 1 || offsetSize > 8 || objRefSize < 1 || objRefSize > 8 {
+		return nil, errors.New("plist: invalid binary plist trailer")
+	}
+	tableEnd := uint64(len(data) - trailerSize)
+	if tableOffset > tableEnd || numObjects > (tableEnd-tableOffset)/uint64(offsetSize) || topObject >= numObjects {
+		return nil, errors.New("plist: invalid binary plist trailer")
+	}
+
+	d := &binaryDecoder{
+		data:       data,
+		offsets:    make([]uint64, numObjects),
+		objRefSize: objRefSize,
+	}
+	for i := range d.offsets {
+		start := int(tableOffset) + i*offsetSize
+		d.offsets[i] = readUint(data[start : start+offsetSize])
+	}
+
+	return d.object(topObject, 0)
+}
+
+// object decodes the object with the given reference
+func (d *binaryDecoder) object(ref uint64, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("plist: nesting too deep")
+	}
+	if ref >= uint64(len(d.offsets)) {
+		return nil, fmt.Errorf("plist: invalid object reference %d", ref)
+	}
+	offset := d.offsets[ref]
+	if offset >= uint64(len(d.data)-trailerSize) {
+		return nil, fmt.Errorf("plist: invalid object offset %d", offset)
+	}
+
+	pos := int(offset)
+	marker := d.data[pos]
+	info := int(marker & 0x0f)
+	pos++
+
+	switch marker & 0xf0 {
+	case markerSimple:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case markerInt:
+		size := 1 << info
+		raw, err := d.bytes(pos, size)
+		if err != nil {
+			return nil, err
+		}
+		if size == 16 {
+			// 128-bit integers only occur for large unsigned values; keep the low half
+			raw = raw[8:]
+		}
+		if size >= 8 {
+			return int64(binary.BigEndian.Uint64(raw)), nil
+		}
+		return int64(readUint(raw)), nil
+	case markerReal:
+		raw, err := d.bytes(pos, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		switch len(raw) {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+		}
+		return nil, fmt.Errorf("plist: invalid real size %d", len(raw))
+	case markerDate:
+		raw, err := d.bytes(pos, 8)
+		if err != nil {
+			return nil, err
+		}
+		return dateFromSeconds(math.Float64frombits(binary.BigEndian.Uint64(raw))), nil
+	case markerData:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.bytes(pos, count)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Clone(raw), nil
+	case markerASCII:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.bytes(pos, count)
+		if err != nil {
+			return nil, err
+		}
+		return string(raw), nil
+	case markerUTF16:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.bytes(pos, 2*count)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(raw[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case markerUID:
+		raw, err := d.bytes(pos, info+1)
+		if err != nil {
+			return nil, err
+		}
+		return UID(readUint(raw)), nil
+	case markerArray:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(pos, count)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]any, count)
+		for i, ref := range refs {
+			if array[i], err = d.object(ref, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case markerDict:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(pos, 2*count)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]any, count)
+		for i := 0; i < count; i++ {
+			key, err := d.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, errors.New("plist: dictionary key is not a string")
+			}
+			if dict[keyString], err = d.object(refs[count+i], depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+
+	return nil, fmt.Errorf("plist: unknown object marker %#x", marker)
+}
+
+// count reads the element count of a data, string, array or dict object.
+// Counts of 15 or more are stored as a following integer object.
+func (d *binaryDecoder) count(info, pos int) (int, int, error) {
+	if info != 0x0f {
+		return info, pos, nil
+	}
+	if pos >= len(d.data) || d.data[pos]&0xf0 != markerInt {
+		return 0, 0, errors.New("plist: invalid object count")
+	}
+	size := 1 << (d.data[pos] & 0x0f)
+	raw, err := d.bytes(pos+1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	count := readUint(raw)
+	if count > uint64(len(d.data)) {
+		return 0, 0, errors.New("plist: invalid object count")
+	}
+	return int(count), pos + 1 + size, nil
+}
+
+// refs reads a list of object references
+func (d *binaryDecoder) refs(pos, count int) ([]uint64, error) {
+	raw, err := d.bytes(pos, count*d.objRefSize)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, count)
+	for i := range refs {
+		refs[i] = readUint(raw[i*d.objRefSize : (i+1)*d.objRefSize])
+	}
+	return refs, nil
+}
+
+// bytes returns n bytes starting at pos
+func (d *binaryDecoder) bytes(pos, n int) ([]byte, error) {
+	if n < 0 || pos+n > len(d.data) {
+		return nil, errors.New("plist: truncated object")
+	}
+	return d.data[pos : pos+n], nil
+}
+
+// readUint reads a big-endian unsigned integer of 1-8 bytes
+func readUint(b []byte) uint64 {
+	var value uint64
+	for _, c := range b {
+		value = value<<8 | uint64(c)
+	}
+	return value
+}
+
+// dateFromSeconds converts seconds since the Apple epoch to a time
+func dateFromSeconds(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return appleEpoch.Add(time.Duration(whole) * time.Second).Add(time.Duration(frac * float64(time.Second)))
+}
+
+// binaryEncoder flattens values into the object list of a binary plist
+type binaryEncoder struct {
+	objects [][]byte // Encoded objects, with references still to be sized
+	refs    [][]int  // Object references of arrays and dicts
+	strings map[string]int
+}
+
+// EncodeBinary encodes a value as a binary plist
+func EncodeBinary(v any) ([]byte, error) {
+	e := &binaryEncoder{strings: make(map[string]int)}
+	if _, err := e.add(v, 0); err != nil {
+		return nil, err
+	}
+
+	refSize := intSize(uint64(len(e.objects)))
+
+	var buf bytes.Buffer
+	buf.Write(binaryMagic)
+	offsets := make([]uint64, len(e.objects))
+	for i, object := range e.objects {
+		offsets[i] = uint64(buf.Len())
+		buf.Write(object)
+		for _, ref := range e.refs[i] {
+			buf.Write(uintBytes(uint64(ref), refSize))
+		}
+	}
+
+	tableOffset := uint64(buf.Len())
+	offsetSize := intSize(tableOffset)
+	for _, offset := range offsets {
+		buf.Write(uintBytes(offset, offsetSize))
+	}
+
+	trailer := make([]byte, trailerSize)
+	trailer[6] = byte(offsetSize)
+	trailer[7] = byte(refSize)
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(e.objects)))
+	binary.BigEndian.PutUint64(trailer[16:], 0)
+	binary.BigEndian.PutUint64(trailer[24:], tableOffset)
+	buf.Write(trailer)
+
+	return buf.Bytes(), nil
+}
+
+// add encodes a value and its children, returning its object reference.
+// Identical strings are only stored once.
+func (e *binaryEncoder) add(v any, depth int) (int, error) {
+	if depth > maxDepth {
+		return 0, errors.New("plist: nesting too deep")
+	}
+
+	if s, ok := v.(string); ok {
+		if ref, ok := e.strings[s]; ok {
+			return ref, nil
+		}
+	}
+
+	ref := len(e.objects)
+	e.objects = append(e.objects, nil)
+	e.refs = append(e.refs, nil)
+
+	var object []byte
+	switch value := v.(type) {
+	case bool:
+		object = []byte{0x08}
+		if value {
+			object[0] = 0x09
+		}
+	case int:
+		object = encodeInt(int64(value))
+	case int64:
+		object = encodeInt(value)
+	case float64:
+		object = make([]byte, 9)
+		object[0] = markerReal | 3
+		binary.BigEndian.PutUint64(object[1:], math.Float64bits(value))
+	case time.Time:
+		object = make([]byte, 9)
+		object[0] = markerDate | 3
+		seconds := float64(value.Sub(appleEpoch)) / float64(time.Second)
+		binary.BigEndian.PutUint64(object[1:], math.Float64bits(seconds))
+	case []byte:
+		object = append(encodeHeader(markerData, len(value)), value...)
+	case string:
+		e.strings[value] = ref
+		object = encodeString(value)
+	case []any:
+		object = encodeHeader(markerArray, len(value))
+		children := make([]int, len(value))
+		for i, child := range value {
+			childRef, err := e.add(child, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			children[i] = childRef
+		}
+		e.refs[ref] = children
+	case map[string]any:
+		// Sort keys for stable output
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		object = encodeHeader(markerDict, len(keys))
+		children := make([]int, 2*len(keys))
+		for i, key := range keys {
+			keyRef, err := e.add(key, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			valueRef, err := e.add(value[key], depth+1)
+			if err != nil {
+				return 0, err
+			}
+			children[i] = keyRef
+			children[len(keys)+i] = valueRef
+		}
+		e.refs[ref] = children
+	default:
+		return 0, fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+	}
+
+	e.objects[ref] = object
+	return ref, nil
+}
+
+// encodeInt encodes an integer object in the smallest size. Negative
+// numbers always take 8 bytes, as only 8-byte integers are signed.
+func encodeInt(value int64) []byte {
+	size := 8
+	if value >= 0 {
+		size = intSize(uint64(value))
+		if size == 3 {
+			size = 4
+		} else if size > 4 {
+			size = 8
+		}
+	}
+	// The marker stores the size as a power of two
+	info := byte(bits.TrailingZeros(uint(size)))
+	return append([]byte{markerInt | info}, uintBytes(uint64(value), size)...)
+}
+
+// encodeString encodes a string as ASCII if possible, or UTF-16BE otherwise
+func encodeString(s string) []byte {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return append(encodeHeader(markerASCII, len(s)), s...)
+	}
+
+	units := utf16.Encode([]rune(s))
+	object := encodeHeader(markerUTF16, len(units))
+	for _, unit := range units {
+		object = binary.BigEndian.AppendUint16(object, unit)
+	}
+	return object
+}
+
+// encodeHeader encodes an object marker with its element count
+func encodeHeader(marker byte, count int) []byte {
+	if count < 0x0f {
+		return []byte{marker | byte(count)}
+	}
+	return append([]byte{marker | 0x0f}, encodeInt(int64(count))...)
+}
+
+// intSize returns the number of bytes needed to store an unsigned value
+func intSize(value uint64) int {
+	size := 1
+	for value > 0xff {
+		value >>= 8
+		size++
+	}
+	return size
+}
+
+// uintBytes encodes an unsigned value big-endian in size bytes
+func uintBytes(value uint64, size int) []byte {
+	b := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		b[i] = byte(value)
+		value >>= 8
+	}
+	return b
+}
@@ -0,0 +1,51 @@
+// Package plist reads and writes Apple property lists in both the binary
+// ("bplist00") and XML formats, as used by Safari's Bookmarks.plist.
+//
+// Values are represented with plain Go types:
+//
+//	dict    map[string]any
+//	array   []any
+//	string  string
+//	integer int64
+//	real    float64
+//	boolean bool
+//	data    []byte
+//	date    time.Time
+//
+// Encoders also accept int for integers. Binary UIDs (used by NSKeyedArchiver)
+// are decoded as UID but can't be written.
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+// UID is a binary plist UID value
+type UID uint64
+
+// binaryMagic is the header that starts every binary plist
+var binaryMagic = []byte("bplist00")
+
+// appleEpoch is the reference date for binary plist dates (2001-01-01 UTC)
+var appleEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxDepth limits nesting, protecting against cyclic or corrupt files
+const maxDepth = 128
+
+// ErrUnsupportedType is returned when encoding a value with no plist equivalent
+var ErrUnsupportedType = errors.New("plist: unsupported type")
+
+// IsBinary reports whether data is a binary plist
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, binaryMagic)
+}
+
+// Decode parses a binary or XML property list
+func Decode(data []byte) (any, error) {
+	if IsBinary(data) {
+		return decodeBinary(data)
+	}
+	return decodeXML(data)
+}
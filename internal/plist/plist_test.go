@@ -0,0 +1,119 @@
+package plist
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleValue uses every supported type, including counts and integers
+// that need the extended encodings
+func sampleValue() map[string]any {
+	many := make([]any, 20)
+	for i := range many {
+		many[i] = int64(i * 1000)
+	}
+	return map[string]any{
+		"string":   "Hello <World> & friends",
+		"unicode":  "Org Mode – Ünïcödé 🚀",
+		"empty":    "",
+		"negative": int64(-42),
+		"large":    int64(1) << 40,
+		"real":     3.25,
+		"true":     true,
+		"false":    false,
+		"date":     time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		"data":     []byte{0, 1, 2, 0xff},
+		"array":    many,
+		"nested":   map[string]any{"list": []any{"a", "a", map[string]any{}}},
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	data, err := EncodeBinary(sampleValue())
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if !IsBinary(data) {
+		t.Fatal("Expected binary plist magic")
+	}
+
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, any(sampleValue())) {
+		t.Errorf("Round trip mismatch:\n got %#v\nwant %#v", decoded, sampleValue())
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	data, err := EncodeXML(sampleValue())
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("<?xml")) || !strings.Contains(string(data), "<string>Hello &lt;World&gt; &amp; friends</string>") {
+		t.Errorf("Unexpected XML:\n%s", data)
+	}
+
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, any(sampleValue())) {
+		t.Errorf("Round trip mismatch:\n got %#v\nwant %#v", decoded, sampleValue())
+	}
+}
+
+func TestDecodeSafariBookmarks(t *testing.T) {
+	// Written by Python's plistlib
+	data, err := os.ReadFile("../../test/testdata/Bookmarks.plist")
+	if err != nil {
+		t.Fatalf("Failed to read plist: %v", err)
+	}
+
+	value, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	root := value.(map[string]any)
+	if root["WebBookmarkFileVersion"] != int64(1) {
+		t.Errorf("Unexpected file version: %v", root["WebBookmarkFileVersion"])
+	}
+	children := root["Children"].([]any)
+	if len(children) != 4 {
+		t.Fatalf("Expected 4 children, got %d", len(children))
+	}
+	reading := children[3].(map[string]any)
+	if reading["ShouldOmitFromUI"] != true {
+		t.Error("Expected ShouldOmitFromUI to be true")
+	}
+	leaf := reading["Children"].([]any)[0].(map[string]any)
+	added := leaf["ReadingList"].(map[string]any)["DateAdded"].(time.Time)
+	if !added.Equal(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date: %v", added)
+	}
+	menu := children[2].(map[string]any)
+	title := menu["Children"].([]any)[0].(map[string]any)["URIDictionary"].(map[string]any)["title"]
+	if title != "Org Mode – Your life in plain text" {
+		t.Errorf("Unexpected UTF-16 string: %q", title)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, input := range []string{"bplist00", "<plist><dict><key>a</key></dict></plist>", "not a plist"} {
+		if _, err := Decode([]byte(input)); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	if _, err := EncodeBinary(map[string]any{"a": struct{}{}}); err == nil {
+		t.Error("Expected an error for an unsupported type")
+	}
+}
@@ -0,0 +1,256 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xmlDateLayout is the date format used in XML plists
+const xmlDateLayout = "2006-01-02T15:04:05Z"
+
+// xmlHeader starts every XML plist
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+// decodeXML parses an XML plist
+func decodeXML(data []byte) (any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	// Find the first value element inside <plist>
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, errors.New("plist: no value found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("plist: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return decodeXMLValue(decoder, start, 0)
+		}
+	}
+}
+
+// decodeXMLValue decodes the value of an element whose start tag was just read
+func decodeXMLValue(decoder *xml.Decoder, start xml.StartElement, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("plist: nesting too deep")
+	}
+
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]any)
+		for {
+			keyStart, ok, err := nextStart(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return dict, nil
+			}
+			if keyStart.Name.Local != "key" {
+				return nil, fmt.Errorf("plist: expected <key>, got <%s>", keyStart.Name.Local)
+			}
+			key, err := elementText(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			valueStart, ok, err := nextStart(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("plist: missing value for key %q", key)
+			}
+			if dict[key], err = decodeXMLValue(decoder, valueStart, depth+1); err != nil {
+				return nil, err
+			}
+		}
+	case "array":
+		array := []any{}
+		for {
+			elementStart, ok, err := nextStart(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return array, nil
+			}
+			value, err := decodeXMLValue(decoder, elementStart, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := elementText(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+	case "integer":
+		value, err := strconv.ParseInt(strings.TrimSpace(text), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid integer %q", text)
+		}
+		return value, nil
+	case "real":
+		value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid real %q", text)
+		}
+		return value, nil
+	case "date":
+		value, err := time.Parse(xmlDateLayout, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid date %q", text)
+		}
+		return value, nil
+	case "data":
+		value, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid data: %w", err)
+		}
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("plist: unknown element <%s>", start.Name.Local)
+}
+
+// nextStart returns the next start element, or ok=false at the end of the
+// enclosing element
+func nextStart(decoder *xml.Decoder) (xml.StartElement, bool, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, false, fmt.Errorf("plist: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t, true, nil
+		case xml.EndElement:
+			return xml.StartElement{}, false, nil
+		}
+	}
+}
+
+// elementText reads the text content up to the end of the current element
+func elementText(decoder *xml.Decoder) (string, error) {
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("plist: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			return "", fmt.Errorf("plist: unexpected <%s>", t.Name.Local)
+		case xml.EndElement:
+			return text.String(), nil
+		}
+	}
+}
+
+// EncodeXML encodes a value as an XML plist, indented with tabs like Apple's tools
+func EncodeXML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xmlHeader)
+	if err := encodeXMLValue(&buf, v, 0); err != nil {
+		return nil, err
+	}
+	buf.WriteString("</plist>\n")
+	return buf.Bytes(), nil
+}
+
+// encodeXMLValue writes a value and its children
+func encodeXMLValue(buf *bytes.Buffer, v any, depth int) error {
+	if depth > maxDepth {
+		return errors.New("plist: nesting too deep")
+	}
+	indent := strings.Repeat("\t", depth)
+
+	switch value := v.(type) {
+	case bool:
+		if value {
+			fmt.Fprintf(buf, "%s<true/>\n", indent)
+		} else {
+			fmt.Fprintf(buf, "%s<false/>\n", indent)
+		}
+	case int:
+		fmt.Fprintf(buf, "%s<integer>%d</integer>\n", indent, value)
+	case int64:
+		fmt.Fprintf(buf, "%s<integer>%d</integer>\n", indent, value)
+	case float64:
+		fmt.Fprintf(buf, "%s<real>%s</real>\n", indent, strconv.FormatFloat(value, 'g', -1, 64))
+	case time.Time:
+		fmt.Fprintf(buf, "%s<date>%s</date>\n", indent, value.UTC().Format(xmlDateLayout))
+	case []byte:
+		fmt.Fprintf(buf, "%s<data>%s</data>\n", indent, base64.StdEncoding.EncodeToString(value))
+	case string:
+		fmt.Fprintf(buf, "%s<string>%s</string>\n", indent, escapeXML(value))
+	case []any:
+		if len(value) == 0 {
+			fmt.Fprintf(buf, "%s<array/>\n", indent)
+			return nil
+		}
+		fmt.Fprintf(buf, "%s<array>\n", indent)
+		for _, child := range value {
+			if err := encodeXMLValue(buf, child, depth+1); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(buf, "%s</array>\n", indent)
+	case map[string]any:
+		if len(value) == 0 {
+			fmt.Fprintf(buf, "%s<dict/>\n", indent)
+			return nil
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(buf, "%s<dict>\n", indent)
+		for _, key := range keys {
+			fmt.Fprintf(buf, "%s\t<key>%s</key>\n", indent, escapeXML(key))
+			if err := encodeXMLValue(buf, value[key], depth+1); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(buf, "%s</dict>\n", indent)
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+	}
+
+	return nil
+}
+
+// escapeXML escapes text for use in XML element content
+func escapeXML(s string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"strings"
+)
+
+// tableConstraints are the keywords that start a table constraint
+// rather than a column definition
+var tableConstraints = []string{"CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN"}
+
+// parseCreateTable extracts the column names from a CREATE TABLE statement.
+// rowidCol is the index of the INTEGER PRIMARY KEY column (an alias for the
+// rowid, stored as NULL in records), or -1. ok is false for tables that
+// can't be read, such as WITHOUT ROWID and virtual tables.
+func parseCreateTable(sql string) (columns []string, rowidCol int, ok bool) {
+	rowidCol = -1
+
+	open := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if open < 0 || end < open {
+		return nil, -1, false
+	}
+
+	upper := strings.ToUpper(sql)
+	if strings.Contains(upper[end:], "WITHOUT ROWID") || strings.HasPrefix(strings.TrimSpace(upper), "CREATE VIRTUAL") {
+		return nil, -1, false
+	}
+
+	for _, definition := range splitDefinitions(sql[open+1 : end]) {
+		fields := strings.Fields(definition)
+		if len(fields) == 0 {
+			continue
+		}
+
+		isConstraint := false
+		for _, keyword := range tableConstraints {
+			if strings.EqualFold(fields[0], keyword) {
+				isConstraint = true
+				break
+			}
+		}
+		if isConstraint {
+			continue
+		}
+
+		if len(fields) >= 2 && strings.EqualFold(fields[1], "INTEGER") &&
+			strings.Contains(strings.ToUpper(definition), "PRIMARY KEY") {
+			rowidCol = len(columns)
+		}
+		columns = append(columns, unquoteIdentifier(fields[0]))
+	}
+
+	return columns, rowidCol, len(columns) > 0
+}
+
+// splitDefinitions splits the body of a CREATE TABLE statement on commas
+// that aren't inside parentheses or quotes
+func splitDefinitions(body string) []string {
+	var definitions []string
+	depth := 0
+	var quote rune
+	start := 0
+
+	for i, r := range body {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '[':
+			quote = ']'
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			definitions = append(definitions, body[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(definitions, body[start:])
+}
+
+// unquoteIdentifier removes SQL identifier quotes
+func unquoteIdentifier(name string) string {
+	if len(name) >= 2 {
+		first, last := name[0], name[len(name)-1]
+		if first == '"' && last == '"' || first == '`' && last == '`' || first == '[' && last == ']' {
+			return name[1 : len(name)-1]
+		}
+	}
+	return name
+}
@@ -0,0 +1,405 @@
+// Package sqlite is a minimal, read-only reader for SQLite 3 database files.
+//
+// It only walks table b-trees and decodes records, which is enough to read
+// small application databases such as Firefox's places.sqlite without cgo
+// or third-party drivers. There is no SQL engine: tables are read in full
+// and returned as rows keyed by column name. Changes that are still in a
+// write-ahead log (the -wal file next to the database) are not seen.
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// magic is the header string that starts every SQLite 3 database
+const magic = "SQLite format 3\x00"
+
+// headerSize is the size of the database header at the start of page 1
+const headerSize = 100
+
+// maxDepth limits b-tree recursion, protecting against corrupt files
+const maxDepth = 32
+
+// b-tree page types
+const (
+	pageInteriorTable = 0x05
+	pageLeafTable     = 0x0d
+)
+
+// ErrNotSQLite is returned when data doesn't start with the SQLite header
+var ErrNotSQLite = errors.New("sqlite: not a SQLite 3 database")
+
+// Row is a table row keyed by column name. Values are nil, int64, float64,
+// string or []byte.
+type Row map[string]any
+
+// DB is a SQLite database loaded into memory
+type DB struct {
+	data       []byte
+	pageSize   int
+	usableSize int
+	tables     map[string]*table
+}
+
+// table describes a table from the schema
+type table struct {
+	name     string
+	rootPage int
+	columns  []string
+	rowidCol int // Index of the INTEGER PRIMARY KEY column, or -1
+}
+
+// Open reads a database file into memory
+func Open(filename string) (*DB, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return New(data)
+}
+
+// New reads a database from its raw bytes
+func New(data []byte) (*DB, error) {
+	if len(data) < headerSize || !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, ErrNotSQLite
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("sqlite: invalid page size %d", pageSize)
+	}
+	if encoding := binary.BigEndian.Uint32(data[56:60]); encoding > 1 {
+		return nil, fmt.Errorf("sqlite: unsupported text encoding %d (only UTF-8 is supported)", encoding)
+	}
+
+	db := &DB{
+		data:       data,
+		pageSize:   pageSize,
+		usableSize: pageSize - int(data[20]),
+		tables:     make(map[string]*table),
+	}
+
+	if err := db.readSchema(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// HasTable reports whether the database has a table with the given name
+func (db *DB) HasTable(name string) bool {
+	_, ok := db.tables[strings.ToLower(name)]
+	return ok
+}
+
+// Rows returns all rows of a table in rowid order
+func (db *DB) Rows(name string) ([]Row, error) {
+	t, ok := db.tables[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("sqlite: no such table: %s", name)
+	}
+
+	var rows []Row
+	err := db.walkTable(t.rootPage, func(rowid int64, values []any) {
+		row := make(Row, len(t.columns))
+		for i, column := range t.columns {
+			switch {
+			case i == t.rowidCol:
+				row[column] = rowid
+			case i < len(values):
+				row[column] = values[i]
+			default:
+				// Columns added later with ALTER TABLE are missing from old records
+				row[column] = nil
+			}
+		}
+		rows = append(rows, row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: reading %s: %w", name, err)
+	}
+
+	return rows, nil
+}
+
+// readSchema reads the table definitions from the schema table on page 1
+func (db *DB) readSchema() error {
+	return db.walkTable(1, func(rowid int64, values []any) {
+		// Schema columns: type, name, tbl_name, rootpage, sql
+		if len(values) < 5 || values[0] != "table" {
+			return
+		}
+		name, _ := values[1].(string)
+		rootPage, _ := values[3].(int64)
+		sql, _ := values[4].(string)
+		if name == "" || rootPage == 0 {
+			return
+		}
+
+		columns, rowidCol, ok := parseCreateTable(sql)
+		if !ok {
+			// WITHOUT ROWID and virtual tables aren't supported
+			return
+		}
+		db.tables[strings.ToLower(name)] = &table{
+			name:     name,
+			rootPage: int(rootPage),
+			columns:  columns,
+			rowidCol: rowidCol,
+		}
+	})
+}
+
+// page returns the contents of a page (numbered from 1)
+func (db *DB) page(number int) ([]byte, error) {
+	start := (number - 1) * db.pageSize
+	if number < 1 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("page %d out of range", number)
+	}
+	return db.data[start : start+db.pageSize], nil
+}
+
+// walkTable calls fn for every record in a table b-tree, in rowid order
+func (db *DB) walkTable(rootPage int, fn func(rowid int64, values []any)) error {
+	return db.walkPage(rootPage, 0, make(map[int]bool), fn)
+}
+
+// walkPage walks the b-tree below a page. Pages that were already visited
+// are an error, as in a corrupt file they could make the walk loop or read
+// the same rows over and over.
+func (db *DB) walkPage(pageNumber, depth int, visited map[int]bool, fn func(rowid int64, values []any)) error {
+	if depth > maxDepth {
+		return errors.New("b-tree too deep")
+	}
+	if visited[pageNumber] {
+		return fmt.Errorf("page %d: visited twice", pageNumber)
+	}
+	visited[pageNumber] = true
+
+	page, err := db.page(pageNumber)
+	if err != nil {
+		return err
+	}
+
+	// Page 1 starts with the database header
+	offset := 0
+	if pageNumber == 1 {
+		offset = headerSize
+	}
+	if offset+8 > len(page) {
+		return fmt.Errorf("page %d: truncated header", pageNumber)
+	}
+
+	pageType := page[offset]
+	cellCount := int(binary.BigEndian.Uint16(page[offset+3 : offset+5]))
+
+	headerLen := 8
+	if pageType == pageInteriorTable {
+		headerLen = 12
+	}
+	pointers := offset + headerLen
+	if pointers+2*cellCount > len(page) {
+		return fmt.Errorf("page %d: too many cells", pageNumber)
+	}
+
+	for i := 0; i < cellCount; i++ {
+		cell := int(binary.BigEndian.Uint16(page[pointers+2*i:]))
+		if cell >= len(page) {
+			return fmt.Errorf("page %d: invalid cell pointer", pageNumber)
+		}
+
+		switch pageType {
+		case pageInteriorTable:
+			if cell+4 > len(page) {
+				return fmt.Errorf("page %d: truncated cell", pageNumber)
+			}
+			child := int(binary.BigEndian.Uint32(page[cell:]))
+			if err := db.walkPage(child, depth+1, visited, fn); err != nil {
+				return err
+			}
+		case pageLeafTable:
+			rowid, payload, err := db.leafCell(page, cell)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pageNumber, err)
+			}
+			values, err := decodeRecord(payload)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pageNumber, err)
+			}
+			fn(rowid, values)
+		default:
+			return fmt.Errorf("page %d: not a table b-tree page (type %#x)", pageNumber, pageType)
+		}
+	}
+
+	if pageType == pageInteriorTable {
+		rightMost := int(binary.BigEndian.Uint32(page[offset+8 : offset+12]))
+		return db.walkPage(rightMost, depth+1, visited, fn)
+	}
+
+	return nil
+}
+
+// leafCell reads a table leaf cell, following overflow pages for large records
+func (db *DB) leafCell(page []byte, cell int) (int64, []byte, error) {
+	size, n := readVarint(page[cell:])
+	if n == 0 {
+		return 0, nil, errors.New("invalid payload size")
+	}
+	cell += n
+	rowid, n := readVarint(page[cell:])
+	if n == 0 {
+		return 0, nil, errors.New("invalid rowid")
+	}
+	cell += n
+
+	payloadSize := int(size)
+	if payloadSize < 0 || payloadSize > len(db.data) {
+		return 0, nil, errors.New("invalid payload size")
+	}
+
+	// How much of the payload is stored on the page itself
+	usable := db.usableSize
+	maxLocal := usable - 35
+	local := payloadSize
+	if payloadSize > maxLocal {
+		minLocal := (usable-12)*32/255 - 23
+		local = minLocal + (payloadSize-minLocal)%(usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+
+	if cell+local > len(page) {
+		return 0, nil, errors.New("truncated cell")
+	}
+	if local == payloadSize {
+		return int64(rowid), page[cell : cell+local], nil
+	}
+
+	// The rest of the payload is in a linked list of overflow pages
+	payload := make([]byte, 0, payloadSize)
+	payload = append(payload, page[cell:cell+local]...)
+	if cell+local+4 > len(page) {
+		return 0, nil, errors.New("truncated cell")
+	}
+	next := int(binary.BigEndian.Uint32(page[cell+local:]))
+	for len(payload) < payloadSize {
+		overflow, err := db.page(next)
+		if err != nil {
+			return 0, nil, fmt.Errorf("overflow: %w", err)
+		}
+		chunk := min(payloadSize-len(payload), usable-4)
+		payload = append(payload, overflow[4:4+chunk]...)
+		next = int(binary.BigEndian.Uint32(overflow))
+	}
+
+	return int64(rowid), payload, nil
+}
+
+// decodeRecord decodes a record into its column values
+func decodeRecord(payload []byte) ([]any, error) {
+	headerLen, n := readVarint(payload)
+	if n == 0 || int(headerLen) > len(payload) {
+		return nil, errors.New("invalid record header")
+	}
+
+	var serialTypes []uint64
+	for pos := n; pos < int(headerLen); {
+		serialType, n := readVarint(payload[pos:headerLen])
+		if n == 0 {
+			return nil, errors.New("invalid record header")
+		}
+		serialTypes = append(serialTypes, serialType)
+		pos += n
+	}
+
+	values := make([]any, len(serialTypes))
+	body := payload[headerLen:]
+	for i, serialType := range serialTypes {
+		size := serialTypeSize(serialType)
+		if size > len(body) {
+			return nil, errors.New("truncated record")
+		}
+		values[i] = decodeValue(serialType, body[:size])
+		body = body[size:]
+	}
+
+	return values, nil
+}
+
+// serialTypeSize returns the number of bytes a value of the serial type takes
+func serialTypeSize(serialType uint64) int {
+	switch serialType {
+	case 0, 8, 9:
+		return 0
+	case 1, 2, 3, 4:
+		return int(serialType)
+	case 5:
+		return 6
+	case 6, 7:
+		return 8
+	}
+	if serialType >= 12 {
+		return int((serialType - 12) / 2)
+	}
+	// 10 and 11 are reserved
+	return 0
+}
+
+// decodeValue decodes a single value of a record
+func decodeValue(serialType uint64, data []byte) any {
+	switch serialType {
+	case 0, 10, 11:
+		return nil
+	case 1, 2, 3, 4, 5, 6:
+		// Big-endian two's complement integer of 1-8 bytes
+		var value int64
+		if data[0]&0x80 != 0 {
+			value = -1
+		}
+		for _, b := range data {
+			value = value<<8 | int64(b)
+		}
+		return value
+	case 7:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	case 8:
+		return int64(0)
+	case 9:
+		return int64(1)
+	}
+	if serialType%2 == 0 {
+		return bytes.Clone(data)
+	}
+	return string(data)
+}
+
+// readVarint reads a SQLite variable-length integer (1-9 bytes, big-endian).
+// It returns the value and the number of bytes read, or 0 if data is too short.
+func readVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(data) {
+			return 0, 0
+		}
+		if i == 8 {
+			// The ninth byte contributes all eight bits
+			return value<<8 | uint64(data[i]), 9
+		}
+		value = value<<7 | uint64(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return value, 9
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadPlaces(t *testing.T) {
+	db, err := Open("../../test/testdata/places.sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	for _, name := range []string{"moz_places", "moz_bookmarks", "moz_keywords", "MOZ_ORIGINS"} {
+		if !db.HasTable(name) {
+			t.Errorf("Expected table %s", name)
+		}
+	}
+	if db.HasTable("moz_bookmarks_guid_uniqueindex") {
+		t.Error("Indexes should not be listed as tables")
+	}
+
+	// Enough rows to need interior pages
+	bookmarks, err := db.Rows("moz_bookmarks")
+	if err != nil {
+		t.Fatalf("Failed to read moz_bookmarks: %v", err)
+	}
+	if len(bookmarks) != 219 {
+		t.Fatalf("Expected 219 bookmarks, got %d", len(bookmarks))
+	}
+	for i, row := range bookmarks {
+		if row["id"] != int64(i+1) {
+			t.Fatalf("Expected rows in rowid order, row %d has id %v", i, row["id"])
+		}
+	}
+
+	root := bookmarks[0]
+	if root["guid"] != "root________" || root["fk"] != nil || root["title"] != "" {
+		t.Errorf("Unexpected root row: %v", root)
+	}
+	magazine := bookmarks[11]
+	if magazine["title"] != "Fedora Magazine" || magazine["dateAdded"] != int64(1367341224000000) {
+		t.Errorf("Unexpected bookmark row: %v", magazine)
+	}
+
+	places, err := db.Rows("moz_places")
+	if err != nil {
+		t.Fatalf("Failed to read moz_places: %v", err)
+	}
+	var longURL string
+	for _, row := range places {
+		if row["frecency"] != int64(-1) {
+			t.Errorf("Expected negative frecency, got %v", row["frecency"])
+		}
+		if url := row["url"].(string); len(url) > len(longURL) {
+			longURL = url
+		}
+	}
+	// Stored in overflow pages
+	if len(longURL) < 4000 || !strings.HasSuffix(longURL, "&key299=value299") {
+		t.Errorf("Long URL not read correctly (%d bytes)", len(longURL))
+	}
+
+	if _, err := db.Rows("missing"); err == nil {
+		t.Error("Expected an error for a missing table")
+	}
+}
+
+func TestNotSQLite(t *testing.T) {
+	if _, err := New([]byte("not a database")); err != ErrNotSQLite {
+		t.Errorf("Expected ErrNotSQLite, got %v", err)
+	}
+}
+
+func TestPageVisitedTwice(t *testing.T) {
+	data, err := os.ReadFile("../../test/testdata/places.sqlite")
+	if err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+	db, err := New(data)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	// Point the right-most child of the moz_bookmarks root at its first child
+	// as well, so that subtree would be read twice
+	start := (db.tables["moz_bookmarks"].rootPage - 1) * db.pageSize
+	page := data[start : start+db.pageSize]
+	if page[0] != pageInteriorTable {
+		t.Fatalf("Expected an interior root page, got type %#x", page[0])
+	}
+	cell := binary.BigEndian.Uint16(page[12:])
+	copy(page[8:12], page[cell:cell+4])
+
+	if _, err := db.Rows("moz_bookmarks"); err == nil || !strings.Contains(err.Error(), "visited twice") {
+		t.Errorf("Expected an error for a page visited twice, got %v", err)
+	}
+}
+
+func TestParseCreateTable(t *testing.T) {
+	columns, rowidCol, ok := parseCreateTable(`CREATE TABLE moz_origins ( id INTEGER PRIMARY KEY, prefix TEXT NOT NULL, "host" TEXT DEFAULT ('a,b'), UNIQUE (prefix, host) )`)
+	if !ok || rowidCol != 0 || strings.Join(columns, ",") != "id,prefix,host" {
+		t.Errorf("Unexpected result: %v %d %v", columns, rowidCol, ok)
+	}
+
+	if _, _, ok := parseCreateTable("CREATE TABLE t (a TEXT PRIMARY KEY, b) WITHOUT ROWID"); ok {
+		t.Error("WITHOUT ROWID tables should not be readable")
+	}
+}
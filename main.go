@@ -1,21 +1,21 @@
 // Package main provides the orgmarks CLI tool for converting between
-// browser bookmark HTML files and Org-mode format.
+// browser bookmark files (Netscape HTML, Firefox JSON backups, Chrome
+// Bookmarks files, XBEL, Safari plists, Markdown link lists, OPML, CSV)
+// and Org-mode format.
 //
 // orgmarks supports bidirectional conversion with full metadata preservation
 // including tags, shortcuts, timestamps, and nested folder structures.
+// Subcommands merge, deduplicate, compare, inspect and search bookmark files;
+// the original "orgmarks -i ... -o ..." form still converts and merges.
 package main
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
-
-	"github.com/drewherron/orgmarks/internal/converter"
-	"github.com/drewherron/orgmarks/internal/models"
-	"github.com/drewherron/orgmarks/internal/parser"
 )
 
 var (
@@ -24,262 +24,109 @@ var (
 	GitCommit = "unknown"
 )
 
-// stringSlice is a custom flag type that allows multiple values
-type stringSlice []string
-
-func (s *stringSlice) String() string {
-	return strings.Join(*s, ", ")
-}
-
-func (s *stringSlice) Set(value string) error {
-	*s = append(*s, value)
-	return nil
-}
-
 func main() {
-	// Define flags
-	var inputFiles stringSlice
-	flag.Var(&inputFiles, "i", "Input file (can be specified multiple times for merging)")
-	outputFile := flag.String("o", "", "Output file (required)")
-	deduplicate := flag.Bool("deduplicate", false, "Remove duplicate bookmarks (keep first occurrence)")
-	deleteEmpty := flag.Bool("delete-empty", false, "Remove empty folders after processing")
-	showVersion := flag.Bool("version", false, "Show version information")
-	flag.Parse()
-
-	// Handle version flag
-	if *showVersion {
-		fmt.Printf("orgmarks version %s (built %s, commit %s)\n", Version, BuildTime, GitCommit)
-		os.Exit(0)
-	}
-
-	// Validate flags
-	if len(inputFiles) == 0 || *outputFile == "" {
-		fmt.Fprintln(os.Stderr, "Usage: orgmarks -i <input-file> [-i <input-file2> ...] -o <output-file>")
-		fmt.Fprintln(os.Stderr, "\nExamples:")
-		fmt.Fprintln(os.Stderr, "  orgmarks -i bookmarks.html -o bookmarks.org")
-		fmt.Fprintln(os.Stderr, "  orgmarks -i bookmarks.org -o bookmarks.html")
-		fmt.Fprintln(os.Stderr, "  orgmarks -i file1.org -i file2.org -o merged.org    # Merge multiple files")
-		fmt.Fprintln(os.Stderr, "  orgmarks -i organized.org -i new.html -o final.org  # Merge different formats")
-		os.Exit(1)
-	}
-
-	// Check all input files exist
-	for _, inputFile := range inputFiles {
-		if _, err := os.Stat(inputFile); os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Error: Input file '%s' does not exist\n", inputFile)
-			os.Exit(1)
-		}
-	}
-
-	outputExt := strings.ToLower(filepath.Ext(*outputFile))
-
-	// Handle multiple input files (merge mode)
-	if len(inputFiles) > 1 {
-		// Merge mode - output must be .org
-		if outputExt != ".org" {
-			fmt.Fprintln(os.Stderr, "Error: When merging multiple files, output must be .org format")
-			os.Exit(1)
-		}
-
-		if err := mergeFiles(inputFiles, *outputFile, *deduplicate, *deleteEmpty); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Successfully merged %d files → %s\n", len(inputFiles), *outputFile)
-		os.Exit(0)
-	}
-
-	// Single input file - regular conversion mode
-	inputFile := inputFiles[0]
-	inputExt := strings.ToLower(filepath.Ext(inputFile))
+	os.Exit(run(os.Args[1:]))
+}
 
-	// Validate format combination for single file conversion
-	if inputExt == outputExt {
-		fmt.Fprintln(os.Stderr, "Error: Input and output must have different formats")
-		os.Exit(1)
+// run dispatches to a subcommand and returns the exit status. Invocations
+// starting with a flag, like "orgmarks -i in.html -o out.org", are handled
+// as convert or merge, depending on the number of inputs.
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return 1
 	}
 
-	// Check if output file exists and prompt for confirmation
-	if _, err := os.Stat(*outputFile); err == nil {
-		fmt.Fprintf(os.Stderr, "orgmarks: overwrite '%s'? ", *outputFile)
-		reader := bufio.NewReader(os.Stdin)
-		response, err := reader.ReadString('\n')
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "\nError reading input: %v\n", err)
-			os.Exit(1)
-		}
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "y" && response != "yes" {
-			fmt.Fprintln(os.Stderr, "Operation cancelled")
-			os.Exit(0)
-		}
+	if strings.HasPrefix(args[0], "-") {
+		return runLegacy(args)
 	}
 
-	// Determine conversion direction
-	if (inputExt == ".html" || inputExt == ".htm") && outputExt == ".org" {
-		// HTML → Org
-		if err := htmlToOrg(inputFile, *outputFile, *deduplicate, *deleteEmpty); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Successfully converted %s → %s\n", inputFile, *outputFile)
-	} else if inputExt == ".org" && (outputExt == ".html" || outputExt == ".htm") {
-		// Org → HTML
-		if err := orgToHTML(inputFile, *outputFile, *deduplicate, *deleteEmpty); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Successfully converted %s → %s\n", inputFile, *outputFile)
-	} else {
-		fmt.Fprintln(os.Stderr, "Error: Unsupported file format combination")
-		fmt.Fprintln(os.Stderr, "Supported conversions:")
-		fmt.Fprintln(os.Stderr, "  .html → .org")
-		fmt.Fprintln(os.Stderr, "  .org → .html")
-		os.Exit(1)
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n\n", args[0])
+		printUsage(os.Stderr)
+		return 1
 	}
+	return runCommand(cmd, args[1:])
 }
 
-// mergeFiles merges multiple bookmark files into a single org file
-func mergeFiles(inputFiles []string, outputFile string, deduplicate, deleteEmpty bool) error {
-	// Parse the first file
-	root, err := parseFile(inputFiles[0])
-	if err != nil {
-		return fmt.Errorf("failed to parse %s: %w", inputFiles[0], err)
-	}
-
-	// Merge remaining files
-	for i := 1; i < len(inputFiles); i++ {
-		nextTree, err := parseFile(inputFiles[i])
-		if err != nil {
-			return fmt.Errorf("failed to parse %s: %w", inputFiles[i], err)
+// runLegacy handles the original command line, without a subcommand
+func runLegacy(args []string) int {
+	p := &pipeline{}
+	fs := flag.NewFlagSet("orgmarks", flag.ContinueOnError)
+	fs.Usage = func() { printUsage(fs.Output()) }
+	p.addInputFlags(fs)
+	p.addTransformFlags(fs)
+	p.addOutputFlags(fs)
+	showVersion := fs.Bool("version", false, "Show version information")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
 		}
-		root = models.MergeFolders(root, nextTree)
-	}
-
-	// Apply deduplication if requested
-	if deduplicate {
-		models.Deduplicate(root)
-	}
-
-	// Remove empty folders if requested
-	if deleteEmpty {
-		models.RemoveEmptyFolders(root)
-	}
-
-	// Create output file
-	out, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return 1
 	}
-	defer out.Close()
 
-	// Convert to org
-	if err := converter.ToOrg(root, out); err != nil {
-		return fmt.Errorf("failed to convert to org: %w", err)
+	// Handle version flag
+	if *showVersion {
+		printVersion()
+		return 0
 	}
 
-	return nil
-}
-
-// parseFile parses a bookmark file (either HTML or org) and returns the root folder
-func parseFile(filename string) (*models.Folder, error) {
-	ext := strings.ToLower(filepath.Ext(filename))
-
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+	// Validate flags
+	if len(p.inputs) == 0 || p.output == "" {
+		printUsage(os.Stderr)
+		return 1
 	}
-	defer file.Close()
 
-	if ext == ".html" || ext == ".htm" {
-		htmlParser := parser.NewHTMLParser(file)
-		return htmlParser.Parse()
-	} else if ext == ".org" {
-		orgParser := parser.NewOrgParser(file)
-		return orgParser.Parse()
+	var err error
+	if len(p.inputs) > 1 {
+		err = merge(p)
 	} else {
-		return nil, fmt.Errorf("unsupported file format: %s", ext)
-	}
-}
-
-// htmlToOrg converts HTML bookmark file to org-mode
-func htmlToOrg(inputFile, outputFile string, deduplicate, deleteEmpty bool) error {
-	// Open input file
-	in, err := os.Open(inputFile)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %w", err)
-	}
-	defer in.Close()
-
-	// Parse HTML
-	htmlParser := parser.NewHTMLParser(in)
-	root, err := htmlParser.Parse()
-	if err != nil {
-		return fmt.Errorf("failed to parse HTML: %w", err)
-	}
-
-	// Apply deduplication if requested
-	if deduplicate {
-		models.Deduplicate(root)
-	}
-
-	// Remove empty folders if requested
-	if deleteEmpty {
-		models.RemoveEmptyFolders(root)
+		err = convert(p)
 	}
-
-	// Create output file
-	out, err := os.Create(outputFile)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
-	}
-	defer out.Close()
-
-	// Convert to org
-	if err := converter.ToOrg(root, out); err != nil {
-		return fmt.Errorf("failed to convert to org: %w", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
 	}
-
-	return nil
+	return 0
 }
 
-// orgToHTML converts org-mode bookmark file to HTML
-func orgToHTML(inputFile, outputFile string, deduplicate, deleteEmpty bool) error {
-	// Open input file
-	in, err := os.Open(inputFile)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %w", err)
-	}
-	defer in.Close()
-
-	// Parse org
-	orgParser := parser.NewOrgParser(in)
-	root, err := orgParser.Parse()
-	if err != nil {
-		return fmt.Errorf("failed to parse org: %w", err)
-	}
-
-	// Apply deduplication if requested
-	if deduplicate {
-		models.Deduplicate(root)
-	}
-
-	// Remove empty folders if requested
-	if deleteEmpty {
-		models.RemoveEmptyFolders(root)
-	}
-
-	// Create output file
-	out, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
-	}
-	defer out.Close()
-
-	// Convert to HTML
-	if err := converter.ToHTML(root, out); err != nil {
-		return fmt.Errorf("failed to convert to HTML: %w", err)
-	}
+// printVersion prints the version and build information
+func printVersion() {
+	fmt.Printf("orgmarks version %s (built %s, commit %s)\n", Version, BuildTime, GitCommit)
+}
 
-	return nil
+// printUsage prints the list of commands and some examples
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: orgmarks <command> [options] [arguments]")
+	fmt.Fprintln(w, "       orgmarks -i <input-file> [-i <input-file2> ...] -o <output-file>")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s  %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'orgmarks help <command>' for the options of a command. Without a")
+	fmt.Fprintln(w, "command, -i and -o convert a single file or merge several files.")
+	fmt.Fprintln(w, "\nExamples:")
+	fmt.Fprintln(w, "  orgmarks convert -i bookmarks.html -o bookmarks.org")
+	fmt.Fprintln(w, "  orgmarks -i bookmarks.org -o bookmarks.html")
+	fmt.Fprintln(w, "  orgmarks -i bookmarks-2024-10-01.jsonlz4 -o bookmarks.org")
+	fmt.Fprintln(w, "  orgmarks -i ~/.config/google-chrome/Default/Bookmarks -o bookmarks.org")
+	fmt.Fprintln(w, "  orgmarks -i bookmarks.org -o floccus.xbel")
+	fmt.Fprintln(w, "  orgmarks -i places.sqlite -o bookmarks.org")
+	fmt.Fprintln(w, "  orgmarks -i ~/Library/Safari/Bookmarks.plist -o bookmarks.org")
+	fmt.Fprintln(w, "  orgmarks -i bookmarks.org -o links.md --md-toc")
+	fmt.Fprintln(w, "  orgmarks -i bookmarks.org -o bookmarks.csv")
+	fmt.Fprintln(w, "  orgmarks -i feeds.opml -o feeds.org")
+	fmt.Fprintln(w, "  orgmarks merge -i bookmarks.org -i awesome-go.md -o merged.org")
+	fmt.Fprintln(w, "  orgmarks merge -i bookmarks.org -i firefox.json -i chrome.json -o import.html")
+	fmt.Fprintln(w, "  orgmarks -i organized.org -i new.html -o final.org  # Merge different formats")
+	fmt.Fprintln(w, "  orgmarks dedupe -i bookmarks.org -o clean.org --delete-empty")
+	fmt.Fprintln(w, "  orgmarks normalize bookmarks.org --deduplicate")
+	fmt.Fprintln(w, "  orgmarks similar bookmarks.org")
+	fmt.Fprintln(w, "  orgmarks diff old.org new.org")
+	fmt.Fprintln(w, "  orgmarks diff old.org new.org -o changes.org  # New tree annotated with the changes")
+	fmt.Fprintln(w, "  orgmarks search emacs bookmarks.org")
+	fmt.Fprintln(w, "  curl -s https://example.com/links.html | orgmarks -i - -o - --to org")
+	fmt.Fprintln(w, "\nSupported formats: "+strings.Join(supportedFormats, ", "))
 }
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/drewherron/orgmarks/internal/converter"
+	"github.com/drewherron/orgmarks/internal/models"
+)
+
+// promptReader reads answers to questions from standard input. All prompts
+// share it, so buffered answers aren't lost between them.
+var promptReader = bufio.NewReader(os.Stdin)
+
+// stringSlice is a custom flag type that allows multiple values
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringSlice) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// inputOptions holds the settings that control how input files are read
+type inputOptions struct {
+	from         string // Format name given with --from, overriding detection
+	markdownTags bool   // Read inline #tags in Markdown files
+	icons        bool   // Read favicons from sidecar files referenced by Org files
+	iconDir      string // Directory the sidecar files must be in, relative to the Org file
+	dropIcons    bool   // Leave out favicons from formats other than Org
+}
+
+// outputOptions holds the settings that control how output files are written
+type outputOptions struct {
+	to       string // Format name given with --to, overriding the file extension
+	org      converter.OrgOptions
+	markdown converter.MarkdownOptions
+	icons    bool   // Keep favicons from other formats in Org files
+	iconDir  string // Directory for favicon sidecar files, relative to the output file
+	plistXML bool   // Write Safari plists as XML instead of binary
+	noIDs    bool   // Don't generate IDs for nodes without one
+}
+
+// pipeline is the shared read, transform and write sequence behind the
+// subcommands: parse and merge the inputs, clean up the tree, write the output
+type pipeline struct {
+	inputs      stringSlice
+	output      string
+	deduplicate bool
+	dedupeOpts  models.DeduplicateOptions
+	reportFile  string // Where to write the deduplication report
+	interactive bool   // Ask which copy of each duplicate to keep
+	deleteEmpty bool
+	mergeOpts   models.MergeOptions
+	inOpts      inputOptions
+	outOpts     outputOptions
+}
+
+// addInputFlags registers the input file flag and the flags for reading inputs
+func (p *pipeline) addInputFlags(fs *flag.FlagSet) {
+	fs.Var(&p.inputs, "i", "Input file, or - for standard input (can be specified multiple times for merging)")
+	p.addReadFlags(fs)
+}
+
+// addReadFlags registers the flags for reading inputs, for commands that
+// take their input files as arguments
+func (p *pipeline) addReadFlags(fs *flag.FlagSet) {
+	fs.StringVar(&p.inOpts.from, "from", "", "Input format, instead of detecting it from the file name and content")
+	fs.BoolVar(&p.inOpts.markdownTags, "md-tags", false, "Read inline #tags in Markdown input as bookmark tags")
+}
+
+// addTransformFlags registers the flags for cleaning up the bookmark tree
+func (p *pipeline) addTransformFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&p.deduplicate, "deduplicate", false, "Remove duplicate bookmarks (keep first occurrence, or as chosen with --strategy)")
+	p.addDedupeFlags(fs)
+	fs.BoolVar(&p.deleteEmpty, "delete-empty", false, "Remove empty folders after processing")
+}
+
+// addDedupeFlags registers the flags that control how duplicates are found
+func (p *pipeline) addDedupeFlags(fs *flag.FlagSet) {
+	fs.Func("url-match", "How strictly URLs are compared when deduplicating: exact (default), basic, standard or aggressive", func(value string) error {
+		level, err := models.ParseURLNormalization(value)
+		p.dedupeOpts.URLs = level
+		return err
+	})
+	fs.Func("strategy", "Which copy of a duplicate to keep: keep-first (default), keep-newest, keep-oldest, keep-deepest-folder or merge", func(value string) error {
+		strategy, err := models.ParseDuplicateStrategy(value)
+		p.dedupeOpts.Strategy = strategy
+		return err
+	})
+	fs.StringVar(&p.reportFile, "report", "", "Write a report of the duplicates to this file (JSON if it ends in .json), or - to print it")
+	fs.BoolVar(&p.interactive, "interactive", false, "Ask which copy of each duplicate to keep")
+}
+
+// addMergeFlags registers the flags that control how inputs are merged
+func (p *pipeline) addMergeFlags(fs *flag.FlagSet) {
+	fs.Func("into", "Put the inputs after the first into this folder, given as a path like Imported/Chrome", func(value string) error {
+		p.mergeOpts.Target = nil
+		for _, title := range strings.Split(value, "/") {
+			if title = strings.TrimSpace(title); title != "" {
+				p.mergeOpts.Target = append(p.mergeOpts.Target, title)
+			}
+		}
+		return nil
+	})
+	fs.Func("alias", "Folder titles that count as the same folder, separated by = (can be specified multiple times)", func(value string) error {
+		var group []string
+		for _, title := range strings.Split(value, "=") {
+			if title = strings.TrimSpace(title); title != "" {
+				group = append(group, title)
+			}
+		}
+		if len(group) < 2 {
+			return errors.New("an alias needs at least two folder titles, like \"Bookmarks bar=Bookmarks Toolbar\"")
+		}
+		p.mergeOpts.Aliases = append(p.mergeOpts.Aliases, group)
+		return nil
+	})
+	fs.BoolFunc("browser-aliases", "Treat the toolbar, other and mobile folders of all browsers as the same folders", func(string) error {
+		p.mergeOpts.Aliases = append(p.mergeOpts.Aliases, models.BrowserFolderAliases...)
+		return nil
+	})
+	fs.StringVar(&p.mergeOpts.Inbox, "inbox", "", "Put the bookmarks of inputs without folders into a folder with this title")
+}
+
+// addOutputFlags registers the output file flag and the flags for writing it
+func (p *pipeline) addOutputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&p.output, "o", "", "Output file, or - for standard output (required)")
+	fs.StringVar(&p.outOpts.to, "to", "", "Output format, instead of choosing it by the file extension")
+	p.addWriteFlags(fs)
+}
+
+// addWriteFlags registers the flags that control how the output is formatted
+func (p *pipeline) addWriteFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&p.outOpts.org.PropertyDrawers, "properties", false, "Write Org metadata in :PROPERTIES: drawers instead of #+KEY: lines")
+	fs.BoolVar(&p.outOpts.org.NoTimestamps, "no-timestamps", false, "Don't write ADD_DATE and LAST_MODIFIED to Org files")
+	fs.BoolVar(&p.outOpts.icons, "icons", false, "Keep favicons (ICON and ICON_URI) from other formats in Org files, and read them from sidecar files")
+	fs.StringVar(&p.outOpts.iconDir, "icon-dir", "", "Store favicons as files in this directory (relative to the Org file); implies --icons")
+	fs.BoolVar(&p.outOpts.plistXML, "plist-xml", false, "Write Safari .plist files as XML instead of binary")
+	fs.BoolVar(&p.outOpts.noIDs, "no-ids", false, "Don't generate IDs for bookmarks and folders that have none")
+	fs.BoolVar(&p.outOpts.markdown.Lists, "md-lists", false, "Write Markdown folders as nested lists instead of headings")
+	fs.IntVar(&p.outOpts.markdown.HeadingLevel, "md-heading-level", 2, "Markdown heading level for top-level folders")
+	fs.IntVar(&p.outOpts.markdown.MaxHeadingLevel, "md-max-heading", 6, "Deepest Markdown heading level; deeper folders become nested lists")
+	fs.BoolVar(&p.outOpts.markdown.TableOfContents, "md-toc", false, "Add a table of contents to Markdown output")
+}
+
+// checkInputs verifies that there is at least one input and all inputs exist
+func (p *pipeline) checkInputs() error {
+	if len(p.inputs) == 0 {
+		return usageError("no input file given (use -i)")
+	}
+	stdin := 0
+	for _, inputFile := range p.inputs {
+		if inputFile == stdio {
+			if stdin++; stdin > 1 {
+				return usageError("standard input can only be read once")
+			}
+			if p.interactive {
+				return usageError("--interactive reads answers from standard input, so it can't read bookmarks from it")
+			}
+			continue
+		}
+		if _, err := os.Stat(inputFile); os.IsNotExist(err) {
+			return fmt.Errorf("input file '%s' does not exist", inputFile)
+		}
+	}
+	return nil
+}
+
+// read parses all inputs and merges them into one tree, in the order given
+func (p *pipeline) read() (*models.Folder, error) {
+	var root *models.Folder
+	for _, inputFile := range p.inputs {
+		tree, err := parseFile(inputFile, p.readOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", displayName(inputFile, true), err)
+		}
+		if root == nil {
+			root = tree
+		} else {
+			root = models.MergeFoldersWithOptions(root, tree, p.mergeOpts)
+		}
+	}
+	return root, nil
+}
+
+// readOptions returns the options for reading the inputs. Favicons are only
+// read from sidecar files when they are going to be written, and favicons
+// from other formats only go into Org output when asked for.
+func (p *pipeline) readOptions() inputOptions {
+	opts := p.inOpts
+	opts.icons = p.outOpts.icons || p.outOpts.iconDir != ""
+	opts.iconDir = p.outOpts.iconDir
+	if outFormat, err := resolveFormat(p.output, false, p.outOpts.to); err == nil {
+		opts.dropIcons = !opts.icons && outFormat == formatOrg
+	}
+	return opts
+}
+
+// transform applies the requested clean-ups to the tree. If duplicates
+// were removed, it returns the deduplication report.
+func (p *pipeline) transform(root *models.Folder) (*models.DeduplicateReport, error) {
+	var report *models.DeduplicateReport
+
+	// Apply deduplication if requested
+	if p.deduplicate {
+		opts := p.dedupeOpts
+		if p.interactive {
+			opts.Resolve = interactiveResolver(opts.Strategy)
+		}
+		var err error
+		if report, err = models.DeduplicateWithOptions(root, opts); err != nil {
+			return nil, err
+		}
+		if p.reportFile != "" {
+			if err := p.writeReport(report); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Remove empty folders if requested
+	if p.deleteEmpty {
+		models.RemoveEmptyFolders(root)
+	}
+	return report, nil
+}
+
+// write writes the tree to the output file
+func (p *pipeline) write(root *models.Folder) error {
+	// Give every node a stable identity, so the output can be matched up
+	// with later versions of itself
+	if !p.outOpts.noIDs {
+		models.AssignIDs(root)
+	}
+	return writeFile(root, p.output, p.outOpts)
+}
+
+// run reads, transforms and writes the bookmarks
+func (p *pipeline) run() error {
+	root, err := p.read()
+	if err != nil {
+		return err
+	}
+	if _, err := p.transform(root); err != nil {
+		return err
+	}
+	return p.write(root)
+}
+
+// reportf prints a status message
+func (p *pipeline) reportf(format string, args ...any) {
+	fmt.Fprintf(p.statusOutput(), format, args...)
+}
+
+// statusOutput returns where status messages go: stderr when the bookmarks
+// are written to standard output, stdout otherwise
+func (p *pipeline) statusOutput() *os.File {
+	if p.output == stdio {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// confirmOverwrite asks before replacing an existing output file.
+// It returns true if the file doesn't exist, is being rewritten in place
+// (it is one of the inputs), or the user agreed.
+func (p *pipeline) confirmOverwrite() (bool, error) {
+	filename := p.output
+	if filename == stdio {
+		return true, nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return true, nil
+	}
+	for _, inputFile := range p.inputs {
+		if inputFile == stdio {
+			continue
+		}
+		if inputInfo, err := os.Stat(inputFile); err == nil && os.SameFile(info, inputInfo) {
+			return true, nil
+		}
+	}
+	if slices.Contains(p.inputs, stdio) {
+		// Standard input holds the bookmarks, not an answer
+		return false, fmt.Errorf("output file '%s' exists; remove it first when reading standard input", filename)
+	}
+
+	fmt.Fprintf(os.Stderr, "orgmarks: overwrite '%s'? ", filename)
+	response, err := promptReader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("reading input: %w", err)
+	}
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes", nil
+}
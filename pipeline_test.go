@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/drewherron/orgmarks/internal/models"
+	"github.com/drewherron/orgmarks/internal/parser"
+)
+
+// normalizeTestOrg uses every field the Org parser reads
+const normalizeTestOrg = `* Bookmarks Toolbar
+:PROPERTIES:
+:ID: 6f9b1a52-3d4e-4c1a-9b7e-2f0c8d5a1e33
+:END:
+#+ROOT: toolbar
+#+ADD_DATE: [2021-03-04 Thu 05:06:07 +0100]
+#+COLOR: blue
+** Go                                                                 :dev:go:
+:PROPERTIES:
+:ID: 0d3c7a4e-8f21-4b6a-a5c9-71e2b4f8d690
+:END:
+#+SHORTCUTURL: go
+#+ADD_DATE: [2017-08-26 Sat 14:26:30 -0700]
+#+LAST_MODIFIED: [2024-10-01 Tue 00:00:00 +0000]
+#+ICON_URI: https://go.dev/favicon.ico
+#+ICON: data:image/png;base64,iVBORw0KGgo=
+#+RATING: 5
+[[https://go.dev/]]
+The Go programming language
+** -----
+:PROPERTIES:
+:ID: 2a8e5f10-c4b7-4d93-8e6a-5b1f0c7d9e24
+:END:
+#+SEPARATOR: t
+** Empty
+:PROPERTIES:
+:ID: 9c1d4e7b-2a6f-4f08-b3d5-e8a0c6b2f417
+:END:
+`
+
+// parseOrgFile parses an Org file, with all timestamps in UTC so trees can
+// be compared whatever offset their timestamps were written with
+func parseOrgFile(t *testing.T, filename string) *models.Folder {
+	t.Helper()
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	root, err := parser.NewOrgParser(file).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", filename, err)
+	}
+
+	models.Walk(root, 0, func(node models.Node, depth int) {
+		switch n := node.(type) {
+		case *models.Bookmark:
+			n.AddDate, n.LastModified = n.AddDate.UTC(), n.LastModified.UTC()
+		case *models.Folder:
+			n.AddDate, n.LastModified = n.AddDate.UTC(), n.LastModified.UTC()
+		}
+	})
+	return root
+}
+
+func TestNormalizeOrgRoundTrip(t *testing.T) {
+	for _, flags := range [][]string{nil, {"--properties"}} {
+		filename := filepath.Join(t.TempDir(), "bookmarks.org")
+		if err := os.WriteFile(filename, []byte(normalizeTestOrg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		want := parseOrgFile(t, filename)
+
+		fs := flag.NewFlagSet("orgmarks normalize", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		if err := runNormalize(fs, append(flags, filename)); err != nil {
+			t.Fatalf("normalize %q failed: %v", flags, err)
+		}
+
+		got := parseOrgFile(t, filename)
+		if !reflect.DeepEqual(got, want) {
+			data, _ := os.ReadFile(filename)
+			t.Errorf("normalize %q changed the bookmarks:\ngot  %#v\nwant %#v\nfile:\n%s", flags, got, want, data)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+#!/usr/bin/env python3
+"""Generate places.sqlite, a small Firefox bookmarks database for tests.
+
+The tables use the same definitions as Firefox's places.sqlite. The tree
+matches bookmarks.json, plus an "Archive" folder with enough bookmarks to
+need interior b-tree pages and one URL long enough to use overflow pages.
+
+Usage: python3 make_places.py  (run from this directory)
+"""
+
+import os
+import sqlite3
+
+SCHEMA = """
+CREATE TABLE moz_origins ( id INTEGER PRIMARY KEY, prefix TEXT NOT NULL, host TEXT NOT NULL, frecency INTEGER NOT NULL, recalc_frecency INTEGER NOT NULL DEFAULT 0, alt_frecency INTEGER, recalc_alt_frecency INTEGER NOT NULL DEFAULT 0, UNIQUE (prefix, host) );
+CREATE TABLE moz_places (   id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR, rev_host LONGVARCHAR, visit_count INTEGER DEFAULT 0, hidden INTEGER DEFAULT 0 NOT NULL, typed INTEGER DEFAULT 0 NOT NULL, frecency INTEGER DEFAULT -1 NOT NULL, last_visit_date INTEGER , guid TEXT, foreign_count INTEGER DEFAULT 0 NOT NULL, url_hash INTEGER DEFAULT 0 NOT NULL , description TEXT, preview_image_url TEXT, site_name TEXT, origin_id INTEGER REFERENCES moz_origins(id), recalc_frecency INTEGER NOT NULL DEFAULT 0, alt_frecency INTEGER, recalc_alt_frecency INTEGER NOT NULL DEFAULT 0);
+CREATE TABLE moz_bookmarks (  id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER DEFAULT NULL, parent INTEGER, position INTEGER, title LONGVARCHAR, keyword_id INTEGER, folder_type TEXT, dateAdded INTEGER, lastModified INTEGER, guid TEXT, syncStatus INTEGER NOT NULL DEFAULT 0, syncChangeCounter INTEGER NOT NULL DEFAULT 1);
+CREATE TABLE moz_keywords (  id INTEGER PRIMARY KEY AUTOINCREMENT, keyword TEXT UNIQUE, place_id INTEGER, post_data TEXT);
+CREATE INDEX moz_places_url_hashindex ON moz_places (url_hash);
+CREATE UNIQUE INDEX moz_places_guid_uniqueindex ON moz_places (guid);
+CREATE INDEX moz_bookmarks_itemindex ON moz_bookmarks (fk, type);
+CREATE INDEX moz_bookmarks_parentindex ON moz_bookmarks (parent, position);
+CREATE UNIQUE INDEX moz_bookmarks_guid_uniqueindex ON moz_bookmarks (guid);
+"""
+
+BOOKMARK, FOLDER, SEPARATOR = 1, 2, 3
+T = 1503757590000000  # 2017-08-26 14:26:30 UTC in microseconds
+
+
+def main():
+    path = "places.sqlite"
+    if os.path.exists(path):
+        os.remove(path)
+
+    db = sqlite3.connect(path)
+    db.execute("PRAGMA page_size = 1024")
+    db.executescript(SCHEMA)
+
+    places = {}
+
+    def place(url, title):
+        if url not in places:
+            cur = db.execute(
+                "INSERT INTO moz_places (url, title, frecency, guid) VALUES (?, ?, -1, ?)",
+                (url, title, "p%011d" % (len(places) + 1)))
+            places[url] = cur.lastrowid
+        return places[url]
+
+    positions = {}
+
+    def item(kind, parent, title, guid, url=None, added=T, modified=T):
+        position = positions.get(parent, 0)
+        positions[parent] = position + 1
+        fk = place(url, title) if url else None
+        cur = db.execute(
+            "INSERT INTO moz_bookmarks (type, fk, parent, position, title, dateAdded, lastModified, guid)"
+            " VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+            (kind, fk, parent, position, title, added, modified, guid))
+        return cur.lastrowid
+
+    root = item(FOLDER, 0, "", "root________")
+    menu = item(FOLDER, root, "menu", "menu________")
+    toolbar = item(FOLDER, root, "toolbar", "toolbar_____")
+    tags = item(FOLDER, root, "tags", "tags________")
+    unfiled = item(FOLDER, root, "unfiled", "unfiled_____")
+    item(FOLDER, root, "mobile", "mobile______")
+
+    item(BOOKMARK, menu, "Recent Tags", "OYpuNvWcLr4B", url="place:type=6&sort=14&maxResults=10")
+    email = item(FOLDER, menu, "Email", "Fp1nb3zVwH3c")
+    item(BOOKMARK, email, "Gmail", "aR7bKq9Xm2Lp", url="https://mail.google.com/")
+    item(SEPARATOR, menu, None, "Sx9pQ2vLm4Nc")
+
+    item(BOOKMARK, toolbar, "Docs", "Zk3mP8qR1tXw", url="https://docs.fedoraproject.org/")
+    item(BOOKMARK, toolbar, "Fedora Magazine", "V2n0aJ7kP1sD", url="https://fedoramagazine.org/",
+         added=1367341224000000, modified=1503757786000000)
+    project = item(FOLDER, toolbar, "Project", "Qw8eR4tY6uI2")
+    item(BOOKMARK, project, "Get Fedora", "Lk5jH7gF3dS1", url="https://getfedora.org/")
+
+    # Tags are folders under the tags root holding untitled bookmarks
+    news = item(FOLDER, tags, "news", "Tg1nEwSaBcDe")
+    item(BOOKMARK, news, None, "Tg2nEwSaBcDe", url="https://fedoramagazine.org/")
+    db.execute("INSERT INTO moz_keywords (keyword, place_id) VALUES (?, ?)",
+               ("magazine", places["https://fedoramagazine.org/"]))
+
+    item(BOOKMARK, unfiled, "GNU", "Gn7uPrOjEcT1", url="https://www.gnu.org/")
+    archive = item(FOLDER, unfiled, "Archive", "ArChIvE00001")
+    for i in range(200):
+        item(BOOKMARK, archive, "Page %d" % i, "ArChIvE%05d" % (i + 2), url="https://example.com/page/%d" % i)
+    long_url = "https://example.com/long?" + "&".join("key%d=value%d" % (i, i) for i in range(300))
+    item(BOOKMARK, archive, "Long URL", "LoNgUrL00001", url=long_url)
+
+    db.commit()
+    db.execute("VACUUM")
+    db.close()
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,71 @@
+#!/usr/bin/env python3
+"""Generate Bookmarks.plist, a small Safari bookmarks file for tests.
+
+The structure follows Safari's ~/Library/Safari/Bookmarks.plist: a root list
+holding the History proxy, the favorites bar, the bookmarks menu and the
+Reading List. The file is written as a binary plist, like Safari does.
+
+Usage: python3 make_safari.py  (run from this directory)
+"""
+
+import datetime
+import plistlib
+
+
+def leaf(uuid, title, url, **extra):
+    node = {
+        "URIDictionary": {"title": title},
+        "URLString": url,
+        "WebBookmarkType": "WebBookmarkTypeLeaf",
+        "WebBookmarkUUID": uuid,
+    }
+    node.update(extra)
+    return node
+
+
+def folder(uuid, title, children, **extra):
+    node = {
+        "Children": children,
+        "Title": title,
+        "WebBookmarkType": "WebBookmarkTypeList",
+        "WebBookmarkUUID": uuid,
+    }
+    node.update(extra)
+    return node
+
+
+def main():
+    bar = folder("B1E2A3C4-0000-4000-8000-000000000001", "BookmarksBar", [
+        leaf("B1E2A3C4-0000-4000-8000-000000000011", "Apple", "https://www.apple.com/"),
+        folder("B1E2A3C4-0000-4000-8000-000000000012", "Dev", [
+            leaf("B1E2A3C4-0000-4000-8000-000000000013", "WebKit", "https://webkit.org/"),
+        ]),
+    ])
+    menu = folder("B1E2A3C4-0000-4000-8000-000000000002", "BookmarksMenu", [
+        leaf("B1E2A3C4-0000-4000-8000-000000000021", "Org Mode – Your life in plain text", "https://orgmode.org/"),
+        folder("B1E2A3C4-0000-4000-8000-000000000022", "Linux", [
+            leaf("B1E2A3C4-0000-4000-8000-000000000023", "GNU", "https://www.gnu.org/"),
+        ]),
+    ])
+    reading = folder("B1E2A3C4-0000-4000-8000-000000000003", "com.apple.ReadingList", [
+        leaf("B1E2A3C4-0000-4000-8000-000000000031", "Long Read", "https://example.com/article",
+             ReadingList={
+                 "DateAdded": datetime.datetime(2024, 3, 1, 12, 30, 0),
+                 "PreviewText": "An article worth reading later",
+             }),
+    ], ShouldOmitFromUI=True)
+    history = {
+        "Title": "History",
+        "WebBookmarkIdentifier": "History Bookmark Proxy Identifier",
+        "WebBookmarkType": "WebBookmarkTypeProxy",
+        "WebBookmarkUUID": "B1E2A3C4-0000-4000-8000-000000000004",
+    }
+    root = folder("B1E2A3C4-0000-4000-8000-000000000000", "", [history, bar, menu, reading],
+                  WebBookmarkFileVersion=1)
+
+    with open("Bookmarks.plist", "wb") as f:
+        plistlib.dump(root, f, fmt=plistlib.FMT_BINARY)
+
+
+if __name__ == "__main__":
+    main()